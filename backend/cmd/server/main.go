@@ -3,10 +3,15 @@ package main
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"markmywords-backend/internal/handlers"
 	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
+	"markmywords-backend/internal/types"
 	"markmywords-backend/internal/websocket"
+	"markmywords-backend/pkg/auth"
+	"markmywords-backend/pkg/config"
 	"markmywords-backend/pkg/database"
 
 	"github.com/gin-contrib/cors"
@@ -17,16 +22,49 @@ func main() {
 	// Initialize database
 	database.InitDB()
 
+	// Load JWT signing keys and rotate them on schedule
+	keyring, err := auth.InitKeyring(database.GetDB())
+	if err != nil {
+		log.Fatal("Failed to initialize signing keys:", err)
+	}
+	go keyring.RunRotation(config.GetDuration("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour))
+
 	// Create WebSocket manager
 	wsManager := websocket.NewManager()
 	go wsManager.Start()
 
+	// Keep directory-backed accounts in sync with LDAP
+	if ldapAuth := services.NewLDAPAuthenticator(database.GetDB()); ldapAuth != nil {
+		go ldapAuth.RunSync(config.GetDuration("LDAP_SYNC_INTERVAL", time.Hour))
+	}
+
+	// Delete data exports once their retention period has passed
+	go services.NewExportService().RunCleanup(time.Hour)
+
+	// Permanently delete threads and notes left in the trash too long
+	go services.NewTrashService().RunPurge(time.Hour)
+
 	// Create handlers
 	authHandler := handlers.NewAuthHandler()
 	threadHandler := handlers.NewThreadHandler()
 	noteHandler := handlers.NewNoteHandler()
 	inviteHandler := handlers.NewInviteHandler()
 	wsHandler := handlers.NewWebSocketHandler()
+	accessTokenHandler := handlers.NewAccessTokenHandler()
+	oidcHandler := handlers.NewOIDCHandler()
+	userHandler := handlers.NewUserHandler()
+	mediaHandler := handlers.NewMediaHandler()
+	exportHandler := handlers.NewExportHandler()
+	collaboratorHandler := handlers.NewCollaboratorHandler()
+	transferHandler := handlers.NewTransferHandler()
+	adminHandler := handlers.NewAdminHandler()
+	shareLinkHandler := handlers.NewShareLinkHandler()
+	trashHandler := handlers.NewTrashHandler()
+	readHandler := handlers.NewReadHandler()
+	labelHandler := handlers.NewLabelHandler()
+	folderHandler := handlers.NewFolderHandler()
+	workspaceHandler := handlers.NewWorkspaceHandler()
+	groupHandler := handlers.NewGroupHandler()
 
 	// Setup Gin router
 	r := gin.Default()
@@ -34,8 +72,9 @@ func main() {
 	// CORS configuration
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Workspace-ID", "If-Match"}
+	config.ExposeHeaders = []string{"ETag"}
 	r.Use(cors.New(config))
 
 	// API routes
@@ -46,38 +85,139 @@ func main() {
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+
+			// Single sign-on through OpenID Connect providers
+			auth.GET("/oidc/providers", oidcHandler.GetProviders)
+			auth.GET("/oidc/:provider/login", oidcHandler.Login)
+			auth.GET("/oidc/:provider/callback", oidcHandler.Callback)
+
+			// Email verification links are opened without a login
+			auth.POST("/email/confirm", userHandler.ConfirmEmailChange)
 		}
 
+		// Data export downloads are authorized by the signed link
+		api.GET("/exports/:id/download", exportHandler.Download)
+
+		// Share link previews and anonymous reading are authorized by the token
+		api.GET("/share/:token", shareLinkHandler.GetSharedThread)
+
 		// Protected routes
 		protected := api.Group("")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.AuthMiddleware(), middleware.WorkspaceContext())
 		{
 			// User routes
-			protected.GET("/auth/me", authHandler.GetMe)
+			protected.GET("/auth/me", middleware.RequireScope(types.ScopeUsersRead), authHandler.GetMe)
 
 			// Thread routes
 			threads := protected.Group("/threads")
+			threads.Use(middleware.RequireScopes(types.ScopeThreadsRead, types.ScopeThreadsWrite))
 			{
 				threads.GET("", threadHandler.GetThreads)
 				threads.POST("", threadHandler.CreateThread)
 				threads.GET("/collaborative", threadHandler.GetCollaborativeThreads)
+				threads.GET("/public", threadHandler.GetPublicThreads)
+				threads.PUT("/order", threadHandler.ReorderThreads)
 				threads.GET("/:id", threadHandler.GetThread)
 				threads.PUT("/:id", threadHandler.UpdateThread)
 				threads.DELETE("/:id", threadHandler.DeleteThread)
+				threads.POST("/:id/restore", trashHandler.RestoreThread)
+				threads.PATCH("/:id/preferences", threadHandler.UpdatePreferences)
+				threads.POST("/:id/read", readHandler.MarkRead)
+				threads.POST("/:id/labels/:labelId", labelHandler.AddThreadLabel)
+				threads.DELETE("/:id/labels/:labelId", labelHandler.RemoveThreadLabel)
+				threads.PUT("/:id/folder", folderHandler.MoveThread)
+				threads.GET("/:id/activity", collaboratorHandler.GetActivity)
+				threads.POST("/:id/transfer", transferHandler.RequestTransfer)
+			}
+
+			// Deleted threads and notes
+			protected.GET("/trash", middleware.RequireScopes(types.ScopeThreadsRead, types.ScopeThreadsWrite), trashHandler.GetTrash)
+
+			// Unread notes across all threads
+			protected.GET("/unread", middleware.RequireScopes(types.ScopeThreadsRead, types.ScopeThreadsWrite), readHandler.GetUnread)
+
+			// Label routes
+			labels := protected.Group("/labels")
+			labels.Use(middleware.RequireScopes(types.ScopeThreadsRead, types.ScopeThreadsWrite))
+			{
+				labels.GET("", labelHandler.GetLabels)
+				labels.POST("", labelHandler.CreateLabel)
+				labels.PUT("/:id", labelHandler.UpdateLabel)
+				labels.DELETE("/:id", labelHandler.DeleteLabel)
+			}
+
+			// Folder routes
+			folders := protected.Group("/folders")
+			folders.Use(middleware.RequireScopes(types.ScopeThreadsRead, types.ScopeThreadsWrite))
+			{
+				folders.GET("", folderHandler.GetFolders)
+				folders.POST("", folderHandler.CreateFolder)
+				folders.PUT("/:id", folderHandler.UpdateFolder)
+				folders.DELETE("/:id", folderHandler.DeleteFolder)
+			}
+
+			// Workspace routes
+			workspaces := protected.Group("/workspaces")
+			workspaces.Use(middleware.RequireScopes(types.ScopeThreadsRead, types.ScopeThreadsWrite))
+			{
+				workspaces.GET("", workspaceHandler.GetWorkspaces)
+				workspaces.POST("", workspaceHandler.CreateWorkspace)
+				workspaces.GET("/:id", workspaceHandler.GetWorkspace)
+				workspaces.PUT("/:id", workspaceHandler.UpdateWorkspace)
+				workspaces.DELETE("/:id", workspaceHandler.DeleteWorkspace)
+				workspaces.GET("/:id/members", workspaceHandler.GetMembers)
+				workspaces.POST("/:id/members", workspaceHandler.AddMember)
+				workspaces.PUT("/:id/members/:userId", workspaceHandler.UpdateMember)
+				workspaces.DELETE("/:id/members/:userId", workspaceHandler.RemoveMember)
+			}
+
+			// User group routes
+			groups := protected.Group("/groups")
+			groups.Use(middleware.RequireScopes(types.ScopeThreadsRead, types.ScopeThreadsWrite))
+			{
+				groups.GET("", groupHandler.GetGroups)
+				groups.POST("", groupHandler.CreateGroup)
+				groups.GET("/:id", groupHandler.GetGroup)
+				groups.PUT("/:id", groupHandler.UpdateGroup)
+				groups.DELETE("/:id", groupHandler.DeleteGroup)
+				groups.GET("/:id/members", groupHandler.GetMembers)
+				groups.POST("/:id/members", groupHandler.AddMember)
+				groups.PUT("/:id/members/:userId", groupHandler.UpdateMember)
+				groups.DELETE("/:id/members/:userId", groupHandler.RemoveMember)
+			}
+
+			// Ownership transfer routes
+			transfers := protected.Group("/transfers")
+			transfers.Use(middleware.RequireScopes(types.ScopeThreadsRead, types.ScopeThreadsWrite))
+			{
+				transfers.GET("", transferHandler.GetTransfers)
+				transfers.POST("/:id/accept", transferHandler.AcceptTransfer)
+				transfers.POST("/:id/decline", transferHandler.DeclineTransfer)
+				transfers.DELETE("/:id", transferHandler.CancelTransfer)
 			}
 
 			// Note routes (messages within threads)
 			notes := protected.Group("/notes")
+			notes.Use(middleware.RequireScopes(types.ScopeNotesRead, types.ScopeNotesWrite))
 			{
 				notes.GET("/thread/:threadId", noteHandler.GetThreadNotes)
 				notes.POST("", noteHandler.CreateNote)
 				notes.GET("/:id", noteHandler.GetNote)
 				notes.PUT("/:id", noteHandler.UpdateNote)
 				notes.DELETE("/:id", noteHandler.DeleteNote)
+				notes.POST("/:id/restore", trashHandler.RestoreNote)
+				notes.GET("/:id/receipts", readHandler.GetNoteReceipts)
+				notes.GET("/:id/replies", noteHandler.GetReplies)
+				notes.POST("/:id/reactions", noteHandler.AddReaction)
+				notes.DELETE("/:id/reactions", noteHandler.RemoveReaction)
+				notes.GET("/:id/revisions", noteHandler.GetRevisions)
+				notes.GET("/:id/revisions/:rev/diff", noteHandler.DiffRevisions)
+				notes.POST("/:id/revisions/:rev/restore", noteHandler.RestoreRevision)
 			}
 
 			// Invite routes
 			invites := protected.Group("/invites")
+			invites.Use(middleware.RequireScopes(types.ScopeInvitesRead, types.ScopeInvitesWrite))
 			{
 				invites.GET("", inviteHandler.GetUserInvites)
 				invites.POST("/:id/accept", inviteHandler.AcceptInvite)
@@ -85,15 +225,80 @@ func main() {
 			}
 
 			// Thread collaboration routes
-			threads.POST("/:id/invite", inviteHandler.CreateInvite)
+			threadInvites := protected.Group("/threads")
+			threadInvites.Use(middleware.RequireScopes(types.ScopeInvitesRead, types.ScopeInvitesWrite))
+			{
+				threadInvites.POST("/:id/invite", inviteHandler.CreateInvite)
+				threadInvites.GET("/:id/collaborators", collaboratorHandler.GetCollaborators)
+				threadInvites.GET("/:id/collaborators/:userId", collaboratorHandler.GetCollaborator)
+				threadInvites.PATCH("/:id/collaborators/:userId", collaboratorHandler.UpdateCollaborator)
+				threadInvites.DELETE("/:id/collaborators/:userId", collaboratorHandler.RemoveCollaborator)
+				threadInvites.POST("/:id/join", collaboratorHandler.JoinThread)
+				threadInvites.POST("/:id/leave", collaboratorHandler.LeaveThread)
+				threadInvites.GET("/:id/groups", groupHandler.GetThreadGroups)
+				threadInvites.POST("/:id/groups", groupHandler.GrantGroup)
+				threadInvites.PATCH("/:id/groups/:groupId", groupHandler.UpdateGroupGrant)
+				threadInvites.DELETE("/:id/groups/:groupId", groupHandler.RevokeGroup)
+				threadInvites.GET("/:id/share-links", shareLinkHandler.GetLinks)
+				threadInvites.POST("/:id/share-links", shareLinkHandler.CreateLink)
+				threadInvites.DELETE("/:id/share-links/:linkId", shareLinkHandler.RevokeLink)
+			}
+
+			// Joining through a share link
+			protected.POST("/share/:token/join", middleware.RequireScopes(types.ScopeInvitesRead, types.ScopeInvitesWrite), shareLinkHandler.Join)
 
 			// User search
-			protected.POST("/users/search", inviteHandler.SearchUsers)
+			protected.POST("/users/search", middleware.RequireScope(types.ScopeUsersRead), inviteHandler.SearchUsers)
+
+			// Current user's profile and account
+			me := protected.Group("/users/me")
+			me.Use(middleware.RequireScopes(types.ScopeUsersRead, types.ScopeUsersWrite))
+			{
+				me.GET("", userHandler.GetProfile)
+				me.PATCH("", userHandler.UpdateProfile)
+				me.GET("/identities", oidcHandler.GetIdentities)
+				me.PUT("/avatar", userHandler.UpdateAvatar)
+				me.DELETE("/avatar", userHandler.DeleteAvatar)
+			}
+
+			// Credentials, sessions and access tokens can only be managed from a login session
+			account := protected.Group("/users/me")
+			account.Use(middleware.RequireSession())
+			{
+				account.POST("/password", userHandler.ChangePassword)
+				account.POST("/email", userHandler.RequestEmailChange)
+				account.DELETE("", userHandler.DeleteAccount)
+				account.GET("/sessions", userHandler.GetSessions)
+				account.DELETE("/sessions/:id", userHandler.RevokeSession)
+				account.POST("/export", exportHandler.RequestExport)
+				account.GET("/exports", exportHandler.GetExports)
+				account.GET("/exports/:id", exportHandler.GetExport)
+				account.GET("/tokens", accessTokenHandler.GetTokens)
+				account.POST("/tokens", accessTokenHandler.CreateToken)
+				account.DELETE("/tokens/:id", accessTokenHandler.RevokeToken)
+				account.POST("/identities/:provider", oidcHandler.Link)
+			}
+
+			// Site administration
+			admin := protected.Group("/admin")
+			admin.Use(middleware.RequireSession(), middleware.RequireAdmin())
+			{
+				admin.POST("/users/:id/deactivate", adminHandler.DeactivateUser)
+				admin.POST("/users/:id/reactivate", adminHandler.ReactivateUser)
+				admin.GET("/users/:id/threads", adminHandler.GetUserThreads)
+				admin.POST("/threads/:id/transfer", adminHandler.TransferThread)
+			}
 		}
 	}
 
 	// WebSocket route
-	r.GET("/ws", wsHandler.HandleWebSocket)
+	r.GET("/ws", middleware.WebSocketAuthMiddleware(), middleware.RequireScopes(types.ScopeNotesRead, types.ScopeNotesWrite), wsHandler.HandleWebSocket)
+
+	// Public keys for verifying our JWTs
+	r.GET("/.well-known/jwks.json", authHandler.GetJWKS)
+
+	// Public media such as avatars
+	r.GET("/media/*key", mediaHandler.Serve)
 
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
+	"markmywords-backend/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccessTokenHandler struct {
+	accessTokenService *services.AccessTokenService
+}
+
+func NewAccessTokenHandler() *AccessTokenHandler {
+	return &AccessTokenHandler{
+		accessTokenService: services.NewAccessTokenService(),
+	}
+}
+
+func (h *AccessTokenHandler) CreateToken(c *gin.Context) {
+	var req types.CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	token, err := h.accessTokenService.CreateToken(&req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Access token created successfully",
+		"token":   token,
+	})
+}
+
+func (h *AccessTokenHandler) GetTokens(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	tokens, err := h.accessTokenService.GetUserTokens(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+func (h *AccessTokenHandler) RevokeToken(c *gin.Context) {
+	tokenID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	err = h.accessTokenService.RevokeToken(uint(tokenID), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Access token revoked successfully"})
+}
@@ -11,12 +11,14 @@ import (
 )
 
 type AuthHandler struct {
-	userService *services.UserService
+	userService    *services.UserService
+	sessionService *services.SessionService
 }
 
 func NewAuthHandler() *AuthHandler {
 	return &AuthHandler{
-		userService: services.NewUserService(),
+		userService:    services.NewUserService(),
+		sessionService: services.NewSessionService(),
 	}
 }
 
@@ -34,7 +36,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// Generate and return a token so the client can be authenticated immediately
-	token, tokenErr := auth.GenerateToken(user.ID, user.Email)
+	token, tokenErr := h.sessionService.CreateSession(user.ID, user.Email, clientInfo(c))
 	if tokenErr != nil {
 		c.JSON(http.StatusCreated, gin.H{
 			"message": "User registered successfully",
@@ -57,7 +59,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, token, err := h.userService.Login(&req)
+	user, token, err := h.userService.Login(&req, clientInfo(c))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -80,3 +82,15 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
+
+func (h *AuthHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth.DefaultKeyring().PublicJWKS())
+}
+
+func clientInfo(c *gin.Context) types.ClientInfo {
+	return types.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
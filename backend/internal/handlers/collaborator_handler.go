@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
+	"markmywords-backend/internal/types"
+	wsmanager "markmywords-backend/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CollaboratorHandler struct {
+	collaboratorService *services.CollaboratorService
+	manager             *wsmanager.Manager
+}
+
+func NewCollaboratorHandler() *CollaboratorHandler {
+	return &CollaboratorHandler{
+		collaboratorService: services.NewCollaboratorService(),
+		manager:             wsmanager.GetManager(),
+	}
+}
+
+func (h *CollaboratorHandler) GetCollaborators(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	collaborators, err := h.collaboratorService.GetCollaborators(uint(threadID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"collaborators": collaborators})
+}
+
+func (h *CollaboratorHandler) GetCollaborator(c *gin.Context) {
+	threadID, targetUserID, ok := parseCollaboratorParams(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	collaborator, err := h.collaboratorService.GetCollaborator(threadID, targetUserID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"collaborator": collaborator})
+}
+
+func (h *CollaboratorHandler) UpdateCollaborator(c *gin.Context) {
+	threadID, targetUserID, ok := parseCollaboratorParams(c)
+	if !ok {
+		return
+	}
+
+	var req types.UpdateCollaboratorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	collaborator, err := h.collaboratorService.UpdateRole(threadID, targetUserID, userID, req.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The client rejoins to pick up its new permissions
+	h.manager.RemoveUserFromThread(threadID, targetUserID, "role_changed")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Collaborator updated successfully",
+		"collaborator": collaborator,
+	})
+}
+
+func (h *CollaboratorHandler) RemoveCollaborator(c *gin.Context) {
+	threadID, targetUserID, ok := parseCollaboratorParams(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	err := h.collaboratorService.RemoveCollaborator(threadID, targetUserID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.manager.RemoveUserFromThread(threadID, targetUserID, "removed")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collaborator removed successfully"})
+}
+
+func (h *CollaboratorHandler) JoinThread(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	collaborator, err := h.collaboratorService.JoinThread(uint(threadID), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Joined thread successfully",
+		"collaborator": collaborator,
+	})
+}
+
+func (h *CollaboratorHandler) LeaveThread(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	err = h.collaboratorService.LeaveThread(uint(threadID), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.manager.RemoveUserFromThread(uint(threadID), userID, "left")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left thread successfully"})
+}
+
+func (h *CollaboratorHandler) GetActivity(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	activity, err := h.collaboratorService.GetActivity(uint(threadID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"activity": activity})
+}
+
+func parseCollaboratorParams(c *gin.Context) (uint, uint, bool) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return 0, 0, false
+	}
+
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, 0, false
+	}
+
+	return uint(threadID), uint(userID), true
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag is the entity tag of a thread or note version.
+func etag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// ifMatchVersion reads the version an update was made against from the
+// If-Match header. "*" matches any version and is returned as 0. Updates
+// without the header are refused, so clients cannot overwrite changes they
+// have not seen.
+func ifMatchVersion(c *gin.Context) (uint, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header with the version being updated is required"})
+		return 0, false
+	}
+	if header == "*" {
+		return 0, true
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.ParseUint(tag, 10, 32)
+	if err != nil || version == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match header"})
+		return 0, false
+	}
+	return uint(version), true
+}
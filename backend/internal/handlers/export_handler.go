@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+func NewExportHandler() *ExportHandler {
+	return &ExportHandler{
+		exportService: services.NewExportService(),
+	}
+}
+
+func (h *ExportHandler) RequestExport(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	export, err := h.exportService.RequestExport(userID)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Export started, you will receive an email when it is ready",
+		"export":  export,
+	})
+}
+
+func (h *ExportHandler) GetExports(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	exports, err := h.exportService.GetUserExports(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"exports": exports})
+}
+
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	exportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	export, err := h.exportService.GetExport(uint(exportID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"export": export})
+}
+
+// Download serves the archive to anyone holding a valid signed link.
+func (h *ExportHandler) Download(c *gin.Context) {
+	exportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	object, err := h.exportService.OpenDownload(uint(exportID), c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	defer object.Body.Close()
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="markmywords-export-%d.zip"`, exportID))
+	c.Header("Cache-Control", "private, no-store")
+	if object.Size >= 0 {
+		c.Header("Content-Length", strconv.FormatInt(object.Size, 10))
+	}
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, object.Body)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
+	"markmywords-backend/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FolderHandler struct {
+	folderService *services.FolderService
+}
+
+func NewFolderHandler() *FolderHandler {
+	return &FolderHandler{
+		folderService: services.NewFolderService(),
+	}
+}
+
+func (h *FolderHandler) GetFolders(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	folders, err := h.folderService.GetFolders(userID, middleware.GetWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"folders": folders})
+}
+
+func (h *FolderHandler) CreateFolder(c *gin.Context) {
+	var req types.CreateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	folder, err := h.folderService.CreateFolder(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Folder created successfully",
+		"folder":  folder,
+	})
+}
+
+func (h *FolderHandler) UpdateFolder(c *gin.Context) {
+	folderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	var req types.UpdateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	folder, err := h.folderService.UpdateFolder(uint(folderID), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Folder updated successfully",
+		"folder":  folder,
+	})
+}
+
+func (h *FolderHandler) DeleteFolder(c *gin.Context) {
+	folderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	if err := h.folderService.DeleteFolder(uint(folderID), userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Folder deleted successfully"})
+}
+
+func (h *FolderHandler) MoveThread(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	var req types.MoveThreadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	thread, err := h.folderService.MoveThread(uint(threadID), userID, req.FolderID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Thread moved successfully",
+		"thread":  thread,
+	})
+}
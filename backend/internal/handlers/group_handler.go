@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
+	"markmywords-backend/internal/types"
+	wsmanager "markmywords-backend/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupHandler struct {
+	groupService *services.GroupService
+	manager      *wsmanager.Manager
+}
+
+func NewGroupHandler() *GroupHandler {
+	return &GroupHandler{
+		groupService: services.NewGroupService(),
+		manager:      wsmanager.GetManager(),
+	}
+}
+
+func (h *GroupHandler) GetGroups(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	groups, err := h.groupService.GetGroups(userID, middleware.GetWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	var req types.CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	group, err := h.groupService.CreateGroup(userID, middleware.GetWorkspaceID(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Group created successfully",
+		"group":   group,
+	})
+}
+
+func (h *GroupHandler) GetGroup(c *gin.Context) {
+	groupID, ok := parseGroupID(c, "id")
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	group, err := h.groupService.GetGroup(groupID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"group": group})
+}
+
+func (h *GroupHandler) UpdateGroup(c *gin.Context) {
+	groupID, ok := parseGroupID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req types.UpdateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	group, err := h.groupService.UpdateGroup(groupID, userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Group updated successfully",
+		"group":   group,
+	})
+}
+
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	groupID, ok := parseGroupID(c, "id")
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	revoked, err := h.groupService.DeleteGroup(groupID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.removeFromThreads(revoked)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group deleted successfully"})
+}
+
+func (h *GroupHandler) GetMembers(c *gin.Context) {
+	groupID, ok := parseGroupID(c, "id")
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	members, err := h.groupService.GetMembers(groupID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+func (h *GroupHandler) AddMember(c *gin.Context) {
+	groupID, ok := parseGroupID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req types.AddGroupMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	member, err := h.groupService.AddMember(groupID, userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Member added successfully",
+		"member":  member,
+	})
+}
+
+func (h *GroupHandler) UpdateMember(c *gin.Context) {
+	groupID, targetUserID, ok := parseGroupMemberParams(c)
+	if !ok {
+		return
+	}
+
+	var req types.UpdateGroupMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	member, err := h.groupService.UpdateMemberRole(groupID, targetUserID, userID, req.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member updated successfully",
+		"member":  member,
+	})
+}
+
+func (h *GroupHandler) RemoveMember(c *gin.Context) {
+	groupID, targetUserID, ok := parseGroupMemberParams(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	revoked, err := h.groupService.RemoveMember(groupID, targetUserID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.removeFromThreads(revoked)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+func (h *GroupHandler) GetThreadGroups(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	groups, err := h.groupService.GetThreadGroups(uint(threadID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+func (h *GroupHandler) GrantGroup(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	var req types.GrantGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	group, err := h.groupService.GrantGroup(uint(threadID), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Group granted successfully",
+		"group":   group,
+	})
+}
+
+func (h *GroupHandler) UpdateGroupGrant(c *gin.Context) {
+	threadID, groupID, ok := parseThreadGroupParams(c)
+	if !ok {
+		return
+	}
+
+	var req types.UpdateGroupGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	group, memberIDs, err := h.groupService.UpdateGroupGrant(threadID, groupID, userID, req.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The members' clients rejoin to pick up their new permissions
+	for _, memberID := range memberIDs {
+		h.manager.RemoveUserFromThread(threadID, memberID, "role_changed")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Group updated successfully",
+		"group":   group,
+	})
+}
+
+func (h *GroupHandler) RevokeGroup(c *gin.Context) {
+	threadID, groupID, ok := parseThreadGroupParams(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	revoked, err := h.groupService.RevokeGroup(threadID, groupID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.removeFromThreads(revoked)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group revoked successfully"})
+}
+
+// removeFromThreads drops users who lost access from the threads' live
+// sessions.
+func (h *GroupHandler) removeFromThreads(revoked []services.RevokedAccess) {
+	for _, access := range revoked {
+		h.manager.RemoveUserFromThread(access.ThreadID, access.UserID, "removed")
+	}
+}
+
+func parseGroupID(c *gin.Context, param string) (uint, bool) {
+	groupID, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return 0, false
+	}
+	return uint(groupID), true
+}
+
+func parseGroupMemberParams(c *gin.Context) (uint, uint, bool) {
+	groupID, ok := parseGroupID(c, "id")
+	if !ok {
+		return 0, 0, false
+	}
+
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, 0, false
+	}
+
+	return groupID, uint(userID), true
+}
+
+func parseThreadGroupParams(c *gin.Context) (uint, uint, bool) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return 0, 0, false
+	}
+
+	groupID, ok := parseGroupID(c, "groupId")
+	if !ok {
+		return 0, 0, false
+	}
+
+	return uint(threadID), groupID, true
+}
@@ -103,7 +103,7 @@ func (h *InviteHandler) SearchUsers(c *gin.Context) {
 	}
 
 	currentUserID := middleware.GetUserID(c)
-	users, err := h.userService.SearchUsers(req.Query, currentUserID)
+	users, err := h.userService.SearchUsers(req.Query, currentUserID, middleware.GetWorkspaceID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
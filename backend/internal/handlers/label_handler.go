@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
+	"markmywords-backend/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LabelHandler struct {
+	labelService *services.LabelService
+}
+
+func NewLabelHandler() *LabelHandler {
+	return &LabelHandler{
+		labelService: services.NewLabelService(),
+	}
+}
+
+func (h *LabelHandler) GetLabels(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	labels, err := h.labelService.GetLabels(userID, middleware.GetWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"labels": labels})
+}
+
+func (h *LabelHandler) CreateLabel(c *gin.Context) {
+	var req types.CreateLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	label, err := h.labelService.CreateLabel(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Label created successfully",
+		"label":   label,
+	})
+}
+
+func (h *LabelHandler) UpdateLabel(c *gin.Context) {
+	labelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label ID"})
+		return
+	}
+
+	var req types.UpdateLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	label, err := h.labelService.UpdateLabel(uint(labelID), userID, middleware.GetWorkspaceID(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Label updated successfully",
+		"label":   label,
+	})
+}
+
+func (h *LabelHandler) DeleteLabel(c *gin.Context) {
+	labelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	if err := h.labelService.DeleteLabel(uint(labelID), userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Label deleted successfully"})
+}
+
+func (h *LabelHandler) AddThreadLabel(c *gin.Context) {
+	threadID, labelID, ok := parseThreadLabelIDs(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	thread, err := h.labelService.AddThreadLabel(threadID, labelID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Label added successfully",
+		"thread":  thread,
+	})
+}
+
+func (h *LabelHandler) RemoveThreadLabel(c *gin.Context) {
+	threadID, labelID, ok := parseThreadLabelIDs(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	thread, err := h.labelService.RemoveThreadLabel(threadID, labelID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Label removed successfully",
+		"thread":  thread,
+	})
+}
+
+func parseThreadLabelIDs(c *gin.Context) (uint, uint, bool) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return 0, 0, false
+	}
+
+	labelID, err := strconv.ParseUint(c.Param("labelId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label ID"})
+		return 0, 0, false
+	}
+
+	return uint(threadID), uint(labelID), true
+}
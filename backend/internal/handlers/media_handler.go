@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"markmywords-backend/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// publicMediaPrefixes lists the key prefixes that may be served without
+// authentication. Everything else in the store stays private.
+var publicMediaPrefixes = []string{"avatars/"}
+
+type MediaHandler struct {
+	store storage.BlobStore
+}
+
+func NewMediaHandler() *MediaHandler {
+	return &MediaHandler{
+		store: storage.Default(),
+	}
+}
+
+func (h *MediaHandler) Serve(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if !isPublicMedia(key) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+
+	object, err := h.store.Get(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to load media"})
+		return
+	}
+	defer object.Body.Close()
+
+	// Keys are never reused, so responses can be cached indefinitely
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Type", object.ContentType)
+	if object.Size >= 0 {
+		c.Header("Content-Length", strconv.FormatInt(object.Size, 10))
+	}
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, object.Body)
+}
+
+func isPublicMedia(key string) bool {
+	for _, prefix := range publicMediaPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
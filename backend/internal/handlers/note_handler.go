@@ -1,23 +1,27 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"markmywords-backend/internal/middleware"
 	"markmywords-backend/internal/services"
 	"markmywords-backend/internal/types"
+	wsmanager "markmywords-backend/internal/websocket"
 
 	"github.com/gin-gonic/gin"
 )
 
 type NoteHandler struct {
 	noteService *services.NoteService
+	manager     *wsmanager.Manager
 }
 
 func NewNoteHandler() *NoteHandler {
 	return &NoteHandler{
 		noteService: services.NewNoteService(),
+		manager:     wsmanager.GetManager(),
 	}
 }
 
@@ -35,6 +39,15 @@ func (h *NoteHandler) CreateNote(c *gin.Context) {
 		return
 	}
 
+	h.manager.BroadcastNoteAdded(&types.NoteAddMessage{ThreadID: note.ThreadID, Note: *note})
+	if recipient := h.noteService.ReplyRecipient(note); recipient != 0 {
+		h.manager.NotifyReply(recipient, &types.NoteReplyMessage{
+			ThreadID:     note.ThreadID,
+			ParentNoteID: *note.ParentNoteID,
+			Note:         *note,
+		})
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Note created successfully",
 		"note":    note,
@@ -48,14 +61,57 @@ func (h *NoteHandler) GetThreadNotes(c *gin.Context) {
 		return
 	}
 
+	var query types.NoteListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	userID := middleware.GetUserID(c)
-	notes, err := h.noteService.GetThreadNotes(uint(threadID), userID)
+	notes, nextCursor, err := h.noteService.GetThreadNotes(uint(threadID), userID, &query)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"notes": notes})
+	c.JSON(http.StatusOK, gin.H{
+		"notes":       notes,
+		"next_cursor": nextCursor,
+	})
+}
+
+func (h *NoteHandler) GetReplies(c *gin.Context) {
+	noteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return
+	}
+
+	var query types.NoteListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	replies, nextCursor, err := h.noteService.GetReplies(uint(noteID), userID, &query)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"replies":     replies,
+		"next_cursor": nextCursor,
+	})
 }
 
 func (h *NoteHandler) GetNote(c *gin.Context) {
@@ -72,6 +128,7 @@ func (h *NoteHandler) GetNote(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", etag(note.Version))
 	c.JSON(http.StatusOK, gin.H{"note": note})
 }
 
@@ -82,6 +139,11 @@ func (h *NoteHandler) UpdateNote(c *gin.Context) {
 		return
 	}
 
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	var req types.UpdateNoteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -89,12 +151,19 @@ func (h *NoteHandler) UpdateNote(c *gin.Context) {
 	}
 
 	userID := middleware.GetUserID(c)
-	note, err := h.noteService.UpdateNote(uint(noteID), userID, &req)
+	note, err := h.noteService.UpdateNote(uint(noteID), userID, version, &req)
+	if errors.Is(err, services.ErrVersionMismatch) {
+		h.versionMismatch(c, uint(noteID), userID)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.manager.BroadcastNoteUpdated(&types.NoteUpdateMessage{ThreadID: note.ThreadID, Note: *note})
+
+	c.Header("ETag", etag(note.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Note updated successfully",
 		"note":    note,
@@ -117,3 +186,169 @@ func (h *NoteHandler) DeleteNote(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Note deleted successfully"})
 }
+
+func (h *NoteHandler) GetRevisions(c *gin.Context) {
+	noteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	revisions, err := h.noteService.GetRevisions(uint(noteID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+func (h *NoteHandler) DiffRevisions(c *gin.Context) {
+	noteID, revision, ok := parseRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	var query types.NoteDiffQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	diff, err := h.noteService.DiffRevisions(noteID, revision, userID, &query)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"diff": diff})
+}
+
+func (h *NoteHandler) RestoreRevision(c *gin.Context) {
+	noteID, revision, ok := parseRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	note, err := h.noteService.RestoreRevision(noteID, revision, userID)
+	if errors.Is(err, services.ErrVersionMismatch) {
+		h.versionMismatch(c, noteID, userID)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.manager.BroadcastNoteUpdated(&types.NoteUpdateMessage{ThreadID: note.ThreadID, Note: *note})
+
+	c.Header("ETag", etag(note.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Note restored successfully",
+		"note":    note,
+	})
+}
+
+func (h *NoteHandler) AddReaction(c *gin.Context) {
+	noteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return
+	}
+
+	var req types.ReactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	reaction, err := h.noteService.AddReaction(uint(noteID), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if reaction != nil {
+		status = http.StatusCreated
+		h.manager.BroadcastReactionAdded(reaction)
+	}
+
+	h.respondWithReactions(c, status, "Reaction added successfully", uint(noteID), userID)
+}
+
+func (h *NoteHandler) RemoveReaction(c *gin.Context) {
+	noteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return
+	}
+
+	var req types.ReactionRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	reaction, err := h.noteService.RemoveReaction(uint(noteID), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.manager.BroadcastReactionRemoved(reaction)
+
+	h.respondWithReactions(c, http.StatusOK, "Reaction removed successfully", uint(noteID), userID)
+}
+
+// respondWithReactions answers a reaction change with the note's reactions
+// as they are now.
+func (h *NoteHandler) respondWithReactions(c *gin.Context, status int, message string, noteID, userID uint) {
+	note, err := h.noteService.GetNoteByID(noteID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(status, gin.H{
+		"message":   message,
+		"reactions": note.Reactions,
+	})
+}
+
+// versionMismatch answers an update that lost the race with the note as it
+// is now, so the client can merge and retry.
+func (h *NoteHandler) versionMismatch(c *gin.Context, noteID, userID uint) {
+	current, err := h.noteService.GetNoteByID(noteID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", etag(current.Version))
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error": "Note " + services.ErrVersionMismatch.Error(),
+		"note":  current,
+	})
+}
+
+func parseRevisionParams(c *gin.Context) (uint, int, bool) {
+	noteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return 0, 0, false
+	}
+
+	revision, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || revision < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return 0, 0, false
+	}
+
+	return uint(noteID), revision, true
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+
+	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
+	"markmywords-backend/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCHandler struct {
+	oidcService *services.OIDCService
+}
+
+func NewOIDCHandler() *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: services.NewOIDCService(),
+	}
+}
+
+func (h *OIDCHandler) GetProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.oidcService.GetProviders()})
+}
+
+// bindingCookie keeps the secret that ties a login attempt to the browser
+// that started it, so a callback cannot be completed in another browser.
+const bindingCookie = "mmw_oidc_binding"
+
+func setBindingCookie(c *gin.Context, binding string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(bindingCookie, binding, maxAge, "/api/auth/oidc", "", c.Request.TLS != nil, true)
+}
+
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, binding, err := h.oidcService.StartLogin(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	setBindingCookie(c, binding, int(services.LoginStateTTL.Seconds()))
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// Link starts a flow that links an identity at the provider to the signed in
+// user's account.
+func (h *OIDCHandler) Link(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	authURL, binding, err := h.oidcService.StartLink(c.Param("provider"), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	setBindingCookie(c, binding, int(services.LoginStateTTL.Seconds()))
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var req types.OIDCCallbackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	binding, err := c.Cookie(bindingCookie)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired login state"})
+		return
+	}
+	setBindingCookie(c, "", -1)
+
+	user, token, err := h.oidcService.CompleteLogin(c.Param("provider"), &req, binding, clientInfo(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+		"user":    user,
+		"token":   token,
+	})
+}
+
+func (h *OIDCHandler) GetIdentities(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	identities, err := h.oidcService.GetUserIdentities(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
+	"markmywords-backend/internal/types"
+	wsmanager "markmywords-backend/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReadHandler struct {
+	readService *services.ReadService
+	manager     *wsmanager.Manager
+}
+
+func NewReadHandler() *ReadHandler {
+	return &ReadHandler{
+		readService: services.NewReadService(),
+		manager:     wsmanager.GetManager(),
+	}
+}
+
+func (h *ReadHandler) MarkRead(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	// The body is optional; without a note the whole thread is marked read
+	var req types.MarkReadRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userID := middleware.GetUserID(c)
+	marker, receipt, err := h.readService.MarkRead(uint(threadID), userID, req.NoteID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if receipt != nil {
+		h.manager.BroadcastNoteRead(receipt)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Thread marked as read successfully",
+		"read_marker": marker,
+	})
+}
+
+func (h *ReadHandler) GetUnread(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	summary, err := h.readService.GetUnreadSummary(userID, middleware.GetWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread": summary})
+}
+
+func (h *ReadHandler) GetNoteReceipts(c *gin.Context) {
+	noteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	receipts, err := h.readService.GetNoteReceipts(uint(noteID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"receipts": receipts})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
+	"markmywords-backend/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShareLinkHandler struct {
+	shareLinkService *services.ShareLinkService
+}
+
+func NewShareLinkHandler() *ShareLinkHandler {
+	return &ShareLinkHandler{
+		shareLinkService: services.NewShareLinkService(),
+	}
+}
+
+func (h *ShareLinkHandler) CreateLink(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	var req types.CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	link, err := h.shareLinkService.CreateLink(uint(threadID), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Share link created successfully",
+		"link":    link,
+	})
+}
+
+func (h *ShareLinkHandler) GetLinks(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	links, err := h.shareLinkService.GetThreadLinks(uint(threadID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+func (h *ShareLinkHandler) RevokeLink(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	linkID, err := strconv.ParseUint(c.Param("linkId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share link ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	err = h.shareLinkService.RevokeLink(uint(threadID), uint(linkID), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share link revoked successfully"})
+}
+
+func (h *ShareLinkHandler) Join(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	thread, err := h.shareLinkService.JoinByLink(c.Param("token"), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Joined thread successfully",
+		"thread":  thread,
+	})
+}
+
+// GetSharedThread is public; the token in the path is the only credential.
+func (h *ShareLinkHandler) GetSharedThread(c *gin.Context) {
+	shared, err := h.shareLinkService.GetSharedThread(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"thread": shared})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -29,7 +30,7 @@ func (h *ThreadHandler) CreateThread(c *gin.Context) {
 	}
 
 	userID := middleware.GetUserID(c)
-	thread, err := h.threadService.CreateThread(&req, userID)
+	thread, err := h.threadService.CreateThread(&req, userID, middleware.GetWorkspaceID(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -42,14 +43,38 @@ func (h *ThreadHandler) CreateThread(c *gin.Context) {
 }
 
 func (h *ThreadHandler) GetThreads(c *gin.Context) {
+	var query types.ThreadListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.listThreads(c, &query)
+}
+
+func (h *ThreadHandler) GetPublicThreads(c *gin.Context) {
+	var query types.PublicThreadsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	userID := middleware.GetUserID(c)
-	threads, err := h.threadService.GetUserThreads(userID)
+	query.WorkspaceID = middleware.GetWorkspaceID(c)
+	threads, nextCursor, err := h.threadService.GetPublicThreads(userID, &query)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"threads": threads})
+	c.JSON(http.StatusOK, gin.H{
+		"threads":     threads,
+		"next_cursor": nextCursor,
+	})
 }
 
 func (h *ThreadHandler) GetThread(c *gin.Context) {
@@ -66,6 +91,7 @@ func (h *ThreadHandler) GetThread(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", etag(thread.Version))
 	c.JSON(http.StatusOK, gin.H{"thread": thread})
 }
 
@@ -76,6 +102,11 @@ func (h *ThreadHandler) UpdateThread(c *gin.Context) {
 		return
 	}
 
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	var req types.UpdateThreadRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -83,12 +114,27 @@ func (h *ThreadHandler) UpdateThread(c *gin.Context) {
 	}
 
 	userID := middleware.GetUserID(c)
-	thread, err := h.threadService.UpdateThread(uint(threadID), userID, &req)
+	thread, err := h.threadService.UpdateThread(uint(threadID), userID, version, &req)
+	if errors.Is(err, services.ErrVersionMismatch) {
+		// Hand back the current copy so the client can merge and retry
+		current, err := h.threadService.GetThreadByID(uint(threadID), userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("ETag", etag(current.Version))
+		c.JSON(http.StatusPreconditionFailed, gin.H{
+			"error":  "Thread " + services.ErrVersionMismatch.Error(),
+			"thread": current,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.Header("ETag", etag(thread.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Thread updated successfully",
 		"thread":  thread,
@@ -113,12 +159,74 @@ func (h *ThreadHandler) DeleteThread(c *gin.Context) {
 }
 
 func (h *ThreadHandler) GetCollaborativeThreads(c *gin.Context) {
+	var query types.ThreadListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Kept for older clients; the same as GET /threads?scope=shared
+	query.Scope = "shared"
+	h.listThreads(c, &query)
+}
+
+func (h *ThreadHandler) listThreads(c *gin.Context, query *types.ThreadListQuery) {
 	userID := middleware.GetUserID(c)
-	threads, err := h.threadService.GetCollaborativeThreads(userID)
+	query.WorkspaceID = middleware.GetWorkspaceID(c)
+	threads, nextCursor, err := h.threadService.ListThreads(userID, query)
+	if errors.Is(err, services.ErrInvalidCursor) || errors.Is(err, services.ErrInvalidLabels) || errors.Is(err, services.ErrInvalidFolder) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"threads": threads})
+	c.JSON(http.StatusOK, gin.H{
+		"threads":     threads,
+		"next_cursor": nextCursor,
+	})
+}
+
+func (h *ThreadHandler) UpdatePreferences(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	var req types.UpdateThreadPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	thread, err := h.threadService.UpdatePreferences(uint(threadID), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Preferences updated successfully",
+		"thread":  thread,
+	})
+}
+
+func (h *ThreadHandler) ReorderThreads(c *gin.Context) {
+	var req types.ReorderThreadsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	if err := h.threadService.ReorderThreads(userID, req.ThreadIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Threads reordered successfully"})
 }
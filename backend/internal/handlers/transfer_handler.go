@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
+	"markmywords-backend/internal/types"
+	wsmanager "markmywords-backend/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransferHandler struct {
+	transferService *services.TransferService
+	manager         *wsmanager.Manager
+}
+
+func NewTransferHandler() *TransferHandler {
+	return &TransferHandler{
+		transferService: services.NewTransferService(),
+		manager:         wsmanager.GetManager(),
+	}
+}
+
+func (h *TransferHandler) RequestTransfer(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	var req types.CreateTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	transfer, err := h.transferService.RequestTransfer(uint(threadID), req.ToUserID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Transfer requested successfully",
+		"transfer": transfer,
+	})
+}
+
+func (h *TransferHandler) GetTransfers(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	transfers, err := h.transferService.GetUserTransfers(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transfers": transfers})
+}
+
+func (h *TransferHandler) AcceptTransfer(c *gin.Context) {
+	transferID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	transfer, err := h.transferService.AcceptTransfer(uint(transferID), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Both sides rejoin to pick up their new roles
+	h.manager.RemoveUserFromThread(transfer.ThreadID, transfer.FromUser.ID, "role_changed")
+	h.manager.RemoveUserFromThread(transfer.ThreadID, userID, "role_changed")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Transfer accepted successfully",
+		"transfer": transfer,
+	})
+}
+
+func (h *TransferHandler) DeclineTransfer(c *gin.Context) {
+	transferID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	err = h.transferService.DeclineTransfer(uint(transferID), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Transfer declined successfully"})
+}
+
+func (h *TransferHandler) CancelTransfer(c *gin.Context) {
+	transferID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	err = h.transferService.CancelTransfer(uint(transferID), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Transfer cancelled successfully"})
+}
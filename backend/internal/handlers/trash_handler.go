@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrashHandler struct {
+	trashService *services.TrashService
+}
+
+func NewTrashHandler() *TrashHandler {
+	return &TrashHandler{
+		trashService: services.NewTrashService(),
+	}
+}
+
+func (h *TrashHandler) GetTrash(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	trash, err := h.trashService.GetTrash(userID, middleware.GetWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"trash": trash})
+}
+
+func (h *TrashHandler) RestoreThread(c *gin.Context) {
+	threadID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	thread, err := h.trashService.RestoreThread(uint(threadID), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Thread restored successfully",
+		"thread":  thread,
+	})
+}
+
+func (h *TrashHandler) RestoreNote(c *gin.Context) {
+	noteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	note, err := h.trashService.RestoreNote(uint(noteID), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Note restored successfully",
+		"note":    note,
+	})
+}
@@ -4,25 +4,41 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"strconv"
 
+	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
 	"markmywords-backend/internal/types"
 	wsmanager "markmywords-backend/internal/websocket"
+	"markmywords-backend/pkg/database"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
 type WebSocketHandler struct {
-	manager *wsmanager.Manager
+	manager     *wsmanager.Manager
+	permissions *services.PermissionChecker
+	readService *services.ReadService
+	userService *services.UserService
 }
 
 func NewWebSocketHandler() *WebSocketHandler {
 	return &WebSocketHandler{
-		manager: wsmanager.GetManager(),
+		manager:     wsmanager.GetManager(),
+		permissions: services.NewPermissionChecker(database.GetDB()),
+		readService: services.NewReadService(),
+		userService: services.NewUserService(),
 	}
 }
 
+// clientMessageTypes are the messages a client may send over its socket.
+var clientMessageTypes = map[string]bool{
+	"thread_join":  true,
+	"thread_leave": true,
+	"user_typing":  true,
+	"thread_read":  true,
+}
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // Allow all origins for development
@@ -30,16 +46,11 @@ var upgrader = websocket.Upgrader{
 }
 
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
-	// Get user ID from query parameter
-	userIDStr := c.Query("user_id")
-	if userIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
-		return
-	}
-
-	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	// The user is the one the token belongs to, never one named by the client
+	userID := middleware.GetUserID(c)
+	user, err := h.userService.GetUserByID(userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 		return
 	}
 
@@ -52,7 +63,7 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 
 	// Create WebSocket client
 	client := &types.WebSocketConnection{
-		UserID: uint(userID),
+		UserID: userID,
 		Conn:   conn,
 	}
 
@@ -75,7 +86,81 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 			continue
 		}
 
+		// Clients may only join, leave, type and mark threads read; every
+		// other event is sent by the server alone
+		if !clientMessageTypes[wsMessage.Type] {
+			continue
+		}
+
+		// Joining, leaving and typing always act as the socket's own user
+		switch wsMessage.Type {
+		case "thread_join", "thread_leave", "user_typing":
+			if !stampSender(&wsMessage, user) {
+				continue
+			}
+		}
+
+		// Only users who can read a thread may join its live session
+		if wsMessage.Type == "thread_join" && !h.canJoin(&wsMessage, userID) {
+			continue
+		}
+
+		// Read markers are stored and the receipt comes from the server
+		if wsMessage.Type == "thread_read" {
+			h.markRead(&wsMessage, userID)
+			continue
+		}
+
 		// Broadcast message
 		h.manager.BroadcastMessage(&wsMessage)
 	}
 }
+
+// stampSender overwrites the user a client message claims to come from with
+// the socket's user.
+func stampSender(message *types.WebSocketMessage, user *types.UserResponse) bool {
+	payload, ok := message.Payload.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	payload["user_id"] = user.ID
+	if message.Type == "user_typing" {
+		payload["username"] = user.Username
+	}
+	return true
+}
+
+func (h *WebSocketHandler) canJoin(message *types.WebSocketMessage, userID uint) bool {
+	var joinMsg types.ThreadJoinMessage
+	data, err := json.Marshal(message.Payload)
+	if err != nil || json.Unmarshal(data, &joinMsg) != nil {
+		return false
+	}
+
+	_, _, err = h.permissions.Authorize(joinMsg.ThreadID, userID, services.ActionViewThread)
+	return err == nil
+}
+
+func (h *WebSocketHandler) markRead(message *types.WebSocketMessage, userID uint) {
+	var readMsg types.ThreadReadMessage
+	data, err := json.Marshal(message.Payload)
+	if err != nil || json.Unmarshal(data, &readMsg) != nil {
+		return
+	}
+
+	// Only the socket's own user can mark a thread read, and only they are
+	// named in the receipt
+	if readMsg.UserID != 0 && readMsg.UserID != userID {
+		return
+	}
+
+	_, receipt, err := h.readService.MarkRead(readMsg.ThreadID, userID, readMsg.NoteID)
+	if err != nil {
+		log.Printf("Error marking thread %d read: %v", readMsg.ThreadID, err)
+		return
+	}
+	if receipt != nil {
+		h.manager.BroadcastNoteRead(receipt)
+	}
+}
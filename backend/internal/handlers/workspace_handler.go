@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"markmywords-backend/internal/middleware"
+	"markmywords-backend/internal/services"
+	"markmywords-backend/internal/types"
+	wsmanager "markmywords-backend/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WorkspaceHandler struct {
+	workspaceService *services.WorkspaceService
+	manager          *wsmanager.Manager
+}
+
+func NewWorkspaceHandler() *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceService: services.NewWorkspaceService(),
+		manager:          wsmanager.GetManager(),
+	}
+}
+
+func (h *WorkspaceHandler) GetWorkspaces(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	workspaces, err := h.workspaceService.GetUserWorkspaces(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workspaces": workspaces})
+}
+
+func (h *WorkspaceHandler) CreateWorkspace(c *gin.Context) {
+	var req types.CreateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	workspace, err := h.workspaceService.CreateWorkspace(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Workspace created successfully",
+		"workspace": workspace,
+	})
+}
+
+func (h *WorkspaceHandler) GetWorkspace(c *gin.Context) {
+	workspaceID, ok := parseWorkspaceID(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	workspace, err := h.workspaceService.GetWorkspace(workspaceID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workspace": workspace})
+}
+
+func (h *WorkspaceHandler) UpdateWorkspace(c *gin.Context) {
+	workspaceID, ok := parseWorkspaceID(c)
+	if !ok {
+		return
+	}
+
+	var req types.UpdateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	workspace, err := h.workspaceService.UpdateWorkspace(workspaceID, userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Workspace updated successfully",
+		"workspace": workspace,
+	})
+}
+
+func (h *WorkspaceHandler) DeleteWorkspace(c *gin.Context) {
+	workspaceID, ok := parseWorkspaceID(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	if err := h.workspaceService.DeleteWorkspace(workspaceID, userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workspace deleted successfully"})
+}
+
+func (h *WorkspaceHandler) GetMembers(c *gin.Context) {
+	workspaceID, ok := parseWorkspaceID(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	members, err := h.workspaceService.GetMembers(workspaceID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+func (h *WorkspaceHandler) AddMember(c *gin.Context) {
+	workspaceID, ok := parseWorkspaceID(c)
+	if !ok {
+		return
+	}
+
+	var req types.AddWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	member, err := h.workspaceService.AddMember(workspaceID, userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Member added successfully",
+		"member":  member,
+	})
+}
+
+func (h *WorkspaceHandler) UpdateMember(c *gin.Context) {
+	workspaceID, targetUserID, ok := parseWorkspaceMemberParams(c)
+	if !ok {
+		return
+	}
+
+	var req types.UpdateWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	member, err := h.workspaceService.UpdateMemberRole(workspaceID, targetUserID, userID, req.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member updated successfully",
+		"member":  member,
+	})
+}
+
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	workspaceID, targetUserID, ok := parseWorkspaceMemberParams(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	threadIDs, err := h.workspaceService.RemoveMember(workspaceID, targetUserID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, threadID := range threadIDs {
+		h.manager.RemoveUserFromThread(threadID, targetUserID, "removed")
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+func parseWorkspaceID(c *gin.Context) (uint, bool) {
+	workspaceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace ID"})
+		return 0, false
+	}
+	return uint(workspaceID), true
+}
+
+func parseWorkspaceMemberParams(c *gin.Context) (uint, uint, bool) {
+	workspaceID, ok := parseWorkspaceID(c)
+	if !ok {
+		return 0, 0, false
+	}
+
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, 0, false
+	}
+
+	return workspaceID, uint(userID), true
+}
@@ -2,14 +2,19 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
+	"markmywords-backend/internal/services"
+	"markmywords-backend/internal/types"
 	"markmywords-backend/pkg/auth"
 
 	"github.com/gin-gonic/gin"
 )
 
 func AuthMiddleware() gin.HandlerFunc {
+	authenticate := tokenAuthenticator()
+
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -26,7 +31,54 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		token := tokenParts[1]
+		authenticate(c, tokenParts[1])
+	}
+}
+
+// WebSocketAuthMiddleware authenticates a WebSocket upgrade. Browsers cannot
+// set headers on the upgrade request, so the token may also be passed as the
+// token query parameter.
+func WebSocketAuthMiddleware() gin.HandlerFunc {
+	authenticate := tokenAuthenticator()
+
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+			token = strings.TrimPrefix(authHeader, "Bearer ")
+		}
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header or token required"})
+			c.Abort()
+			return
+		}
+
+		authenticate(c, token)
+	}
+}
+
+// tokenAuthenticator checks a session JWT or personal access token and sets
+// the user it belongs to on the context, or aborts with 401.
+func tokenAuthenticator() func(c *gin.Context, token string) {
+	accessTokenService := services.NewAccessTokenService()
+	sessionService := services.NewSessionService()
+
+	return func(c *gin.Context, token string) {
+		// Personal access tokens carry their own scopes
+		if auth.IsAccessToken(token) {
+			pat, err := accessTokenService.Authenticate(token)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				c.Abort()
+				return
+			}
+
+			c.Set("user_id", pat.UserID)
+			c.Set("email", pat.User.Email)
+			c.Set("token_scopes", pat.ScopeList())
+			c.Next()
+			return
+		}
+
 		claims, err := auth.ValidateToken(token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -34,13 +86,117 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// The session may have been revoked before the token expired
+		session, err := sessionService.ValidateSession(claims.ID, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired"})
+			c.Abort()
+			return
+		}
+
 		// Set user info in context
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("session_id", session.ID)
+		c.Next()
+	}
+}
+
+// RequireScopes restricts personal access tokens to the given scopes. Safe
+// methods need the read scope and everything else needs the write scope.
+// Requests authenticated with a session JWT are not restricted.
+func RequireScopes(read, write types.TokenScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		required := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			required = read
+		}
+
+		if !HasScope(c, required) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token is missing required scope: " + string(required)})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope restricts personal access tokens to one scope whatever the
+// method, for routes such as searches that read through a POST.
+func RequireScope(scope types.TokenScope) gin.HandlerFunc {
+	return RequireScopes(scope, scope)
+}
+
+// RequireSession rejects requests authenticated with a personal access token.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isToken := c.Get("token_scopes"); isToken {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint cannot be used with an access token"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
 
+// RequireAdmin restricts a route to site administrators.
+func RequireAdmin() gin.HandlerFunc {
+	adminService := services.NewAdminService()
+
+	return func(c *gin.Context) {
+		if !adminService.IsAdmin(GetUserID(c)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// WorkspaceContext reads the workspace a request works in from the
+// X-Workspace-ID header and checks that the user belongs to it. Without the
+// header the request works on the user's personal threads.
+func WorkspaceContext() gin.HandlerFunc {
+	workspaceService := services.NewWorkspaceService()
+
+	return func(c *gin.Context) {
+		header := c.GetHeader("X-Workspace-ID")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		workspaceID, err := strconv.ParseUint(header, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace ID"})
+			c.Abort()
+			return
+		}
+
+		if !workspaceService.IsMember(uint(workspaceID), GetUserID(c)) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+			c.Abort()
+			return
+		}
+
+		c.Set("workspace_id", uint(workspaceID))
+		c.Next()
+	}
+}
+
+func HasScope(c *gin.Context, scope types.TokenScope) bool {
+	scopes, exists := c.Get("token_scopes")
+	if !exists {
+		return true
+	}
+	for _, s := range scopes.([]types.TokenScope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 func GetUserID(c *gin.Context) uint {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -49,6 +205,26 @@ func GetUserID(c *gin.Context) uint {
 	return userID.(uint)
 }
 
+// GetSessionID returns the login session of the request, or 0 for requests
+// authenticated with an access token.
+func GetSessionID(c *gin.Context) uint {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return 0
+	}
+	return sessionID.(uint)
+}
+
+// GetWorkspaceID returns the workspace of the request, or 0 for personal
+// threads.
+func GetWorkspaceID(c *gin.Context) uint {
+	workspaceID, exists := c.Get("workspace_id")
+	if !exists {
+		return 0
+	}
+	return workspaceID.(uint)
+}
+
 func GetUserEmail(c *gin.Context) string {
 	email, exists := c.Get("email")
 	if !exists {
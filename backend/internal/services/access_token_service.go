@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/auth"
+	"markmywords-backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type AccessTokenService struct {
+	db *gorm.DB
+}
+
+func NewAccessTokenService() *AccessTokenService {
+	return &AccessTokenService{
+		db: database.GetDB(),
+	}
+}
+
+func (s *AccessTokenService) CreateToken(req *types.CreateAccessTokenRequest, userID uint) (*types.CreateAccessTokenResponse, error) {
+	var scopes []string
+	seen := make(map[types.TokenScope]bool)
+	for _, scope := range req.Scopes {
+		if !types.IsValidTokenScope(scope) {
+			return nil, fmt.Errorf("invalid scope: %s", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, string(scope))
+		}
+	}
+
+	plain, prefix, hash, err := auth.GenerateAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	token := types.PersonalAccessToken{
+		UserID:      userID,
+		Name:        req.Name,
+		TokenHash:   hash,
+		TokenPrefix: prefix,
+		Scopes:      strings.Join(scopes, " "),
+	}
+
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.db.Create(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return &types.CreateAccessTokenResponse{
+		AccessTokenResponse: toAccessTokenResponse(&token),
+		Token:               plain,
+	}, nil
+}
+
+func (s *AccessTokenService) GetUserTokens(userID uint) ([]types.AccessTokenResponse, error) {
+	var tokens []types.PersonalAccessToken
+	err := s.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []types.AccessTokenResponse
+	for i := range tokens {
+		responses = append(responses, toAccessTokenResponse(&tokens[i]))
+	}
+
+	return responses, nil
+}
+
+func (s *AccessTokenService) RevokeToken(tokenID, userID uint) error {
+	var token types.PersonalAccessToken
+	if err := s.db.First(&token, tokenID).Error; err != nil {
+		return errors.New("token not found")
+	}
+
+	if token.UserID != userID {
+		return errors.New("access denied")
+	}
+
+	return s.db.Delete(&token).Error
+}
+
+// Authenticate resolves a plain personal access token to its stored record and
+// records the time it was used.
+func (s *AccessTokenService) Authenticate(plain string) (*types.PersonalAccessToken, error) {
+	var token types.PersonalAccessToken
+	err := s.db.Where("token_hash = ?", auth.HashToken(plain)).
+		Preload("User").
+		First(&token).Error
+
+	if err != nil || token.User.ID == 0 || token.User.DeactivatedAt != nil {
+		return nil, errors.New("invalid token")
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, errors.New("token expired")
+	}
+
+	if err := s.db.Model(&token).UpdateColumn("last_used_at", now).Error; err != nil {
+		return nil, err
+	}
+	token.LastUsedAt = &now
+
+	return &token, nil
+}
+
+func toAccessTokenResponse(token *types.PersonalAccessToken) types.AccessTokenResponse {
+	return types.AccessTokenResponse{
+		ID:          token.ID,
+		Name:        token.Name,
+		TokenPrefix: token.TokenPrefix,
+		Scopes:      token.ScopeList(),
+		ExpiresAt:   token.ExpiresAt,
+		LastUsedAt:  token.LastUsedAt,
+		CreatedAt:   token.CreatedAt,
+	}
+}
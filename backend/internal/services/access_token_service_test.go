@@ -0,0 +1,127 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"markmywords-backend/internal/types"
+)
+
+func TestCreateTokenChecksScopes(t *testing.T) {
+	db := newTestDB(t)
+	user := createUser(t, db, "user")
+	tokens := NewAccessTokenService()
+
+	cases := []struct {
+		name   string
+		scopes []types.TokenScope
+		want   []types.TokenScope
+		ok     bool
+	}{
+		{"one scope", []types.TokenScope{types.ScopeThreadsRead}, []types.TokenScope{types.ScopeThreadsRead}, true},
+		{"duplicates", []types.TokenScope{types.ScopeNotesRead, types.ScopeNotesWrite, types.ScopeNotesRead}, []types.TokenScope{types.ScopeNotesRead, types.ScopeNotesWrite}, true},
+		{"unknown scope", []types.TokenScope{types.ScopeThreadsRead, "threads:admin"}, nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			created, err := tokens.CreateToken(&types.CreateAccessTokenRequest{Name: tc.name, Scopes: tc.scopes}, user.ID)
+			if !tc.ok {
+				if err == nil {
+					t.Fatal("created a token with an unknown scope")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("create token: %v", err)
+			}
+			if len(created.Scopes) != len(tc.want) {
+				t.Fatalf("scopes = %v, want %v", created.Scopes, tc.want)
+			}
+			for i := range tc.want {
+				if created.Scopes[i] != tc.want[i] {
+					t.Fatalf("scopes = %v, want %v", created.Scopes, tc.want)
+				}
+			}
+
+			// Only the hash of the token is stored
+			var stored types.PersonalAccessToken
+			db.First(&stored, created.ID)
+			if stored.TokenHash == created.Token || stored.TokenHash == "" {
+				t.Fatal("the plain token was stored")
+			}
+		})
+	}
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	db := newTestDB(t)
+	user := createUser(t, db, "user")
+	inactive := createUser(t, db, "inactive")
+	tokens := NewAccessTokenService()
+
+	create := func(userID uint) *types.CreateAccessTokenResponse {
+		t.Helper()
+		created, err := tokens.CreateToken(&types.CreateAccessTokenRequest{Name: "cli", Scopes: []types.TokenScope{types.ScopeThreadsRead}}, userID)
+		if err != nil {
+			t.Fatalf("create token: %v", err)
+		}
+		return created
+	}
+
+	valid := create(user.ID)
+	expired := create(user.ID)
+	db.Model(&types.PersonalAccessToken{}).Where("id = ?", expired.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	revoked := create(user.ID)
+	if err := tokens.RevokeToken(revoked.ID, user.ID); err != nil {
+		t.Fatalf("revoke token: %v", err)
+	}
+	deactivated := create(inactive.ID)
+	db.Model(inactive).Update("deactivated_at", time.Now())
+
+	cases := []struct {
+		name  string
+		plain string
+		ok    bool
+	}{
+		{"valid", valid.Token, true},
+		{"unknown", "mmw_pat_unknown", false},
+		{"expired", expired.Token, false},
+		{"revoked", revoked.Token, false},
+		{"deactivated user", deactivated.Token, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := tokens.Authenticate(tc.plain)
+			if !tc.ok {
+				if err == nil {
+					t.Fatal("the token was accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("authenticate: %v", err)
+			}
+			if token.UserID != user.ID || token.LastUsedAt == nil {
+				t.Fatalf("token = %+v, want user %d with a last use", token, user.ID)
+			}
+		})
+	}
+}
+
+func TestRevokeTokenOfAnotherUser(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	other := createUser(t, db, "other")
+	tokens := NewAccessTokenService()
+
+	created, err := tokens.CreateToken(&types.CreateAccessTokenRequest{Name: "cli", Scopes: []types.TokenScope{types.ScopeThreadsRead}}, owner.ID)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if err := tokens.RevokeToken(created.ID, other.ID); err == nil {
+		t.Fatal("another user revoked the token")
+	}
+	if _, err := tokens.Authenticate(created.Token); err != nil {
+		t.Fatalf("the token stopped working: %v", err)
+	}
+}
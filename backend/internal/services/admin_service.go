@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// AdminService holds the site administration actions.
+type AdminService struct {
+	db        *gorm.DB
+	sessions  *SessionService
+	transfers *TransferService
+}
+
+func NewAdminService() *AdminService {
+	return &AdminService{
+		db:        database.GetDB(),
+		sessions:  NewSessionService(),
+		transfers: NewTransferService(),
+	}
+}
+
+func (s *AdminService) IsAdmin(userID uint) bool {
+	var user types.User
+	if err := s.db.Select("id", "role", "deactivated_at").First(&user, userID).Error; err != nil {
+		return false
+	}
+	return user.Role == types.UserRoleAdmin && user.DeactivatedAt == nil
+}
+
+// DeactivateUser locks an account without deleting any of its data. Every
+// session is signed out and access tokens stop working.
+func (s *AdminService) DeactivateUser(targetUserID, adminID uint) error {
+	if targetUserID == adminID {
+		return errors.New("you cannot deactivate your own account")
+	}
+
+	var user types.User
+	if err := s.db.First(&user, targetUserID).Error; err != nil {
+		return errors.New("user not found")
+	}
+	if user.DeactivatedAt != nil {
+		return errors.New("user is already deactivated")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("deactivated_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return s.sessions.RevokeOtherSessions(tx, targetUserID, 0)
+	})
+}
+
+func (s *AdminService) ReactivateUser(targetUserID uint) error {
+	var user types.User
+	if err := s.db.First(&user, targetUserID).Error; err != nil {
+		return errors.New("user not found")
+	}
+	if user.DeactivatedAt == nil {
+		return errors.New("user is not deactivated")
+	}
+
+	return s.db.Model(&user).Update("deactivated_at", nil).Error
+}
+
+// GetUserThreads lists the threads a user owns, with their collaborators, so
+// an admin can pick new owners.
+func (s *AdminService) GetUserThreads(targetUserID uint) ([]types.ThreadResponse, error) {
+	var threads []types.Thread
+	err := s.db.Where("user_id = ?", targetUserID).
+		Preload("User").
+		Preload("Collaborators.User").
+		Order("created_at ASC").
+		Find(&threads).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []types.ThreadResponse
+	for i := range threads {
+		responses = append(responses, toThreadResponse(&threads[i], ""))
+	}
+
+	if err := attachNoteStats(s.db, responses); err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
+
+// TransferThread offers a deactivated owner's thread to one of its
+// collaborators. The recipient still has to accept.
+func (s *AdminService) TransferThread(threadID, toUserID, adminID uint) (*types.ThreadTransferResponse, error) {
+	var thread types.Thread
+	if err := s.db.Preload("User").First(&thread, threadID).Error; err != nil {
+		return nil, errThreadNotFound
+	}
+
+	if thread.User.DeactivatedAt == nil {
+		return nil, errors.New("only threads of deactivated accounts can be transferred by an admin")
+	}
+
+	return s.transfers.offer(&thread, toUserID, adminID)
+}
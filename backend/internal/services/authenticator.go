@@ -0,0 +1,190 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/auth"
+	"markmywords-backend/pkg/directory"
+
+	"gorm.io/gorm"
+)
+
+// errUnknownUser tells the login chain to try the next authenticator.
+var errUnknownUser = errors.New("unknown user")
+
+// Authenticator verifies a user's credentials against one backend.
+type Authenticator interface {
+	Name() string
+	// Authenticate returns the local user for valid credentials,
+	// errUnknownUser when the backend does not own this account, or any
+	// other error to stop the login.
+	Authenticate(email, password string) (*types.User, error)
+}
+
+// PasswordAuthenticator checks the bcrypt hash stored on local accounts.
+type PasswordAuthenticator struct {
+	db *gorm.DB
+}
+
+func NewPasswordAuthenticator(db *gorm.DB) *PasswordAuthenticator {
+	return &PasswordAuthenticator{db: db}
+}
+
+func (a *PasswordAuthenticator) Name() string {
+	return string(types.AuthSourceLocal)
+}
+
+func (a *PasswordAuthenticator) Authenticate(email, password string) (*types.User, error) {
+	var user types.User
+	if err := a.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, errUnknownUser
+	}
+
+	if user.AuthSource != "" && user.AuthSource != types.AuthSourceLocal {
+		return nil, errUnknownUser
+	}
+
+	if !auth.CheckPassword(password, user.Password) {
+		return nil, errors.New("invalid credentials")
+	}
+
+	return &user, nil
+}
+
+// LDAPAuthenticator binds against an LDAP / Active Directory server and keeps
+// a local shadow account for each directory user.
+type LDAPAuthenticator struct {
+	db     *gorm.DB
+	client *directory.Client
+}
+
+// NewLDAPAuthenticator returns nil when no directory is configured.
+func NewLDAPAuthenticator(db *gorm.DB) *LDAPAuthenticator {
+	cfg := directory.LoadConfig()
+	if cfg == nil {
+		return nil
+	}
+
+	return &LDAPAuthenticator{
+		db:     db,
+		client: directory.NewClient(cfg),
+	}
+}
+
+func (a *LDAPAuthenticator) Name() string {
+	return string(types.AuthSourceLDAP)
+}
+
+func (a *LDAPAuthenticator) Authenticate(email, password string) (*types.User, error) {
+	entry, err := a.client.Authenticate(email, password)
+	if errors.Is(err, directory.ErrEntryNotFound) {
+		return nil, errUnknownUser
+	}
+	if errors.Is(err, directory.ErrInvalidCredentials) {
+		return nil, errors.New("invalid credentials")
+	}
+	if errors.Is(err, directory.ErrAmbiguousEntry) {
+		// Never guess which of the entries the user meant
+		log.Printf("LDAP authentication: %s matches more than one directory entry", email)
+		return nil, directory.ErrAmbiguousEntry
+	}
+	if err != nil {
+		log.Printf("LDAP authentication error: %v", err)
+		return nil, errors.New("directory unavailable")
+	}
+
+	var user types.User
+	err = a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("external_dn = ? AND auth_source = ?", entry.DN, types.AuthSourceLDAP).First(&user).Error; err == nil {
+			return tx.Model(&user).Updates(a.attributes(entry, &user)).Error
+		}
+
+		// Never take over an account that belongs to another backend
+		var existing types.User
+		if err := tx.Where("email = ?", entry.Email).First(&existing).Error; err == nil {
+			return errors.New("an account with this email already exists")
+		}
+
+		username, err := uniqueUsername(tx, entry.Username, entry.Email)
+		if err != nil {
+			return err
+		}
+
+		user = types.User{
+			Email:      entry.Email,
+			Username:   username,
+			FirstName:  entry.FirstName,
+			LastName:   entry.LastName,
+			Role:       types.UserRole(a.client.RoleFor(entry, string(types.UserRoleUser))),
+			AuthSource: types.AuthSourceLDAP,
+			ExternalDN: entry.DN,
+		}
+		return tx.Create(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// SyncUsers refreshes profile attributes and roles of every LDAP-backed
+// account from the directory.
+func (a *LDAPAuthenticator) SyncUsers() error {
+	var users []types.User
+	if err := a.db.Where("auth_source = ?", types.AuthSourceLDAP).Find(&users).Error; err != nil {
+		return err
+	}
+
+	for i := range users {
+		user := &users[i]
+		entry, err := a.client.Lookup(user.ExternalDN)
+		if errors.Is(err, directory.ErrEntryNotFound) {
+			log.Printf("LDAP sync: entry for user %d no longer exists", user.ID)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := a.db.Model(user).Updates(a.attributes(entry, user)).Error; err != nil {
+			log.Printf("LDAP sync: failed to update user %d: %v", user.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// RunSync calls SyncUsers every interval until the process exits.
+func (a *LDAPAuthenticator) RunSync(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := a.SyncUsers(); err != nil {
+			log.Printf("LDAP sync failed: %v", err)
+		}
+	}
+}
+
+func (a *LDAPAuthenticator) attributes(entry *directory.Entry, user *types.User) map[string]interface{} {
+	updates := map[string]interface{}{
+		"first_name": entry.FirstName,
+		"last_name":  entry.LastName,
+		"role":       a.client.RoleFor(entry, string(types.UserRoleUser)),
+	}
+
+	// Only follow email changes that don't collide with another account
+	if entry.Email != "" && entry.Email != user.Email {
+		var count int64
+		a.db.Model(&types.User{}).Where("email = ? AND id != ?", entry.Email, user.ID).Count(&count)
+		if count == 0 {
+			updates["email"] = entry.Email
+		}
+	}
+
+	return updates
+}
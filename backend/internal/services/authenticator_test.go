@@ -0,0 +1,179 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/directory"
+	"markmywords-backend/pkg/directory/ldaptest"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ldapServiceDN = "cn=service,dc=example,dc=com"
+	ldapAdminsDN  = "cn=admins,ou=groups,dc=example,dc=com"
+)
+
+func ldapPerson(uid, email, password, firstName string, groups ...string) ldaptest.Entry {
+	return ldaptest.Entry{
+		DN:       "uid=" + uid + ",ou=people,dc=example,dc=com",
+		Password: password,
+		Attributes: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {uid},
+			"mail":        {email},
+			"givenName":   {firstName},
+			"sn":          {"Tester"},
+			"memberOf":    groups,
+		},
+	}
+}
+
+func newLDAPTestAuthenticator(t *testing.T, db *gorm.DB, entries ...ldaptest.Entry) (*ldaptest.Server, *LDAPAuthenticator) {
+	t.Helper()
+
+	entries = append(entries, ldaptest.Entry{DN: ldapServiceDN, Password: "service-secret"})
+	server, err := ldaptest.NewServer(entries...)
+	if err != nil {
+		t.Fatalf("start directory: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	return server, &LDAPAuthenticator{
+		db: db,
+		client: directory.NewClient(&directory.Config{
+			URL:           server.URL,
+			BindDN:        ldapServiceDN,
+			BindPassword:  "service-secret",
+			BaseDN:        "dc=example,dc=com",
+			UserFilter:    "(&(objectClass=person)(mail=%s))",
+			EmailAttr:     "mail",
+			UsernameAttr:  "uid",
+			FirstNameAttr: "givenName",
+			LastNameAttr:  "sn",
+			GroupAttr:     "memberOf",
+			GroupRoles:    map[string]string{ldapAdminsDN: string(types.UserRoleAdmin)},
+			Timeout:       5 * time.Second,
+		}),
+	}
+}
+
+// acceptAnyone stands in for a later authenticator in the chain.
+type acceptAnyone struct {
+	db *gorm.DB
+}
+
+func (a acceptAnyone) Name() string { return "anyone" }
+
+func (a acceptAnyone) Authenticate(email, password string) (*types.User, error) {
+	user := types.User{Email: email, Username: "anyone"}
+	return &user, a.db.Create(&user).Error
+}
+
+func TestLDAPLoginProvisionsUser(t *testing.T) {
+	db := newTestDB(t)
+	_, ldapAuth := newLDAPTestAuthenticator(t, db, ldapPerson("ada", "ada@example.com", "ada-secret", "Ada", ldapAdminsDN))
+
+	user, err := ldapAuth.Authenticate("ada@example.com", "ada-secret")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if user.AuthSource != types.AuthSourceLDAP || user.FirstName != "Ada" || user.Role != types.UserRoleAdmin {
+		t.Fatalf("user = %+v", user)
+	}
+
+	if _, err := ldapAuth.Authenticate("ada@example.com", "wrong"); err == nil {
+		t.Fatal("a wrong directory password was accepted")
+	}
+	if _, err := ldapAuth.Authenticate("nobody@example.com", "secret"); !errors.Is(err, errUnknownUser) {
+		t.Fatalf("unknown email: err = %v, want errUnknownUser", err)
+	}
+}
+
+func TestLoginStopsAtAmbiguousDirectoryEntry(t *testing.T) {
+	db := newTestDB(t)
+	_, ldapAuth := newLDAPTestAuthenticator(t, db,
+		ldapPerson("ada", "ada@example.com", "ada-secret", "Ada"),
+		ldapPerson("ada2", "ada@example.com", "other-secret", "Ada"),
+	)
+
+	users := &UserService{
+		db:             db,
+		authenticators: []Authenticator{NewPasswordAuthenticator(db), ldapAuth, acceptAnyone{db: db}},
+		sessions:       NewSessionService(),
+	}
+	_, _, err := users.Login(&types.LoginRequest{Email: "ada@example.com", Password: "ada-secret"}, types.ClientInfo{})
+	if !errors.Is(err, directory.ErrAmbiguousEntry) {
+		t.Fatalf("login: err = %v, want ErrAmbiguousEntry", err)
+	}
+
+	var count int64
+	db.Model(&types.User{}).Where("email = ?", "ada@example.com").Count(&count)
+	if count != 0 {
+		t.Fatal("the ambiguous login went on to another authenticator")
+	}
+}
+
+func TestSyncUsers(t *testing.T) {
+	db := newTestDB(t)
+	ada := ldapPerson("ada", "ada@example.com", "ada-secret", "Ada")
+	bob := ldapPerson("bob", "bob@example.com", "bob-secret", "Bob", ldapAdminsDN)
+	server, ldapAuth := newLDAPTestAuthenticator(t, db, ada, bob)
+
+	adaUser, err := ldapAuth.Authenticate("ada@example.com", "ada-secret")
+	if err != nil {
+		t.Fatalf("authenticate ada: %v", err)
+	}
+	bobUser, err := ldapAuth.Authenticate("bob@example.com", "bob-secret")
+	if err != nil {
+		t.Fatalf("authenticate bob: %v", err)
+	}
+
+	// Ada is renamed and made an admin, Bob leaves the directory
+	server.Remove(ada.DN)
+	server.Add(ldapPerson("ada", "ada.lovelace@example.com", "ada-secret", "Augusta", ldapAdminsDN))
+	server.Remove(bob.DN)
+
+	if err := ldapAuth.SyncUsers(); err != nil {
+		t.Fatalf("sync users: %v", err)
+	}
+
+	var synced types.User
+	db.First(&synced, adaUser.ID)
+	if synced.FirstName != "Augusta" || synced.Email != "ada.lovelace@example.com" || synced.Role != types.UserRoleAdmin {
+		t.Fatalf("synced ada = %+v", synced)
+	}
+
+	var kept types.User
+	db.First(&kept, bobUser.ID)
+	if kept.FirstName != "Bob" || kept.Role != types.UserRoleAdmin {
+		t.Fatalf("bob changed although his entry is gone: %+v", kept)
+	}
+}
+
+func TestSyncUsersKeepsTakenEmails(t *testing.T) {
+	db := newTestDB(t)
+	ada := ldapPerson("ada", "ada@example.com", "ada-secret", "Ada")
+	server, ldapAuth := newLDAPTestAuthenticator(t, db, ada)
+
+	adaUser, err := ldapAuth.Authenticate("ada@example.com", "ada-secret")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	local := createUser(t, db, "local")
+
+	server.Remove(ada.DN)
+	server.Add(ldapPerson("ada", local.Email, "ada-secret", "Ada"))
+	if err := ldapAuth.SyncUsers(); err != nil {
+		t.Fatalf("sync users: %v", err)
+	}
+
+	var synced types.User
+	db.First(&synced, adaUser.ID)
+	if synced.Email != "ada@example.com" {
+		t.Fatalf("sync moved ada to the email of another account: %q", synced.Email)
+	}
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"errors"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type CollaboratorService struct {
+	db          *gorm.DB
+	permissions *PermissionChecker
+}
+
+func NewCollaboratorService() *CollaboratorService {
+	db := database.GetDB()
+	return &CollaboratorService{
+		db:          db,
+		permissions: NewPermissionChecker(db),
+	}
+}
+
+// GetCollaborators lists the thread's owner followed by its collaborators.
+func (s *CollaboratorService) GetCollaborators(threadID, userID uint) ([]types.CollaboratorResponse, error) {
+	thread, _, err := s.permissions.Authorize(threadID, userID, ActionViewThread)
+	if err != nil {
+		return nil, err
+	}
+
+	var owner types.User
+	if err := s.db.First(&owner, thread.UserID).Error; err != nil {
+		return nil, err
+	}
+
+	var collaborators []types.ThreadCollaborator
+	err = s.db.Where("thread_id = ?", threadID).
+		Preload("User").
+		Order("created_at ASC").
+		Find(&collaborators).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []types.CollaboratorResponse{{
+		UserResponse: toUserResponse(&owner),
+		Role:         types.ThreadRoleOwner,
+		JoinedAt:     thread.CreatedAt,
+	}}
+	for i := range collaborators {
+		responses = append(responses, toCollaboratorResponse(&collaborators[i]))
+	}
+
+	return responses, nil
+}
+
+func (s *CollaboratorService) GetCollaborator(threadID, targetUserID, userID uint) (*types.CollaboratorResponse, error) {
+	if _, _, err := s.permissions.Authorize(threadID, userID, ActionViewThread); err != nil {
+		return nil, err
+	}
+
+	collaborator, err := s.findCollaborator(threadID, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := toCollaboratorResponse(collaborator)
+	return &response, nil
+}
+
+// UpdateRole changes a collaborator's role. Admins manage the roles below
+// their own; only the owner can change other admins or grant admin.
+func (s *CollaboratorService) UpdateRole(threadID, targetUserID, userID uint, role types.ThreadRole) (*types.CollaboratorResponse, error) {
+	_, actorRole, err := s.permissions.Authorize(threadID, userID, ActionInvite)
+	if err != nil {
+		return nil, err
+	}
+
+	if !role.IsAssignable() {
+		return nil, errors.New("invalid role")
+	}
+
+	collaborator, err := s.findCollaborator(threadID, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canManage(actorRole, collaborator.Role) {
+		return nil, errAccessDenied
+	}
+	if !actorRole.AtLeast(role) {
+		return nil, errors.New("cannot grant a role higher than your own")
+	}
+
+	if collaborator.Role == role {
+		response := toCollaboratorResponse(collaborator)
+		return &response, nil
+	}
+
+	fromRole := collaborator.Role
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(collaborator).Update("role", role).Error; err != nil {
+			return err
+		}
+		return recordActivity(tx, threadID, userID, types.ActivityCollaboratorRoleChanged, &targetUserID, fromRole, role)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	collaborator.Role = role
+	response := toCollaboratorResponse(collaborator)
+	return &response, nil
+}
+
+// RemoveCollaborator revokes a collaborator's access to the thread.
+func (s *CollaboratorService) RemoveCollaborator(threadID, targetUserID, userID uint) error {
+	if targetUserID == userID {
+		return errors.New("use leave to remove yourself from a thread")
+	}
+
+	_, actorRole, err := s.permissions.Authorize(threadID, userID, ActionInvite)
+	if err != nil {
+		return err
+	}
+
+	collaborator, err := s.findCollaborator(threadID, targetUserID)
+	if err != nil {
+		return err
+	}
+
+	if !canManage(actorRole, collaborator.Role) {
+		return errAccessDenied
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(collaborator).Error; err != nil {
+			return err
+		}
+		return recordActivity(tx, threadID, userID, types.ActivityCollaboratorRemoved, &targetUserID, collaborator.Role, "")
+	})
+}
+
+// JoinThread adds the user to a public thread as a commenter.
+func (s *CollaboratorService) JoinThread(threadID, userID uint) (*types.CollaboratorResponse, error) {
+	thread, role, err := s.permissions.Authorize(threadID, userID, ActionViewThread)
+	if err != nil {
+		return nil, err
+	}
+
+	if thread.IsPrivate {
+		return nil, errors.New("only public threads can be joined")
+	}
+	if role != "" {
+		return nil, errors.New("you are already a member of this thread")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		collaborator := types.ThreadCollaborator{
+			ThreadID: threadID,
+			UserID:   userID,
+			Role:     types.ThreadRoleCommenter,
+		}
+		if err := tx.Create(&collaborator).Error; err != nil {
+			return err
+		}
+		return recordActivity(tx, threadID, userID, types.ActivityCollaboratorJoined, nil, "", types.ThreadRoleCommenter)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCollaborator(threadID, userID, userID)
+}
+
+// LeaveThread removes the user's own membership. Owners must transfer the
+// thread before they can leave it, and access through a group ends with the
+// group membership or grant, not here.
+func (s *CollaboratorService) LeaveThread(threadID, userID uint) error {
+	thread, role, err := s.permissions.Authorize(threadID, userID, ActionViewThread)
+	if err != nil {
+		return err
+	}
+
+	if thread.UserID == userID {
+		return errors.New("the owner cannot leave the thread")
+	}
+
+	var collaborator types.ThreadCollaborator
+	if err := s.db.Where("thread_id = ? AND user_id = ?", threadID, userID).First(&collaborator).Error; err != nil {
+		if role != "" {
+			return errors.New("your access comes from a group; leave the group or ask for its access to be removed")
+		}
+		return errors.New("you are not a member of this thread")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&collaborator).Error; err != nil {
+			return err
+		}
+		return recordActivity(tx, threadID, userID, types.ActivityCollaboratorLeft, nil, collaborator.Role, "")
+	})
+}
+
+func (s *CollaboratorService) GetActivity(threadID, userID uint) ([]types.ThreadActivityResponse, error) {
+	if _, _, err := s.permissions.Authorize(threadID, userID, ActionViewThread); err != nil {
+		return nil, err
+	}
+
+	var activities []types.ThreadActivity
+	err := s.db.Where("thread_id = ?", threadID).
+		Preload("Actor").
+		Preload("TargetUser").
+		Preload("Group").
+		Order("created_at DESC, id DESC").
+		Limit(200).
+		Find(&activities).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []types.ThreadActivityResponse
+	for _, activity := range activities {
+		response := types.ThreadActivityResponse{
+			ID:        activity.ID,
+			ThreadID:  activity.ThreadID,
+			Action:    activity.Action,
+			FromRole:  activity.FromRole,
+			ToRole:    activity.ToRole,
+			CreatedAt: activity.CreatedAt,
+		}
+
+		if activity.Actor.ID != 0 {
+			response.Actor = toUserResponse(&activity.Actor)
+		}
+		if activity.TargetUser != nil && activity.TargetUser.ID != 0 {
+			target := toUserResponse(activity.TargetUser)
+			response.TargetUser = &target
+		}
+		if activity.Group != nil && activity.Group.ID != 0 {
+			response.Group = &types.GroupSummary{ID: activity.Group.ID, Name: activity.Group.Name}
+		}
+
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+func (s *CollaboratorService) findCollaborator(threadID, userID uint) (*types.ThreadCollaborator, error) {
+	var collaborator types.ThreadCollaborator
+	err := s.db.Where("thread_id = ? AND user_id = ?", threadID, userID).
+		Preload("User").
+		First(&collaborator).Error
+
+	if err != nil {
+		return nil, errors.New("collaborator not found")
+	}
+
+	return &collaborator, nil
+}
+
+// canManage reports whether an actor may change or remove a collaborator.
+// Owners manage everyone, admins manage everyone below admin.
+func canManage(actorRole, targetRole types.ThreadRole) bool {
+	if actorRole == types.ThreadRoleOwner {
+		return true
+	}
+	return actorRole.Rank() > targetRole.Rank()
+}
+
+// recordActivity appends an entry to the thread's membership history.
+func recordActivity(tx *gorm.DB, threadID, actorID uint, action types.ActivityAction, targetUserID *uint, fromRole, toRole types.ThreadRole) error {
+	return tx.Create(&types.ThreadActivity{
+		ThreadID:     threadID,
+		ActorID:      actorID,
+		Action:       action,
+		TargetUserID: targetUserID,
+		FromRole:     fromRole,
+		ToRole:       toRole,
+	}).Error
+}
+
+func toCollaboratorResponse(collaborator *types.ThreadCollaborator) types.CollaboratorResponse {
+	return types.CollaboratorResponse{
+		UserResponse: toUserResponse(&collaborator.User),
+		Role:         collaborator.Role,
+		JoinedAt:     collaborator.CreatedAt,
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"testing"
+
+	"markmywords-backend/internal/types"
+)
+
+func TestUpdateRoleLimits(t *testing.T) {
+	cases := []struct {
+		name   string
+		actor  types.ThreadRole
+		target types.ThreadRole
+		role   types.ThreadRole
+		ok     bool
+	}{
+		{"owner promotes to admin", types.ThreadRoleOwner, types.ThreadRoleEditor, types.ThreadRoleAdmin, true},
+		{"owner demotes an admin", types.ThreadRoleOwner, types.ThreadRoleAdmin, types.ThreadRoleViewer, true},
+		{"admin promotes to editor", types.ThreadRoleAdmin, types.ThreadRoleViewer, types.ThreadRoleEditor, true},
+		{"admin grants up to their own role", types.ThreadRoleAdmin, types.ThreadRoleEditor, types.ThreadRoleAdmin, true},
+		{"admin cannot change an admin", types.ThreadRoleAdmin, types.ThreadRoleAdmin, types.ThreadRoleViewer, false},
+		{"editor cannot change roles", types.ThreadRoleEditor, types.ThreadRoleViewer, types.ThreadRoleCommenter, false},
+		{"owner cannot be granted", types.ThreadRoleOwner, types.ThreadRoleEditor, types.ThreadRoleOwner, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestDB(t)
+			owner := createUser(t, db, "owner")
+			target := createUser(t, db, "target")
+			thread := createThread(t, owner.ID, 0, "Plans")
+			db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: target.ID, Role: tc.target})
+
+			actorID := owner.ID
+			if tc.actor != types.ThreadRoleOwner {
+				actor := createUser(t, db, "actor")
+				db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: actor.ID, Role: tc.actor})
+				actorID = actor.ID
+			}
+
+			updated, err := NewCollaboratorService().UpdateRole(thread.ID, target.ID, actorID, tc.role)
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("changed the %s to %s", tc.target, tc.role)
+				}
+				if count := countActivity(t, db, thread.ID, types.ActivityCollaboratorRoleChanged); count != 0 {
+					t.Fatalf("recorded %d role changes for a refused change", count)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("update role: %v", err)
+			}
+			if updated.Role != tc.role {
+				t.Fatalf("role = %s, want %s", updated.Role, tc.role)
+			}
+			if count := countActivity(t, db, thread.ID, types.ActivityCollaboratorRoleChanged); count != 1 {
+				t.Fatalf("recorded %d role changes, want 1", count)
+			}
+		})
+	}
+}
+
+func TestRemoveCollaborator(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	admin := createUser(t, db, "admin")
+	otherAdmin := createUser(t, db, "other-admin")
+	editor := createUser(t, db, "editor")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: admin.ID, Role: types.ThreadRoleAdmin})
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: otherAdmin.ID, Role: types.ThreadRoleAdmin})
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: editor.ID, Role: types.ThreadRoleEditor})
+
+	collaborators := NewCollaboratorService()
+	if err := collaborators.RemoveCollaborator(thread.ID, otherAdmin.ID, admin.ID); err == nil {
+		t.Fatal("an admin removed another admin")
+	}
+	if err := collaborators.RemoveCollaborator(thread.ID, admin.ID, admin.ID); err == nil {
+		t.Fatal("an admin removed themselves instead of leaving")
+	}
+	if err := collaborators.RemoveCollaborator(thread.ID, editor.ID, admin.ID); err != nil {
+		t.Fatalf("remove editor: %v", err)
+	}
+	if _, _, err := NewPermissionChecker(db).Authorize(thread.ID, editor.ID, ActionViewThread); err == nil {
+		t.Fatal("the removed editor can still read the thread")
+	}
+	if err := collaborators.RemoveCollaborator(thread.ID, otherAdmin.ID, owner.ID); err != nil {
+		t.Fatalf("owner removes admin: %v", err)
+	}
+	if count := countActivity(t, db, thread.ID, types.ActivityCollaboratorRemoved); count != 2 {
+		t.Fatalf("recorded %d removals, want 2", count)
+	}
+}
+
+func TestLeaveThread(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	viewer := createUser(t, db, "viewer")
+	stranger := createUser(t, db, "stranger")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: viewer.ID, Role: types.ThreadRoleViewer})
+
+	collaborators := NewCollaboratorService()
+	if err := collaborators.LeaveThread(thread.ID, owner.ID); err == nil {
+		t.Fatal("the owner left their own thread")
+	}
+	if err := collaborators.LeaveThread(thread.ID, stranger.ID); err == nil {
+		t.Fatal("a stranger left a thread they were not in")
+	}
+	if err := collaborators.LeaveThread(thread.ID, viewer.ID); err != nil {
+		t.Fatalf("leave thread: %v", err)
+	}
+	if err := collaborators.LeaveThread(thread.ID, viewer.ID); err == nil {
+		t.Fatal("left the same thread twice")
+	}
+
+	activity, err := collaborators.GetActivity(thread.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("get activity: %v", err)
+	}
+	if len(activity) != 1 || activity[0].Action != types.ActivityCollaboratorLeft || activity[0].Actor.ID != viewer.ID || activity[0].FromRole != types.ThreadRoleViewer {
+		t.Fatalf("activity = %+v, want the viewer leaving", activity)
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/auth"
+	"markmywords-backend/pkg/database"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestMain loads the process wide signing keys from a database of their own
+// that outlives the per-test databases.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "services-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "keys.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := db.AutoMigrate(&auth.KeyRecord{}); err != nil {
+		log.Fatal(err)
+	}
+
+	os.Setenv("JWT_SIGNING_ALG", "EdDSA")
+	os.Setenv("KEY_ENCRYPTION_SECRET", "services test secret")
+	if _, err := auth.InitKeyring(db); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestDB gives the test a fresh, migrated database and makes it the one
+// services are built on.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func createUser(t *testing.T, db *gorm.DB, name string) *types.User {
+	t.Helper()
+
+	user := types.User{
+		Email:     fmt.Sprintf("%s@example.com", name),
+		Username:  name,
+		Password:  "not-a-hash",
+		FirstName: name,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user %s: %v", name, err)
+	}
+	return &user
+}
+
+func createThread(t *testing.T, userID, workspaceID uint, title string) *types.ThreadResponse {
+	t.Helper()
+
+	thread, err := NewThreadService().CreateThread(&types.CreateThreadRequest{Title: title}, userID, workspaceID)
+	if err != nil {
+		t.Fatalf("create thread %s: %v", title, err)
+	}
+	return thread
+}
+
+func threadIDs(threads []types.ThreadResponse) []uint {
+	ids := make([]uint, len(threads))
+	for i := range threads {
+		ids[i] = threads[i].ID
+	}
+	return ids
+}
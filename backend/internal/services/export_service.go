@@ -0,0 +1,729 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/auth"
+	"markmywords-backend/pkg/config"
+	"markmywords-backend/pkg/database"
+	"markmywords-backend/pkg/mailer"
+	"markmywords-backend/pkg/storage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// exportTimeout is how long an export may stay pending or running before it is
+// taken to have died with the process building it.
+const exportTimeout = time.Hour
+
+const exportFailedMessage = "the export could not be created, please try again"
+
+type ExportService struct {
+	db        *gorm.DB
+	store     storage.BlobStore
+	mailer    mailer.Mailer
+	linkTTL   time.Duration
+	retention time.Duration
+}
+
+func NewExportService() *ExportService {
+	return &ExportService{
+		db:        database.GetDB(),
+		store:     storage.Default(),
+		mailer:    mailer.New(),
+		linkTTL:   config.GetDuration("EXPORT_LINK_TTL", time.Hour),
+		retention: config.GetDuration("EXPORT_RETENTION", 7*24*time.Hour),
+	}
+}
+
+// RequestExport queues a new export and builds it in the background.
+func (s *ExportService) RequestExport(userID uint) (*types.DataExportResponse, error) {
+	var count int64
+	s.db.Model(&types.DataExport{}).
+		Where("user_id = ? AND status IN ?", userID, []types.DataExportStatus{types.DataExportStatusPending, types.DataExportStatusRunning}).
+		Count(&count)
+	if count > 0 {
+		return nil, errors.New("an export is already in progress")
+	}
+
+	export := types.DataExport{
+		UserID: userID,
+		Status: types.DataExportStatusPending,
+	}
+
+	if err := s.db.Create(&export).Error; err != nil {
+		return nil, err
+	}
+
+	go s.run(export.ID)
+
+	return s.toDataExportResponse(&export), nil
+}
+
+func (s *ExportService) GetUserExports(userID uint) ([]types.DataExportResponse, error) {
+	var exports []types.DataExport
+	err := s.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&exports).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []types.DataExportResponse
+	for i := range exports {
+		responses = append(responses, *s.toDataExportResponse(&exports[i]))
+	}
+
+	return responses, nil
+}
+
+func (s *ExportService) GetExport(exportID, userID uint) (*types.DataExportResponse, error) {
+	var export types.DataExport
+	if err := s.db.First(&export, exportID).Error; err != nil {
+		return nil, errors.New("export not found")
+	}
+
+	if export.UserID != userID {
+		return nil, errors.New("access denied")
+	}
+
+	return s.toDataExportResponse(&export), nil
+}
+
+// OpenDownload checks a signed download link and opens the archive. The
+// caller must close the returned object's Body.
+func (s *ExportService) OpenDownload(exportID uint, token string) (*storage.Object, error) {
+	if err := auth.ValidateDownloadToken(token, exportResource(exportID)); err != nil {
+		return nil, errors.New("download link is invalid or has expired")
+	}
+
+	var export types.DataExport
+	if err := s.db.First(&export, exportID).Error; err != nil {
+		return nil, errors.New("export not found")
+	}
+
+	if export.Status != types.DataExportStatusCompleted {
+		return nil, errors.New("export is not available")
+	}
+
+	return s.store.Get(context.Background(), export.BlobKey)
+}
+
+// DeleteUserExports removes every archive of the user, e.g. when the account
+// is deleted.
+func (s *ExportService) DeleteUserExports(userID uint) error {
+	var exports []types.DataExport
+	if err := s.db.Where("user_id = ?", userID).Find(&exports).Error; err != nil {
+		return err
+	}
+
+	for i := range exports {
+		s.expire(&exports[i])
+	}
+	return nil
+}
+
+// RunCleanup deletes archives past their retention period and fails exports
+// that never finished, once at startup and then every interval until the
+// process exits.
+func (s *ExportService) RunCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.cleanup()
+		<-ticker.C
+	}
+}
+
+func (s *ExportService) cleanup() {
+	// A restart or crash leaves the export pending or running for good, and
+	// that would block the user from requesting another one
+	result := s.db.Model(&types.DataExport{}).
+		Where("status IN ? AND updated_at < ?",
+			[]types.DataExportStatus{types.DataExportStatusPending, types.DataExportStatusRunning},
+			time.Now().Add(-exportTimeout)).
+		Updates(map[string]interface{}{
+			"status": types.DataExportStatusFailed,
+			"error":  exportFailedMessage,
+		})
+	if result.Error != nil {
+		log.Printf("Export cleanup failed: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("Export cleanup: failed %d stale exports", result.RowsAffected)
+	}
+
+	var exports []types.DataExport
+	err := s.db.Where("status = ? AND expires_at < ?", types.DataExportStatusCompleted, time.Now()).
+		Find(&exports).Error
+	if err != nil {
+		log.Printf("Export cleanup failed: %v", err)
+		return
+	}
+
+	for i := range exports {
+		s.expire(&exports[i])
+	}
+}
+
+func (s *ExportService) expire(export *types.DataExport) {
+	if export.BlobKey != "" {
+		if err := s.store.Delete(context.Background(), export.BlobKey); err != nil {
+			log.Printf("Failed to delete export %d: %v", export.ID, err)
+			return
+		}
+	}
+
+	if export.Status == types.DataExportStatusCompleted {
+		s.db.Model(export).Updates(map[string]interface{}{
+			"status":   types.DataExportStatusExpired,
+			"blob_key": "",
+		})
+	}
+}
+
+func (s *ExportService) run(exportID uint) {
+	var export types.DataExport
+	if err := s.db.First(&export, exportID).Error; err != nil {
+		log.Printf("Export %d: %v", exportID, err)
+		return
+	}
+
+	// A bug in the archive writer must not take the server down or leave the
+	// export running forever
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Export %d panicked: %v\n%s", exportID, r, debug.Stack())
+			s.fail(&export)
+		}
+	}()
+
+	s.db.Model(&export).Update("status", types.DataExportStatusRunning)
+
+	if err := s.build(&export); err != nil {
+		log.Printf("Export %d failed: %v", exportID, err)
+		s.fail(&export)
+		return
+	}
+
+	var user types.User
+	if err := s.db.First(&user, export.UserID).Error; err != nil {
+		return
+	}
+	if err := s.db.First(&export, exportID).Error; err != nil {
+		return
+	}
+
+	response := s.toDataExportResponse(&export)
+	body := fmt.Sprintf("Hi %s,\n\nYour MarkMyWords data export is ready. Download it here:\n\n%s\n\nThe link expires in %s. You can request a new link from your account settings until %s.\n",
+		user.FirstName, response.DownloadURL, s.linkTTL, export.ExpiresAt.Format("January 2, 2006"))
+
+	if err := s.mailer.Send(user.Email, "Your data export is ready", body); err != nil {
+		log.Printf("Export %d: failed to send email: %v", exportID, err)
+	}
+}
+
+func (s *ExportService) fail(export *types.DataExport) {
+	s.db.Model(export).Updates(map[string]interface{}{
+		"status": types.DataExportStatusFailed,
+		"error":  exportFailedMessage,
+	})
+}
+
+// build writes the archive to a temporary file and uploads it.
+func (s *ExportService) build(export *types.DataExport) error {
+	file, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := s.writeArchive(file, export.UserID); err != nil {
+		return err
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("exports/%d/%s.zip", export.UserID, uuid.NewString())
+	if err := s.store.Put(context.Background(), key, file, size, "application/zip"); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.retention)
+	return s.db.Model(export).Updates(map[string]interface{}{
+		"status":       types.DataExportStatusCompleted,
+		"blob_key":     key,
+		"size":         size,
+		"completed_at": now,
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+type exportProfile struct {
+	User         types.UserResponse               `json:"user"`
+	Role         types.UserRole                   `json:"role"`
+	AuthSource   types.AuthSource                 `json:"auth_source"`
+	UpdatedAt    time.Time                        `json:"updated_at"`
+	Identities   []types.ExternalIdentityResponse `json:"identities"`
+	AccessTokens []types.AccessTokenResponse      `json:"access_tokens"`
+}
+
+type exportNote struct {
+	ID           uint      `json:"id"`
+	Author       string    `json:"author"`
+	AuthorID     uint      `json:"author_id"`
+	Content      string    `json:"content"`
+	ParentNoteID *uint     `json:"parent_note_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type exportThread struct {
+	ID          uint         `json:"id"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	IsPrivate   bool         `json:"is_private"`
+	Owned       bool         `json:"owned"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Notes       []exportNote `json:"notes"`
+}
+
+type exportInvite struct {
+	ID        uint               `json:"id"`
+	Direction string             `json:"direction"`
+	ThreadID  uint               `json:"thread_id"`
+	Thread    string             `json:"thread"`
+	From      string             `json:"from"`
+	To        string             `json:"to"`
+	Status    types.InviteStatus `json:"status"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type exportSession struct {
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+func (s *ExportService) writeArchive(w io.Writer, userID uint) error {
+	var user types.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	files := []string{}
+	exportedAt := time.Now()
+
+	addFile := func(name, content string) error {
+		f, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: exportedAt,
+		})
+		if err != nil {
+			return err
+		}
+		files = append(files, name)
+		_, err = io.WriteString(f, content)
+		return err
+	}
+	addJSON := func(name string, value interface{}) error {
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		return addFile(name, string(data)+"\n")
+	}
+
+	// Profile
+	profile, err := s.collectProfile(&user)
+	if err != nil {
+		return err
+	}
+	if err := addJSON("profile.json", profile); err != nil {
+		return err
+	}
+	if err := addFile("profile.md", profileMarkdown(profile)); err != nil {
+		return err
+	}
+
+	// Owned threads with every note, then the user's notes elsewhere
+	owned, collaborative, err := s.collectThreads(userID)
+	if err != nil {
+		return err
+	}
+	for _, thread := range owned {
+		name := fmt.Sprintf("threads/%d-%s", thread.ID, slugify(thread.Title))
+		if err := addJSON(name+".json", thread); err != nil {
+			return err
+		}
+		if err := addFile(name+".md", threadMarkdown(thread)); err != nil {
+			return err
+		}
+	}
+	if err := addJSON("notes/collaborative.json", collaborative); err != nil {
+		return err
+	}
+	if err := addFile("notes/collaborative.md", collaborativeMarkdown(collaborative)); err != nil {
+		return err
+	}
+
+	// Invites and sessions
+	invites, err := s.collectInvites(userID)
+	if err != nil {
+		return err
+	}
+	if err := addJSON("invites.json", invites); err != nil {
+		return err
+	}
+	if err := addFile("invites.md", invitesMarkdown(invites)); err != nil {
+		return err
+	}
+
+	sessions, err := s.collectSessions(userID)
+	if err != nil {
+		return err
+	}
+	if err := addJSON("sessions.json", sessions); err != nil {
+		return err
+	}
+	if err := addFile("sessions.md", sessionsMarkdown(sessions)); err != nil {
+		return err
+	}
+
+	// Index of everything above
+	var readme strings.Builder
+	fmt.Fprintf(&readme, "# MarkMyWords data export\n\nExported for %s (%s) on %s.\n\n",
+		user.Username, user.Email, exportedAt.UTC().Format(time.RFC1123))
+	readme.WriteString("Every file is provided as JSON and as Markdown.\n\n")
+	for _, name := range files {
+		fmt.Fprintf(&readme, "- %s\n", name)
+	}
+	if err := addFile("README.md", readme.String()); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func (s *ExportService) collectProfile(user *types.User) (*exportProfile, error) {
+	profile := &exportProfile{
+		User: types.UserResponse{
+			ID:        user.ID,
+			Email:     user.Email,
+			Username:  user.Username,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			AvatarURL: user.AvatarURL,
+			CreatedAt: user.CreatedAt,
+		},
+		Role:       user.Role,
+		AuthSource: user.AuthSource,
+		UpdatedAt:  user.UpdatedAt,
+	}
+
+	var identities []types.ExternalIdentity
+	if err := s.db.Where("user_id = ?", user.ID).Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	for _, identity := range identities {
+		profile.Identities = append(profile.Identities, types.ExternalIdentityResponse{
+			ID:        identity.ID,
+			Provider:  identity.Provider,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt,
+		})
+	}
+
+	var tokens []types.PersonalAccessToken
+	if err := s.db.Where("user_id = ?", user.ID).Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	for i := range tokens {
+		profile.AccessTokens = append(profile.AccessTokens, toAccessTokenResponse(&tokens[i]))
+	}
+
+	return profile, nil
+}
+
+func (s *ExportService) collectThreads(userID uint) ([]exportThread, []exportThread, error) {
+	var ownedThreads []types.Thread
+	err := s.db.Where("user_id = ?", userID).
+		Preload("Notes", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Notes.User").
+		Order("created_at ASC").
+		Find(&ownedThreads).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var owned []exportThread
+	for _, thread := range ownedThreads {
+		owned = append(owned, toExportThread(thread, thread.Notes, true))
+	}
+
+	var notes []types.Note
+	err = s.db.Joins("JOIN threads ON threads.id = notes.thread_id AND threads.deleted_at IS NULL").
+		Where("notes.user_id = ? AND threads.user_id != ?", userID, userID).
+		Preload("Thread").
+		Preload("User").
+		Order("notes.thread_id ASC, notes.created_at ASC").
+		Find(&notes).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var collaborative []exportThread
+	for start := 0; start < len(notes); {
+		end := start
+		for end < len(notes) && notes[end].ThreadID == notes[start].ThreadID {
+			end++
+		}
+		collaborative = append(collaborative, toExportThread(notes[start].Thread, notes[start:end], false))
+		start = end
+	}
+
+	return owned, collaborative, nil
+}
+
+func (s *ExportService) collectInvites(userID uint) ([]exportInvite, error) {
+	var invites []types.Invite
+	err := s.db.Where("from_user_id = ? OR to_user_id = ?", userID, userID).
+		Preload("Thread").
+		Preload("FromUser").
+		Preload("ToUser").
+		Order("created_at ASC").
+		Find(&invites).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var exports []exportInvite
+	for _, invite := range invites {
+		direction := "received"
+		if invite.FromUserID == userID {
+			direction = "sent"
+		}
+		exports = append(exports, exportInvite{
+			ID:        invite.ID,
+			Direction: direction,
+			ThreadID:  invite.ThreadID,
+			Thread:    invite.Thread.Title,
+			From:      invite.FromUser.Username,
+			To:        invite.ToUser.Username,
+			Status:    invite.Status,
+			CreatedAt: invite.CreatedAt,
+		})
+	}
+
+	return exports, nil
+}
+
+func (s *ExportService) collectSessions(userID uint) ([]exportSession, error) {
+	var sessions []types.Session
+	if err := s.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	var exports []exportSession
+	for _, session := range sessions {
+		exports = append(exports, exportSession{
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			RevokedAt:  session.RevokedAt,
+		})
+	}
+
+	return exports, nil
+}
+
+func (s *ExportService) toDataExportResponse(export *types.DataExport) *types.DataExportResponse {
+	response := &types.DataExportResponse{
+		ID:          export.ID,
+		Status:      export.Status,
+		Size:        export.Size,
+		Error:       export.Error,
+		CompletedAt: export.CompletedAt,
+		ExpiresAt:   export.ExpiresAt,
+		CreatedAt:   export.CreatedAt,
+	}
+
+	// Every fetch hands out a fresh short-lived link
+	if export.Status == types.DataExportStatusCompleted {
+		token, expiresAt, err := auth.GenerateDownloadToken(exportResource(export.ID), s.linkTTL)
+		if err != nil {
+			log.Printf("Export %d: failed to sign download link: %v", export.ID, err)
+			return response
+		}
+		response.DownloadURL = fmt.Sprintf("%s/api/exports/%d/download?token=%s",
+			strings.TrimRight(config.Get("API_BASE_URL", "http://localhost:8080"), "/"), export.ID, url.QueryEscape(token))
+		response.DownloadExpiresAt = &expiresAt
+	}
+
+	return response
+}
+
+func exportResource(exportID uint) string {
+	return fmt.Sprintf("export:%d", exportID)
+}
+
+func toExportThread(thread types.Thread, notes []types.Note, owned bool) exportThread {
+	export := exportThread{
+		ID:          thread.ID,
+		Title:       thread.Title,
+		Description: thread.Description,
+		IsPrivate:   thread.IsPrivate,
+		Owned:       owned,
+		CreatedAt:   thread.CreatedAt,
+		Notes:       []exportNote{},
+	}
+
+	for _, note := range notes {
+		export.Notes = append(export.Notes, exportNote{
+			ID:           note.ID,
+			Author:       note.User.Username,
+			AuthorID:     note.UserID,
+			Content:      note.Content,
+			ParentNoteID: note.ParentNoteID,
+			CreatedAt:    note.CreatedAt,
+			UpdatedAt:    note.UpdatedAt,
+		})
+	}
+
+	return export
+}
+
+func slugify(title string) string {
+	slug := strings.Trim(slugInvalidChars.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 40 {
+		slug = strings.TrimRight(slug[:40], "-")
+	}
+	if slug == "" {
+		slug = "untitled"
+	}
+	return slug
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 MST")
+}
+
+func profileMarkdown(profile *exportProfile) string {
+	var b strings.Builder
+	user := profile.User
+	fmt.Fprintf(&b, "# Profile\n\n")
+	fmt.Fprintf(&b, "- **Username:** %s\n", user.Username)
+	fmt.Fprintf(&b, "- **Email:** %s\n", user.Email)
+	fmt.Fprintf(&b, "- **Name:** %s %s\n", user.FirstName, user.LastName)
+	fmt.Fprintf(&b, "- **Role:** %s\n", profile.Role)
+	fmt.Fprintf(&b, "- **Sign-in method:** %s\n", profile.AuthSource)
+	if user.AvatarURL != "" {
+		fmt.Fprintf(&b, "- **Avatar:** %s\n", user.AvatarURL)
+	}
+	fmt.Fprintf(&b, "- **Member since:** %s\n", formatTime(user.CreatedAt))
+
+	if len(profile.Identities) > 0 {
+		b.WriteString("\n## Linked identities\n\n")
+		for _, identity := range profile.Identities {
+			fmt.Fprintf(&b, "- %s (%s), linked %s\n", identity.Provider, identity.Email, formatTime(identity.CreatedAt))
+		}
+	}
+
+	if len(profile.AccessTokens) > 0 {
+		b.WriteString("\n## Access tokens\n\n")
+		for _, token := range profile.AccessTokens {
+			fmt.Fprintf(&b, "- %s (`%s…`), created %s\n", token.Name, token.TokenPrefix, formatTime(token.CreatedAt))
+		}
+	}
+
+	return b.String()
+}
+
+func threadMarkdown(thread exportThread) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", thread.Title)
+	if thread.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", thread.Description)
+	}
+	fmt.Fprintf(&b, "_Created %s_\n", formatTime(thread.CreatedAt))
+	writeNotesMarkdown(&b, thread.Notes)
+	return b.String()
+}
+
+func collaborativeMarkdown(threads []exportThread) string {
+	var b strings.Builder
+	b.WriteString("# Notes in threads shared with you\n")
+	if len(threads) == 0 {
+		b.WriteString("\nNone.\n")
+	}
+	for _, thread := range threads {
+		fmt.Fprintf(&b, "\n## %s\n", thread.Title)
+		writeNotesMarkdown(&b, thread.Notes)
+	}
+	return b.String()
+}
+
+func writeNotesMarkdown(b *strings.Builder, notes []exportNote) {
+	for _, note := range notes {
+		author := note.Author
+		if author == "" {
+			author = "Deleted user"
+		}
+		fmt.Fprintf(b, "\n---\n\n**%s** · %s\n\n%s\n", author, formatTime(note.CreatedAt), note.Content)
+	}
+}
+
+func invitesMarkdown(invites []exportInvite) string {
+	var b strings.Builder
+	b.WriteString("# Invites\n\n")
+	if len(invites) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, invite := range invites {
+		fmt.Fprintf(&b, "- %s: %s → %s for \"%s\" (%s), %s\n",
+			invite.Direction, invite.From, invite.To, invite.Thread, invite.Status, formatTime(invite.CreatedAt))
+	}
+	return b.String()
+}
+
+func sessionsMarkdown(sessions []exportSession) string {
+	var b strings.Builder
+	b.WriteString("# Sessions\n\n")
+	for _, session := range sessions {
+		fmt.Fprintf(&b, "- %s from %s, signed in %s, last seen %s\n",
+			session.UserAgent, session.IPAddress, formatTime(session.CreatedAt), formatTime(session.LastSeenAt))
+	}
+	return b.String()
+}
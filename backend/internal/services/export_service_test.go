@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"markmywords-backend/internal/types"
+
+	"gorm.io/gorm"
+)
+
+func createExport(t *testing.T, db *gorm.DB, userID uint, status types.DataExportStatus, age time.Duration) *types.DataExport {
+	t.Helper()
+
+	export := types.DataExport{UserID: userID, Status: status}
+	if err := db.Create(&export).Error; err != nil {
+		t.Fatalf("create export: %v", err)
+	}
+	db.Model(&export).UpdateColumn("updated_at", time.Now().Add(-age))
+	return &export
+}
+
+func exportStatus(t *testing.T, db *gorm.DB, exportID uint) types.DataExportStatus {
+	t.Helper()
+
+	var export types.DataExport
+	if err := db.First(&export, exportID).Error; err != nil {
+		t.Fatalf("load export: %v", err)
+	}
+	return export.Status
+}
+
+func TestCleanupFailsStaleExports(t *testing.T) {
+	db := newTestDB(t)
+	user := createUser(t, db, "user")
+
+	stalePending := createExport(t, db, user.ID, types.DataExportStatusPending, 2*exportTimeout)
+	staleRunning := createExport(t, db, user.ID, types.DataExportStatusRunning, 2*exportTimeout)
+	running := createExport(t, db, user.ID, types.DataExportStatusRunning, time.Minute)
+
+	(&ExportService{db: db}).cleanup()
+
+	cases := []struct {
+		name   string
+		export *types.DataExport
+		want   types.DataExportStatus
+	}{
+		{"stale pending", stalePending, types.DataExportStatusFailed},
+		{"stale running", staleRunning, types.DataExportStatusFailed},
+		{"running", running, types.DataExportStatusRunning},
+	}
+	for _, tc := range cases {
+		if got := exportStatus(t, db, tc.export.ID); got != tc.want {
+			t.Errorf("%s export is %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestExportPanicFailsExport(t *testing.T) {
+	db := newTestDB(t)
+	user := createUser(t, db, "user")
+	export := createExport(t, db, user.ID, types.DataExportStatusPending, 0)
+
+	// Without a store the upload panics
+	exports := &ExportService{db: db}
+	exports.run(export.ID)
+
+	if got := exportStatus(t, db, export.ID); got != types.DataExportStatusFailed {
+		t.Fatalf("export is %s after the panic, want failed", got)
+	}
+}
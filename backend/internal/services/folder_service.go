@@ -0,0 +1,336 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidFolder is returned for a folder filter that is not "none" or the
+// ID of one of the user's folders.
+var ErrInvalidFolder = errors.New("folder must be \"none\" or the ID of one of your folders")
+
+var errFolderNotFound = errors.New("folder not found")
+
+type FolderService struct {
+	db          *gorm.DB
+	permissions *PermissionChecker
+	threads     *ThreadService
+}
+
+func NewFolderService() *FolderService {
+	db := database.GetDB()
+	return &FolderService{
+		db:          db,
+		permissions: NewPermissionChecker(db),
+		threads:     NewThreadService(),
+	}
+}
+
+// GetFolders returns the user's folder tree, each level sorted by name, with
+// the number of threads of the workspace in each folder.
+func (s *FolderService) GetFolders(userID, workspaceID uint) ([]types.FolderResponse, error) {
+	tree, err := loadFolderTree(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		FolderID    uint
+		ThreadCount int
+	}
+	inWorkspaceScope(s.db.Model(&types.ThreadPreference{}), workspaceID).
+		Select("thread_preferences.folder_id, COUNT(*) AS thread_count").
+		Joins("JOIN threads ON threads.id = thread_preferences.thread_id AND threads.deleted_at IS NULL").
+		Where("thread_preferences.user_id = ? AND thread_preferences.folder_id IS NOT NULL", userID).
+		Group("thread_preferences.folder_id").
+		Scan(&counts)
+
+	countByFolder := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		countByFolder[count.FolderID] = count.ThreadCount
+	}
+
+	return tree.responses(nil, countByFolder), nil
+}
+
+func (s *FolderService) CreateFolder(userID uint, req *types.CreateFolderRequest) (*types.FolderResponse, error) {
+	name, err := folderName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := loadFolderTree(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.ParentID != nil && tree.folders[*req.ParentID] == nil {
+		return nil, errFolderNotFound
+	}
+	if err := tree.checkNameFree(req.ParentID, name, 0); err != nil {
+		return nil, err
+	}
+
+	folder := types.Folder{
+		UserID:   userID,
+		ParentID: req.ParentID,
+		Name:     name,
+	}
+	if err := s.db.Create(&folder).Error; err != nil {
+		return nil, err
+	}
+
+	tree.folders[folder.ID] = &folder
+	response := tree.response(&folder)
+	return &response, nil
+}
+
+// UpdateFolder renames a folder or moves it, along with everything in it,
+// under another folder or to the top of the tree.
+func (s *FolderService) UpdateFolder(folderID, userID uint, req *types.UpdateFolderRequest) (*types.FolderResponse, error) {
+	tree, err := loadFolderTree(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	folder := tree.folders[folderID]
+	if folder == nil {
+		return nil, errFolderNotFound
+	}
+
+	parentID := folder.ParentID
+	if req.MoveToRoot {
+		parentID = nil
+	} else if req.ParentID != nil {
+		if tree.folders[*req.ParentID] == nil {
+			return nil, errFolderNotFound
+		}
+		for _, id := range tree.descendants(folder.ID) {
+			if id == *req.ParentID {
+				return nil, errors.New("a folder cannot be moved into itself")
+			}
+		}
+		parentID = req.ParentID
+	}
+
+	name := folder.Name
+	if req.Name != "" {
+		if name, err = folderName(req.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tree.checkNameFree(parentID, name, folder.ID); err != nil {
+		return nil, err
+	}
+
+	folder.Name = name
+	folder.ParentID = parentID
+	if err := s.db.Save(folder).Error; err != nil {
+		return nil, err
+	}
+
+	response := tree.response(folder)
+	return &response, nil
+}
+
+// DeleteFolder deletes a folder. Its threads and subfolders move up to its
+// parent rather than being deleted with it.
+func (s *FolderService) DeleteFolder(folderID, userID uint) error {
+	tree, err := loadFolderTree(s.db, userID)
+	if err != nil {
+		return err
+	}
+	folder := tree.folders[folderID]
+	if folder == nil {
+		return errFolderNotFound
+	}
+
+	for _, child := range tree.children[folder.ID] {
+		if err := tree.checkNameFree(folder.ParentID, child.Name, folder.ID); err != nil {
+			return errors.New("a subfolder has the same name as a folder it would move next to")
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&types.ThreadPreference{}).
+			Where("user_id = ? AND folder_id = ?", userID, folder.ID).
+			Update("folder_id", folder.ParentID).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&types.Folder{}).
+			Where("user_id = ? AND parent_id = ?", userID, folder.ID).
+			Update("parent_id", folder.ParentID).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(folder).Error
+	})
+}
+
+// MoveThread files a thread the user can read into one of their folders, or
+// takes it out of its folder when folderID is nil.
+func (s *FolderService) MoveThread(threadID, userID uint, folderID *uint) (*types.ThreadResponse, error) {
+	if _, _, err := s.permissions.Authorize(threadID, userID, ActionViewThread); err != nil {
+		return nil, err
+	}
+
+	if folderID != nil {
+		var folder types.Folder
+		if err := s.db.Where("id = ? AND user_id = ?", *folderID, userID).First(&folder).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errFolderNotFound
+			}
+			return nil, err
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		pref, err := findOrCreatePreference(tx, threadID, userID)
+		if err != nil {
+			return err
+		}
+		return tx.Model(pref).Update("folder_id", folderID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.threads.GetThreadByID(threadID, userID)
+}
+
+// folderTree is one user's folders, indexed for walking up and down.
+type folderTree struct {
+	folders  map[uint]*types.Folder
+	children map[uint][]*types.Folder // 0 holds the top level folders
+}
+
+func loadFolderTree(db *gorm.DB, userID uint) (*folderTree, error) {
+	var folders []types.Folder
+	if err := db.Where("user_id = ?", userID).Order("name").Find(&folders).Error; err != nil {
+		return nil, err
+	}
+
+	tree := &folderTree{
+		folders:  make(map[uint]*types.Folder, len(folders)),
+		children: make(map[uint][]*types.Folder),
+	}
+	for i := range folders {
+		folder := &folders[i]
+		tree.folders[folder.ID] = folder
+
+		var parentID uint
+		if folder.ParentID != nil {
+			parentID = *folder.ParentID
+		}
+		tree.children[parentID] = append(tree.children[parentID], folder)
+	}
+	return tree, nil
+}
+
+// path joins the names from the top of the tree down to the folder.
+func (t *folderTree) path(folderID uint) string {
+	var names []string
+	for folder := t.folders[folderID]; folder != nil; {
+		names = append([]string{folder.Name}, names...)
+		if folder.ParentID == nil {
+			break
+		}
+		folder = t.folders[*folder.ParentID]
+	}
+	return strings.Join(names, "/")
+}
+
+// descendants is the folder and every folder below it.
+func (t *folderTree) descendants(folderID uint) []uint {
+	ids := []uint{folderID}
+	for _, child := range t.children[folderID] {
+		ids = append(ids, t.descendants(child.ID)...)
+	}
+	return ids
+}
+
+func (t *folderTree) checkNameFree(parentID *uint, name string, exceptID uint) error {
+	var key uint
+	if parentID != nil {
+		key = *parentID
+	}
+	for _, sibling := range t.children[key] {
+		if sibling.ID != exceptID && strings.EqualFold(sibling.Name, name) {
+			return errors.New("a folder with this name already exists here")
+		}
+	}
+	return nil
+}
+
+func (t *folderTree) responses(parentID *uint, counts map[uint]int) []types.FolderResponse {
+	var key uint
+	if parentID != nil {
+		key = *parentID
+	}
+
+	responses := []types.FolderResponse{}
+	for _, folder := range t.children[key] {
+		response := t.response(folder)
+		response.ThreadCount = counts[folder.ID]
+		response.Children = t.responses(&folder.ID, counts)
+		responses = append(responses, response)
+	}
+	return responses
+}
+
+func (t *folderTree) response(folder *types.Folder) types.FolderResponse {
+	return types.FolderResponse{
+		ID:        folder.ID,
+		ParentID:  folder.ParentID,
+		Name:      folder.Name,
+		Path:      t.path(folder.ID),
+		Children:  []types.FolderResponse{},
+		CreatedAt: folder.CreatedAt,
+		UpdatedAt: folder.UpdatedAt,
+	}
+}
+
+// folderName trims a folder name; slashes are kept out so paths stay
+// unambiguous.
+func folderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	if strings.Contains(name, "/") {
+		return "", errors.New("folder names cannot contain \"/\"")
+	}
+	return name, nil
+}
+
+// folderFilter resolves the folder filter of a thread listing to the folders
+// to list, or nil for unfiled threads.
+func folderFilter(db *gorm.DB, userID uint, folder string, recursive bool) ([]uint, error) {
+	if folder == "none" {
+		return nil, nil
+	}
+
+	id, err := strconv.ParseUint(folder, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidFolder
+	}
+
+	tree, err := loadFolderTree(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tree.folders[uint(id)] == nil {
+		return nil, ErrInvalidFolder
+	}
+
+	if recursive {
+		return tree.descendants(uint(id)), nil
+	}
+	return []uint{uint(id)}, nil
+}
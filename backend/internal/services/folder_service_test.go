@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"markmywords-backend/internal/types"
+)
+
+func createFolder(t *testing.T, userID uint, name string, parent *types.FolderResponse) *types.FolderResponse {
+	t.Helper()
+
+	req := &types.CreateFolderRequest{Name: name}
+	if parent != nil {
+		req.ParentID = &parent.ID
+	}
+	folder, err := NewFolderService().CreateFolder(userID, req)
+	if err != nil {
+		t.Fatalf("create folder %s: %v", name, err)
+	}
+	return folder
+}
+
+func fileThread(t *testing.T, threadID, userID uint, folder *types.FolderResponse) {
+	t.Helper()
+
+	if _, err := NewFolderService().MoveThread(threadID, userID, &folder.ID); err != nil {
+		t.Fatalf("file thread %d into %s: %v", threadID, folder.Name, err)
+	}
+}
+
+func TestCreateFolderChecksNames(t *testing.T) {
+	db := newTestDB(t)
+	alice := createUser(t, db, "alice")
+	bob := createUser(t, db, "bob")
+	work := createFolder(t, alice.ID, "Work", nil)
+	projects := createFolder(t, alice.ID, "Projects", work)
+	theirs := createFolder(t, bob.ID, "Theirs", nil)
+
+	if projects.Path != "Work/Projects" {
+		t.Fatalf("path = %q, want Work/Projects", projects.Path)
+	}
+
+	cases := []struct {
+		name    string
+		folder  string
+		parent  *uint
+		wantErr bool
+	}{
+		{"sibling with the same name", "work", nil, true},
+		{"same name in another folder", "Work", &work.ID, false},
+		{"slash in the name", "a/b", nil, true},
+		{"someone else's parent", "Inside", &theirs.ID, true},
+	}
+	folders := NewFolderService()
+	for _, tc := range cases {
+		_, err := folders.CreateFolder(alice.ID, &types.CreateFolderRequest{Name: tc.folder, ParentID: tc.parent})
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v, want error: %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestUpdateFolderMovesSubtree(t *testing.T) {
+	db := newTestDB(t)
+	alice := createUser(t, db, "alice")
+	work := createFolder(t, alice.ID, "Work", nil)
+	projects := createFolder(t, alice.ID, "Projects", work)
+	alpha := createFolder(t, alice.ID, "Alpha", projects)
+
+	folders := NewFolderService()
+	for _, parentID := range []uint{work.ID, alpha.ID} {
+		if _, err := folders.UpdateFolder(work.ID, alice.ID, &types.UpdateFolderRequest{ParentID: &parentID}); err == nil {
+			t.Errorf("moved Work into folder %d, which is inside it", parentID)
+		}
+	}
+
+	moved, err := folders.UpdateFolder(projects.ID, alice.ID, &types.UpdateFolderRequest{MoveToRoot: true, Name: "Archive"})
+	if err != nil {
+		t.Fatalf("move folder: %v", err)
+	}
+	if moved.ParentID != nil || moved.Path != "Archive" {
+		t.Fatalf("moved folder = %+v, want Archive at the top", moved)
+	}
+
+	tree, err := folders.GetFolders(alice.ID, 0)
+	if err != nil {
+		t.Fatalf("get folders: %v", err)
+	}
+	if len(tree) != 2 || tree[0].Name != "Archive" || len(tree[0].Children) != 1 || tree[0].Children[0].Path != "Archive/Alpha" {
+		t.Fatalf("tree = %+v, want Archive/Alpha next to Work", tree)
+	}
+}
+
+func TestDeleteFolderMovesContentsUp(t *testing.T) {
+	db := newTestDB(t)
+	alice := createUser(t, db, "alice")
+	work := createFolder(t, alice.ID, "Work", nil)
+	projects := createFolder(t, alice.ID, "Projects", work)
+	alpha := createFolder(t, alice.ID, "Alpha", projects)
+	thread := createThread(t, alice.ID, 0, "Plans")
+	fileThread(t, thread.ID, alice.ID, projects)
+
+	folders := NewFolderService()
+	clash := createFolder(t, alice.ID, "alpha", work)
+	if err := folders.DeleteFolder(projects.ID, alice.ID); err == nil {
+		t.Fatal("deleted a folder whose subfolder clashes with a folder next to it")
+	}
+	if err := folders.DeleteFolder(clash.ID, alice.ID); err != nil {
+		t.Fatalf("delete folder: %v", err)
+	}
+
+	if err := folders.DeleteFolder(projects.ID, alice.ID); err != nil {
+		t.Fatalf("delete folder: %v", err)
+	}
+
+	got, err := NewThreadService().GetThreadByID(thread.ID, alice.ID)
+	if err != nil {
+		t.Fatalf("get thread: %v", err)
+	}
+	if got.FolderPath != "Work" {
+		t.Errorf("thread filed in %q, want Work", got.FolderPath)
+	}
+
+	tree, err := folders.GetFolders(alice.ID, 0)
+	if err != nil {
+		t.Fatalf("get folders: %v", err)
+	}
+	if len(tree) != 1 || tree[0].ThreadCount != 1 || len(tree[0].Children) != 1 || tree[0].Children[0].ID != alpha.ID {
+		t.Fatalf("tree = %+v, want Work holding the thread and Alpha", tree)
+	}
+}
+
+func TestListThreadsFiltersByFolder(t *testing.T) {
+	db := newTestDB(t)
+	alice := createUser(t, db, "alice")
+	bob := createUser(t, db, "bob")
+	work := createFolder(t, alice.ID, "Work", nil)
+	projects := createFolder(t, alice.ID, "Projects", work)
+	theirs := createFolder(t, bob.ID, "Theirs", nil)
+	inWork := createThread(t, alice.ID, 0, "In work")
+	inProjects := createThread(t, alice.ID, 0, "In projects")
+	unfiled := createThread(t, alice.ID, 0, "Unfiled")
+	fileThread(t, inWork.ID, alice.ID, work)
+	fileThread(t, inProjects.ID, alice.ID, projects)
+
+	folder := func(f *types.FolderResponse) string {
+		return strconv.FormatUint(uint64(f.ID), 10)
+	}
+	cases := []struct {
+		name  string
+		query types.ThreadListQuery
+		want  []uint
+	}{
+		{"folder", types.ThreadListQuery{Folder: folder(work)}, []uint{inWork.ID}},
+		{"folder and below", types.ThreadListQuery{Folder: folder(work), Recursive: true}, []uint{inWork.ID, inProjects.ID}},
+		{"unfiled", types.ThreadListQuery{Folder: "none"}, []uint{unfiled.ID}},
+	}
+	threads := NewThreadService()
+	for _, tc := range cases {
+		listed, _, err := threads.ListThreads(alice.ID, &tc.query)
+		if err != nil {
+			t.Fatalf("%s: list threads: %v", tc.name, err)
+		}
+		if got := threadIDs(listed); !sameIDs(got, tc.want) {
+			t.Errorf("%s: threads = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"work", folder(theirs)} {
+		if _, _, err := threads.ListThreads(alice.ID, &types.ThreadListQuery{Folder: bad}); !errors.Is(err, ErrInvalidFolder) {
+			t.Errorf("folder=%s: err = %v, want %v", bad, err, ErrInvalidFolder)
+		}
+	}
+}
@@ -0,0 +1,720 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var errGroupNotFound = errors.New("group not found")
+
+// RevokedAccess is a user who lost their place on a thread and is to be
+// dropped from its live session.
+type RevokedAccess struct {
+	ThreadID uint
+	UserID   uint
+}
+
+type GroupService struct {
+	db          *gorm.DB
+	permissions *PermissionChecker
+}
+
+func NewGroupService() *GroupService {
+	db := database.GetDB()
+	return &GroupService{
+		db:          db,
+		permissions: NewPermissionChecker(db),
+	}
+}
+
+// GetGroups lists the groups the user can see in the workspace, by name: all
+// of a workspace's groups, or outside workspaces the groups they are in.
+func (s *GroupService) GetGroups(userID, workspaceID uint) ([]types.GroupResponse, error) {
+	db := s.db.Preload("Members").Order("user_groups.name")
+	if workspaceID == 0 {
+		db = db.Where("user_groups.workspace_id IS NULL").
+			Where("EXISTS (SELECT 1 FROM user_group_members WHERE user_group_members.group_id = user_groups.id AND user_group_members.user_id = ?)", userID)
+	} else {
+		db = db.Where("user_groups.workspace_id = ?", workspaceID)
+	}
+
+	var groups []types.UserGroup
+	if err := db.Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	responses := []types.GroupResponse{}
+	for i := range groups {
+		responses = append(responses, toGroupResponse(&groups[i], userID))
+	}
+	return responses, nil
+}
+
+// CreateGroup creates a group in the workspace with the user as its manager.
+// Only workspace admins create a workspace's groups.
+func (s *GroupService) CreateGroup(userID, workspaceID uint, req *types.CreateGroupRequest) (*types.GroupResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	group := types.UserGroup{
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		CreatedByID: userID,
+	}
+	if workspaceID != 0 {
+		if !workspaceRole(s.db, workspaceID, userID).AtLeast(types.WorkspaceRoleAdmin) {
+			return nil, errors.New("only workspace admins can create groups")
+		}
+		group.WorkspaceID = &workspaceID
+	}
+
+	if err := s.checkNameFree(&group, name); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+		return tx.Create(&types.UserGroupMember{
+			GroupID: group.ID,
+			UserID:  userID,
+			Role:    types.GroupRoleManager,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(group.ID, userID)
+}
+
+func (s *GroupService) GetGroup(groupID, userID uint) (*types.GroupResponse, error) {
+	group, err := s.findVisibleGroup(groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Where("group_id = ?", group.ID).Find(&group.Members).Error; err != nil {
+		return nil, err
+	}
+
+	response := toGroupResponse(group, userID)
+	return &response, nil
+}
+
+func (s *GroupService) UpdateGroup(groupID, userID uint, req *types.UpdateGroupRequest) (*types.GroupResponse, error) {
+	group, err := s.findManagedGroup(groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" && name != group.Name {
+		if err := s.checkNameFree(group, name); err != nil {
+			return nil, err
+		}
+		group.Name = name
+	}
+	if req.Description != nil {
+		group.Description = strings.TrimSpace(*req.Description)
+	}
+
+	if err := s.db.Save(group).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(group.ID, userID)
+}
+
+// DeleteGroup deletes the group and everything it was granted. Returns who
+// lost access to threads because of it.
+func (s *GroupService) DeleteGroup(groupID, userID uint) ([]RevokedAccess, error) {
+	group, err := s.findManagedGroup(groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := s.groupAccess(group.ID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return deleteGroups(tx, []uint{group.ID})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.revoked(candidates), nil
+}
+
+// GetMembers lists the group's members, the longest standing first.
+func (s *GroupService) GetMembers(groupID, userID uint) ([]types.GroupMemberResponse, error) {
+	group, err := s.findVisibleGroup(groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []types.UserGroupMember
+	err = s.db.Where("group_id = ?", group.ID).
+		Preload("User").
+		Order("created_at ASC").
+		Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []types.GroupMemberResponse{}
+	for i := range members {
+		responses = append(responses, toGroupMemberResponse(&members[i]))
+	}
+	return responses, nil
+}
+
+// AddMember adds a user to the group, giving them every thread it has been
+// granted. A workspace's groups only take its members.
+func (s *GroupService) AddMember(groupID, userID uint, req *types.AddGroupMemberRequest) (*types.GroupMemberResponse, error) {
+	group, err := s.findManagedGroup(groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = types.GroupRoleMember
+	}
+
+	var user types.User
+	if err := s.db.First(&user, req.UserID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.DeactivatedAt != nil {
+		return nil, errors.New("user is deactivated")
+	}
+	if group.WorkspaceID != nil && workspaceRole(s.db, *group.WorkspaceID, user.ID) == "" {
+		return nil, errors.New("user is not a member of this group's workspace")
+	}
+	if _, err := findGroupMember(s.db, group.ID, user.ID); err == nil {
+		return nil, errors.New("user is already a member of this group")
+	}
+
+	member := types.UserGroupMember{
+		GroupID: group.ID,
+		UserID:  user.ID,
+		Role:    role,
+	}
+	if err := s.db.Create(&member).Error; err != nil {
+		return nil, err
+	}
+	member.User = user
+
+	response := toGroupMemberResponse(&member)
+	return &response, nil
+}
+
+// UpdateMemberRole makes a member a manager or back. A group always keeps at
+// least one manager.
+func (s *GroupService) UpdateMemberRole(groupID, targetUserID, userID uint, role types.GroupRole) (*types.GroupMemberResponse, error) {
+	group, err := s.findManagedGroup(groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := findGroupMember(s.db, group.ID, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if member.Role == types.GroupRoleManager && role != types.GroupRoleManager {
+		var managers int64
+		s.db.Model(&types.UserGroupMember{}).Where("group_id = ? AND role = ?", group.ID, types.GroupRoleManager).Count(&managers)
+		if managers == 1 {
+			return nil, errors.New("a group needs at least one manager")
+		}
+	}
+
+	if err := s.db.Model(member).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+
+	response := toGroupMemberResponse(member)
+	return &response, nil
+}
+
+// RemoveMember takes a user out of the group, or lets a member leave. When
+// the last manager leaves, the longest standing member takes over. Returns
+// the threads the user lost access to.
+func (s *GroupService) RemoveMember(groupID, targetUserID, userID uint) ([]RevokedAccess, error) {
+	var group *types.UserGroup
+	var err error
+	if targetUserID == userID {
+		group, err = s.findVisibleGroup(groupID, userID)
+	} else {
+		group, err = s.findManagedGroup(groupID, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := findGroupMember(s.db, group.ID, targetUserID); err != nil {
+		return nil, err
+	}
+
+	candidates, err := s.groupAccess(group.ID, nil, &targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return leaveGroups(tx, targetUserID, []uint{group.ID})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.revoked(candidates), nil
+}
+
+// GetThreadGroups lists the groups granted on a thread.
+func (s *GroupService) GetThreadGroups(threadID, userID uint) ([]types.ThreadGroupResponse, error) {
+	if _, _, err := s.permissions.Authorize(threadID, userID, ActionViewThread); err != nil {
+		return nil, err
+	}
+
+	var grants []types.ThreadGroupGrant
+	err := s.db.Where("thread_id = ?", threadID).
+		Preload("Group.Members").
+		Preload("GrantedBy").
+		Order("created_at ASC").
+		Find(&grants).Error
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []types.ThreadGroupResponse{}
+	for i := range grants {
+		responses = append(responses, toThreadGroupResponse(&grants[i]))
+	}
+	return responses, nil
+}
+
+// GrantGroup gives every member of a group a role on the thread. The group
+// has to be one the user can see, in the thread's workspace, and nobody can
+// grant more than they have.
+func (s *GroupService) GrantGroup(threadID, userID uint, req *types.GrantGroupRequest) (*types.ThreadGroupResponse, error) {
+	thread, actorRole, err := s.permissions.Authorize(threadID, userID, ActionInvite)
+	if err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = types.ThreadRoleEditor
+	}
+	if !role.IsAssignable() {
+		return nil, errors.New("invalid role")
+	}
+	if !actorRole.AtLeast(role) {
+		return nil, errors.New("cannot grant a role higher than your own")
+	}
+
+	group, err := s.findVisibleGroup(req.GroupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !sameWorkspace(group.WorkspaceID, thread.WorkspaceID) {
+		return nil, errors.New("the group and the thread belong to different workspaces")
+	}
+
+	var existing int64
+	s.db.Model(&types.ThreadGroupGrant{}).Where("thread_id = ? AND group_id = ?", thread.ID, group.ID).Count(&existing)
+	if existing > 0 {
+		return nil, errors.New("group already has access to this thread")
+	}
+
+	grant := types.ThreadGroupGrant{
+		ThreadID:    thread.ID,
+		GroupID:     group.ID,
+		Role:        role,
+		GrantedByID: userID,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&grant).Error; err != nil {
+			return err
+		}
+		return recordGroupActivity(tx, thread.ID, userID, types.ActivityGroupGranted, group.ID, "", role)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.findThreadGroup(thread.ID, group.ID)
+}
+
+// UpdateGroupGrant changes the role a group has on the thread. Returns the
+// group's members, whose live sessions have to pick up the new role.
+func (s *GroupService) UpdateGroupGrant(threadID, groupID, userID uint, role types.ThreadRole) (*types.ThreadGroupResponse, []uint, error) {
+	_, actorRole, err := s.permissions.Authorize(threadID, userID, ActionInvite)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !role.IsAssignable() {
+		return nil, nil, errors.New("invalid role")
+	}
+
+	grant, err := s.findGrant(threadID, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !canManage(actorRole, grant.Role) {
+		return nil, nil, errAccessDenied
+	}
+	if !actorRole.AtLeast(role) {
+		return nil, nil, errors.New("cannot grant a role higher than your own")
+	}
+
+	if grant.Role != role {
+		fromRole := grant.Role
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(grant).Update("role", role).Error; err != nil {
+				return err
+			}
+			return recordGroupActivity(tx, threadID, userID, types.ActivityGroupRoleChanged, groupID, fromRole, role)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var memberIDs []uint
+	s.db.Model(&types.UserGroupMember{}).Where("group_id = ?", groupID).Pluck("user_id", &memberIDs)
+
+	response, err := s.findThreadGroup(threadID, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return response, memberIDs, nil
+}
+
+// RevokeGroup takes a group's access to the thread away. Returns the members
+// left without access.
+func (s *GroupService) RevokeGroup(threadID, groupID, userID uint) ([]RevokedAccess, error) {
+	_, actorRole, err := s.permissions.Authorize(threadID, userID, ActionInvite)
+	if err != nil {
+		return nil, err
+	}
+
+	grant, err := s.findGrant(threadID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if !canManage(actorRole, grant.Role) {
+		return nil, errAccessDenied
+	}
+
+	candidates, err := s.groupAccess(groupID, &threadID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(grant).Error; err != nil {
+			return err
+		}
+		return recordGroupActivity(tx, threadID, userID, types.ActivityGroupRevoked, groupID, grant.Role, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.revoked(candidates), nil
+}
+
+// findVisibleGroup loads a group the user can see: any group of their
+// workspaces, or a group outside workspaces they are in. Others are reported
+// as not found.
+func (s *GroupService) findVisibleGroup(groupID, userID uint) (*types.UserGroup, error) {
+	var group types.UserGroup
+	if err := s.db.First(&group, groupID).Error; err != nil {
+		return nil, errGroupNotFound
+	}
+
+	if group.WorkspaceID != nil {
+		if workspaceRole(s.db, *group.WorkspaceID, userID) == "" {
+			return nil, errGroupNotFound
+		}
+	} else if _, err := findGroupMember(s.db, group.ID, userID); err != nil {
+		return nil, errGroupNotFound
+	}
+	return &group, nil
+}
+
+// findManagedGroup loads a group the user manages. Workspace admins manage
+// all of their workspace's groups.
+func (s *GroupService) findManagedGroup(groupID, userID uint) (*types.UserGroup, error) {
+	group, err := s.findVisibleGroup(groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if member, err := findGroupMember(s.db, group.ID, userID); err == nil && member.Role == types.GroupRoleManager {
+		return group, nil
+	}
+	if group.WorkspaceID != nil && workspaceRole(s.db, *group.WorkspaceID, userID).AtLeast(types.WorkspaceRoleAdmin) {
+		return group, nil
+	}
+	return nil, errAccessDenied
+}
+
+// checkNameFree keeps group names unique within a workspace, and outside
+// workspaces among the groups one user created.
+func (s *GroupService) checkNameFree(group *types.UserGroup, name string) error {
+	db := s.db.Model(&types.UserGroup{}).Where("LOWER(name) = LOWER(?) AND id <> ?", name, group.ID)
+	if group.WorkspaceID != nil {
+		db = db.Where("workspace_id = ?", *group.WorkspaceID)
+	} else {
+		db = db.Where("workspace_id IS NULL AND created_by_id = ?", group.CreatedByID)
+	}
+
+	var count int64
+	db.Count(&count)
+	if count > 0 {
+		return errors.New("a group with this name already exists")
+	}
+	return nil
+}
+
+func (s *GroupService) findGrant(threadID, groupID uint) (*types.ThreadGroupGrant, error) {
+	var grant types.ThreadGroupGrant
+	if err := s.db.Where("thread_id = ? AND group_id = ?", threadID, groupID).First(&grant).Error; err != nil {
+		return nil, errors.New("group has no access to this thread")
+	}
+	return &grant, nil
+}
+
+func (s *GroupService) findThreadGroup(threadID, groupID uint) (*types.ThreadGroupResponse, error) {
+	var grant types.ThreadGroupGrant
+	err := s.db.Where("thread_id = ? AND group_id = ?", threadID, groupID).
+		Preload("Group.Members").
+		Preload("GrantedBy").
+		First(&grant).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := toThreadGroupResponse(&grant)
+	return &response, nil
+}
+
+// groupAccess pairs the group's threads with its members, optionally only
+// one thread or one member.
+func (s *GroupService) groupAccess(groupID uint, threadID, userID *uint) ([]RevokedAccess, error) {
+	db := s.db.Table("thread_group_grants").
+		Select("thread_group_grants.thread_id, user_group_members.user_id").
+		Joins("JOIN user_group_members ON user_group_members.group_id = thread_group_grants.group_id").
+		Where("thread_group_grants.group_id = ?", groupID)
+	if threadID != nil {
+		db = db.Where("thread_group_grants.thread_id = ?", *threadID)
+	}
+	if userID != nil {
+		db = db.Where("user_group_members.user_id = ?", *userID)
+	}
+
+	var access []RevokedAccess
+	if err := db.Scan(&access).Error; err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+// revoked keeps the pairs that no longer have a role on the thread.
+func (s *GroupService) revoked(candidates []RevokedAccess) []RevokedAccess {
+	threads := make(map[uint]*types.Thread)
+	var revoked []RevokedAccess
+	for _, access := range candidates {
+		thread, ok := threads[access.ThreadID]
+		if !ok {
+			thread = &types.Thread{}
+			if err := s.db.First(thread, access.ThreadID).Error; err != nil {
+				thread = nil
+			}
+			threads[access.ThreadID] = thread
+		}
+		if thread != nil && s.permissions.Role(thread, access.UserID) == "" {
+			revoked = append(revoked, access)
+		}
+	}
+	return revoked
+}
+
+func findGroupMember(db *gorm.DB, groupID, userID uint) (*types.UserGroupMember, error) {
+	var member types.UserGroupMember
+	if err := db.Where("group_id = ? AND user_id = ?", groupID, userID).Preload("User").First(&member).Error; err != nil {
+		return nil, errors.New("member not found")
+	}
+	return &member, nil
+}
+
+// sharedCondition matches the threads shared with a user, directly or
+// through one of their groups. It takes the user's ID twice.
+const sharedCondition = "(EXISTS (SELECT 1 FROM thread_collaborators WHERE thread_collaborators.thread_id = threads.id AND thread_collaborators.user_id = ? AND thread_collaborators.deleted_at IS NULL)" +
+	" OR EXISTS (SELECT 1 FROM thread_group_grants JOIN user_group_members ON user_group_members.group_id = thread_group_grants.group_id" +
+	" WHERE thread_group_grants.thread_id = threads.id AND user_group_members.user_id = ?))"
+
+// groupRoles returns the highest role the user's groups have on each of the
+// threads.
+func groupRoles(db *gorm.DB, userID uint, threadIDs []uint) map[uint]types.ThreadRole {
+	roles := make(map[uint]types.ThreadRole)
+	if len(threadIDs) == 0 {
+		return roles
+	}
+
+	var grants []types.ThreadGroupGrant
+	db.Joins("JOIN user_group_members ON user_group_members.group_id = thread_group_grants.group_id").
+		Where("thread_group_grants.thread_id IN ? AND user_group_members.user_id = ?", threadIDs, userID).
+		Find(&grants)
+
+	for _, grant := range grants {
+		if grant.Role.Rank() > roles[grant.ThreadID].Rank() {
+			roles[grant.ThreadID] = grant.Role
+		}
+	}
+	return roles
+}
+
+// groupMemberIDs lists everyone who reaches the thread through a group.
+func groupMemberIDs(db *gorm.DB, threadID uint) []uint {
+	var userIDs []uint
+	db.Model(&types.UserGroupMember{}).
+		Distinct("user_group_members.user_id").
+		Joins("JOIN thread_group_grants ON thread_group_grants.group_id = user_group_members.group_id").
+		Where("thread_group_grants.thread_id = ?", threadID).
+		Pluck("user_group_members.user_id", &userIDs)
+	return userIDs
+}
+
+// sameWorkspace reports whether two workspace IDs, nil for none, match.
+func sameWorkspace(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// leaveGroups takes the user out of the groups. A group left without a
+// manager passes to its longest standing member, and is deleted when nobody
+// is left.
+func leaveGroups(tx *gorm.DB, userID uint, groupIDs []uint) error {
+	if len(groupIDs) == 0 {
+		return nil
+	}
+
+	if err := tx.Where("user_id = ? AND group_id IN ?", userID, groupIDs).Delete(&types.UserGroupMember{}).Error; err != nil {
+		return err
+	}
+
+	var empty []uint
+	for _, groupID := range groupIDs {
+		var members []types.UserGroupMember
+		if err := tx.Where("group_id = ?", groupID).Order("created_at ASC").Find(&members).Error; err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			empty = append(empty, groupID)
+			continue
+		}
+
+		hasManager := false
+		for _, member := range members {
+			if member.Role == types.GroupRoleManager {
+				hasManager = true
+			}
+		}
+		if !hasManager {
+			if err := tx.Model(&members[0]).Update("role", types.GroupRoleManager).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return deleteGroups(tx, empty)
+}
+
+// deleteGroups deletes the groups with their members and grants.
+func deleteGroups(tx *gorm.DB, groupIDs []uint) error {
+	if len(groupIDs) == 0 {
+		return nil
+	}
+
+	if err := tx.Where("group_id IN ?", groupIDs).Delete(&types.ThreadGroupGrant{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("group_id IN ?", groupIDs).Delete(&types.UserGroupMember{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", groupIDs).Delete(&types.UserGroup{}).Error
+}
+
+// recordGroupActivity appends a group's change of access to the thread's
+// membership history.
+func recordGroupActivity(tx *gorm.DB, threadID, actorID uint, action types.ActivityAction, groupID uint, fromRole, toRole types.ThreadRole) error {
+	return tx.Create(&types.ThreadActivity{
+		ThreadID: threadID,
+		ActorID:  actorID,
+		Action:   action,
+		GroupID:  &groupID,
+		FromRole: fromRole,
+		ToRole:   toRole,
+	}).Error
+}
+
+func toGroupResponse(group *types.UserGroup, userID uint) types.GroupResponse {
+	response := types.GroupResponse{
+		ID:           group.ID,
+		Name:         group.Name,
+		Description:  group.Description,
+		WorkspaceID:  group.WorkspaceID,
+		MembersCount: len(group.Members),
+		CreatedAt:    group.CreatedAt,
+		UpdatedAt:    group.UpdatedAt,
+	}
+
+	for _, member := range group.Members {
+		if member.UserID == userID {
+			response.Role = member.Role
+		}
+	}
+
+	return response
+}
+
+func toGroupMemberResponse(member *types.UserGroupMember) types.GroupMemberResponse {
+	return types.GroupMemberResponse{
+		UserResponse: toUserResponse(&member.User),
+		Role:         member.Role,
+		JoinedAt:     member.CreatedAt,
+	}
+}
+
+func toThreadGroupResponse(grant *types.ThreadGroupGrant) types.ThreadGroupResponse {
+	response := types.ThreadGroupResponse{
+		ID:           grant.Group.ID,
+		Name:         grant.Group.Name,
+		MembersCount: len(grant.Group.Members),
+		Role:         grant.Role,
+		GrantedAt:    grant.CreatedAt,
+	}
+	if grant.GrantedBy.ID != 0 {
+		response.GrantedBy = toUserResponse(&grant.GrantedBy)
+	}
+	return response
+}
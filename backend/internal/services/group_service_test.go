@@ -0,0 +1,145 @@
+package services
+
+import (
+	"testing"
+
+	"markmywords-backend/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// grantThroughGroup puts the member in a group of the owner's and grants the
+// group the role on the thread.
+func grantThroughGroup(t *testing.T, ownerID, memberID, threadID uint, role types.ThreadRole) {
+	t.Helper()
+
+	groups := NewGroupService()
+	group, err := groups.CreateGroup(ownerID, 0, &types.CreateGroupRequest{Name: "Team"})
+	if err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	if _, err := groups.AddMember(group.ID, ownerID, &types.AddGroupMemberRequest{UserID: memberID}); err != nil {
+		t.Fatalf("add group member: %v", err)
+	}
+	if _, err := groups.GrantGroup(threadID, ownerID, &types.GrantGroupRequest{GroupID: group.ID, Role: role}); err != nil {
+		t.Fatalf("grant group: %v", err)
+	}
+}
+
+func countActivity(t *testing.T, db *gorm.DB, threadID uint, action types.ActivityAction) int64 {
+	t.Helper()
+
+	var count int64
+	db.Model(&types.ThreadActivity{}).Where("thread_id = ? AND action = ?", threadID, action).Count(&count)
+	return count
+}
+
+func TestRoleIncludesGroupGrants(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	member := createUser(t, db, "member")
+	thread := createThread(t, owner.ID, 0, "Plans")
+
+	var model types.Thread
+	db.First(&model, thread.ID)
+	permissions := NewPermissionChecker(db)
+
+	if role := permissions.Role(&model, member.ID); role != "" {
+		t.Fatalf("role before the grant = %q, want none", role)
+	}
+
+	grantThroughGroup(t, owner.ID, member.ID, thread.ID, types.ThreadRoleEditor)
+	if role := permissions.Role(&model, member.ID); role != types.ThreadRoleEditor {
+		t.Fatalf("role through the group = %q, want editor", role)
+	}
+
+	// The higher of the direct and the group role wins, either way round
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: member.ID, Role: types.ThreadRoleViewer})
+	if role := permissions.Role(&model, member.ID); role != types.ThreadRoleEditor {
+		t.Fatalf("role with a lower direct role = %q, want editor", role)
+	}
+	db.Model(&types.ThreadCollaborator{}).Where("user_id = ?", member.ID).Update("role", types.ThreadRoleAdmin)
+	if role := permissions.Role(&model, member.ID); role != types.ThreadRoleAdmin {
+		t.Fatalf("role with a higher direct role = %q, want admin", role)
+	}
+}
+
+func TestListThreadsIncludesGroupThreads(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	member := createUser(t, db, "member")
+	shared := createThread(t, owner.ID, 0, "Shared")
+	createThread(t, owner.ID, 0, "Not shared")
+
+	grantThroughGroup(t, owner.ID, member.ID, shared.ID, types.ThreadRoleCommenter)
+
+	threads, _, err := NewThreadService().ListThreads(member.ID, &types.ThreadListQuery{Scope: "shared"})
+	if err != nil {
+		t.Fatalf("list threads: %v", err)
+	}
+	if len(threads) != 1 || threads[0].ID != shared.ID {
+		t.Fatalf("shared threads = %v, want [%d]", threadIDs(threads), shared.ID)
+	}
+	if threads[0].Role != types.ThreadRoleCommenter {
+		t.Fatalf("listed role = %q, want commenter", threads[0].Role)
+	}
+
+	owned, _, err := NewThreadService().ListThreads(member.ID, &types.ThreadListQuery{Scope: "owned"})
+	if err != nil {
+		t.Fatalf("list owned threads: %v", err)
+	}
+	if len(owned) != 0 {
+		t.Fatalf("owned threads = %v, want none", threadIDs(owned))
+	}
+}
+
+func TestLeaveThreadWithOnlyGroupAccess(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	member := createUser(t, db, "member")
+	thread := createThread(t, owner.ID, 0, "Plans")
+
+	grantThroughGroup(t, owner.ID, member.ID, thread.ID, types.ThreadRoleEditor)
+
+	if err := NewCollaboratorService().LeaveThread(thread.ID, member.ID); err == nil {
+		t.Fatal("leaving a thread shared only through a group succeeded")
+	}
+	if count := countActivity(t, db, thread.ID, types.ActivityCollaboratorLeft); count != 0 {
+		t.Fatalf("recorded %d collaborator_left activities, want none", count)
+	}
+
+	var model types.Thread
+	db.First(&model, thread.ID)
+	if role := NewPermissionChecker(db).Role(&model, member.ID); role != types.ThreadRoleEditor {
+		t.Fatalf("role after the failed leave = %q, want editor", role)
+	}
+}
+
+func TestLeaveThreadKeepsGroupAccess(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	member := createUser(t, db, "member")
+	thread := createThread(t, owner.ID, 0, "Plans")
+
+	grantThroughGroup(t, owner.ID, member.ID, thread.ID, types.ThreadRoleViewer)
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: member.ID, Role: types.ThreadRoleEditor})
+
+	if err := NewCollaboratorService().LeaveThread(thread.ID, member.ID); err != nil {
+		t.Fatalf("leave thread: %v", err)
+	}
+
+	var collaborators int64
+	db.Model(&types.ThreadCollaborator{}).Where("thread_id = ? AND user_id = ?", thread.ID, member.ID).Count(&collaborators)
+	if collaborators != 0 {
+		t.Fatal("the collaborator row is still there after leaving")
+	}
+	if count := countActivity(t, db, thread.ID, types.ActivityCollaboratorLeft); count != 1 {
+		t.Fatalf("recorded %d collaborator_left activities, want 1", count)
+	}
+
+	var model types.Thread
+	db.First(&model, thread.ID)
+	if role := NewPermissionChecker(db).Role(&model, member.ID); role != types.ThreadRoleViewer {
+		t.Fatalf("role after leaving = %q, want the group's viewer", role)
+	}
+}
@@ -10,24 +10,40 @@ import (
 )
 
 type InviteService struct {
-	db *gorm.DB
+	db          *gorm.DB
+	permissions *PermissionChecker
 }
 
 func NewInviteService() *InviteService {
+	db := database.GetDB()
 	return &InviteService{
-		db: database.GetDB(),
+		db:          db,
+		permissions: NewPermissionChecker(db),
 	}
 }
 
 func (s *InviteService) CreateInvite(req *types.CreateInviteRequest, fromUserID uint) (*types.InviteResponse, error) {
-	// Check if thread exists and user owns it
-	var thread types.Thread
-	if err := s.db.First(&thread, req.ThreadID).Error; err != nil {
-		return nil, errors.New("thread not found")
+	// Check if thread exists and user may invite to it
+	thread, inviterRole, err := s.permissions.Authorize(req.ThreadID, fromUserID, ActionInvite)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collaborators join as editors unless a role is given, and nobody can
+	// grant more than they have
+	role := req.Role
+	if role == "" {
+		role = types.ThreadRoleEditor
+	}
+	if !role.IsAssignable() {
+		return nil, errors.New("invalid role")
+	}
+	if !inviterRole.AtLeast(role) {
+		return nil, errors.New("cannot grant a role higher than your own")
 	}
 
-	if thread.UserID != fromUserID {
-		return nil, errors.New("access denied")
+	if req.ToUserID == thread.UserID {
+		return nil, errors.New("user already owns this thread")
 	}
 
 	// Check if target user exists
@@ -36,6 +52,11 @@ func (s *InviteService) CreateInvite(req *types.CreateInviteRequest, fromUserID
 		return nil, errors.New("target user not found")
 	}
 
+	// Workspace threads are only shared within the workspace
+	if thread.WorkspaceID != nil && workspaceRole(s.db, *thread.WorkspaceID, toUser.ID) == "" {
+		return nil, errNotWorkspaceMember
+	}
+
 	// Check if invite already exists
 	var existingInvite types.Invite
 	if err := s.db.Where("thread_id = ? AND to_user_id = ? AND status = ?",
@@ -53,6 +74,7 @@ func (s *InviteService) CreateInvite(req *types.CreateInviteRequest, fromUserID
 		ThreadID:   req.ThreadID,
 		FromUserID: fromUserID,
 		ToUserID:   req.ToUserID,
+		Role:       role,
 		Status:     types.InviteStatusPending,
 	}
 
@@ -82,6 +104,7 @@ func (s *InviteService) GetUserInvites(userID uint) ([]types.InviteResponse, err
 			ThreadID:   invite.ThreadID,
 			FromUserID: invite.FromUserID,
 			ToUserID:   invite.ToUserID,
+			Role:       invite.Role,
 			Status:     invite.Status,
 			CreatedAt:  invite.CreatedAt,
 			UpdatedAt:  invite.UpdatedAt,
@@ -106,6 +129,7 @@ func (s *InviteService) GetUserInvites(userID uint) ([]types.InviteResponse, err
 				Username:  invite.FromUser.Username,
 				FirstName: invite.FromUser.FirstName,
 				LastName:  invite.FromUser.LastName,
+				AvatarURL: invite.FromUser.AvatarURL,
 				CreatedAt: invite.FromUser.CreatedAt,
 			}
 		}
@@ -117,6 +141,7 @@ func (s *InviteService) GetUserInvites(userID uint) ([]types.InviteResponse, err
 				Username:  invite.ToUser.Username,
 				FirstName: invite.ToUser.FirstName,
 				LastName:  invite.ToUser.LastName,
+				AvatarURL: invite.ToUser.AvatarURL,
 				CreatedAt: invite.ToUser.CreatedAt,
 			}
 		}
@@ -154,6 +179,7 @@ func (s *InviteService) AcceptInvite(inviteID, userID uint) error {
 	collaborator := types.ThreadCollaborator{
 		ThreadID: invite.ThreadID,
 		UserID:   userID,
+		Role:     invite.Role,
 	}
 
 	if err := tx.Create(&collaborator).Error; err != nil {
@@ -161,6 +187,11 @@ func (s *InviteService) AcceptInvite(inviteID, userID uint) error {
 		return err
 	}
 
+	if err := recordActivity(tx, invite.ThreadID, invite.FromUserID, types.ActivityCollaboratorAdded, &userID, "", invite.Role); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit().Error
 }
 
@@ -198,6 +229,7 @@ func (s *InviteService) GetInviteByID(inviteID uint) (*types.InviteResponse, err
 		ThreadID:   invite.ThreadID,
 		FromUserID: invite.FromUserID,
 		ToUserID:   invite.ToUserID,
+		Role:       invite.Role,
 		Status:     invite.Status,
 		CreatedAt:  invite.CreatedAt,
 		UpdatedAt:  invite.UpdatedAt,
@@ -222,6 +254,7 @@ func (s *InviteService) GetInviteByID(inviteID uint) (*types.InviteResponse, err
 			Username:  invite.FromUser.Username,
 			FirstName: invite.FromUser.FirstName,
 			LastName:  invite.FromUser.LastName,
+			AvatarURL: invite.FromUser.AvatarURL,
 			CreatedAt: invite.FromUser.CreatedAt,
 		}
 	}
@@ -233,6 +266,7 @@ func (s *InviteService) GetInviteByID(inviteID uint) (*types.InviteResponse, err
 			Username:  invite.ToUser.Username,
 			FirstName: invite.ToUser.FirstName,
 			LastName:  invite.ToUser.LastName,
+			AvatarURL: invite.ToUser.AvatarURL,
 			CreatedAt: invite.ToUser.CreatedAt,
 		}
 	}
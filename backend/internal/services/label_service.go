@@ -0,0 +1,278 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidLabels is returned for a labels filter that is not a comma
+// separated list of label IDs.
+var ErrInvalidLabels = errors.New("labels must be a comma separated list of label IDs")
+
+const defaultLabelColor = "#808080"
+
+var errLabelNotFound = errors.New("label not found")
+
+type LabelService struct {
+	db          *gorm.DB
+	permissions *PermissionChecker
+	threads     *ThreadService
+}
+
+func NewLabelService() *LabelService {
+	db := database.GetDB()
+	return &LabelService{
+		db:          db,
+		permissions: NewPermissionChecker(db),
+		threads:     NewThreadService(),
+	}
+}
+
+// GetLabels lists the user's labels by name, with the number of threads of
+// the workspace each is on.
+func (s *LabelService) GetLabels(userID, workspaceID uint) ([]types.LabelResponse, error) {
+	var labels []types.Label
+	if err := s.db.Where("user_id = ?", userID).Order("name").Find(&labels).Error; err != nil {
+		return nil, err
+	}
+
+	countByLabel := s.threadCounts(userID, workspaceID)
+
+	responses := []types.LabelResponse{}
+	for i := range labels {
+		response := toLabelResponse(&labels[i])
+		response.ThreadCount = countByLabel[labels[i].ID]
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+func (s *LabelService) CreateLabel(userID uint, req *types.CreateLabelRequest) (*types.LabelResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if err := s.checkNameFree(userID, name, 0); err != nil {
+		return nil, err
+	}
+
+	color := req.Color
+	if color == "" {
+		color = defaultLabelColor
+	}
+
+	label := types.Label{
+		UserID: userID,
+		Name:   name,
+		Color:  strings.ToLower(color),
+	}
+	if err := s.db.Create(&label).Error; err != nil {
+		return nil, err
+	}
+
+	response := toLabelResponse(&label)
+	return &response, nil
+}
+
+func (s *LabelService) UpdateLabel(labelID, userID, workspaceID uint, req *types.UpdateLabelRequest) (*types.LabelResponse, error) {
+	label, err := s.findOwnLabel(labelID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" && name != label.Name {
+		if err := s.checkNameFree(userID, name, label.ID); err != nil {
+			return nil, err
+		}
+		label.Name = name
+	}
+	if req.Color != "" {
+		label.Color = strings.ToLower(req.Color)
+	}
+
+	if err := s.db.Save(label).Error; err != nil {
+		return nil, err
+	}
+
+	response := toLabelResponse(label)
+	response.ThreadCount = s.threadCounts(userID, workspaceID)[label.ID]
+	return &response, nil
+}
+
+// DeleteLabel deletes the label and takes it off every thread.
+func (s *LabelService) DeleteLabel(labelID, userID uint) error {
+	label, err := s.findOwnLabel(labelID, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("label_id = ?", label.ID).Delete(&types.ThreadLabel{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(label).Error
+	})
+}
+
+// AddThreadLabel puts one of the user's labels on a thread they can edit.
+// Adding a label twice is a no-op.
+func (s *LabelService) AddThreadLabel(threadID, labelID, userID uint) (*types.ThreadResponse, error) {
+	if _, _, err := s.permissions.Authorize(threadID, userID, ActionEditThread); err != nil {
+		return nil, err
+	}
+
+	label, err := s.findOwnLabel(labelID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	threadLabel := types.ThreadLabel{ThreadID: threadID, LabelID: label.ID}
+	err = s.db.Where(&threadLabel).
+		Attrs(types.ThreadLabel{AddedByID: userID}).
+		FirstOrCreate(&threadLabel).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return s.threads.GetThreadByID(threadID, userID)
+}
+
+// RemoveThreadLabel takes a label off a thread. Anyone who can edit the
+// thread may remove any label from it.
+func (s *LabelService) RemoveThreadLabel(threadID, labelID, userID uint) (*types.ThreadResponse, error) {
+	if _, _, err := s.permissions.Authorize(threadID, userID, ActionEditThread); err != nil {
+		return nil, err
+	}
+
+	result := s.db.Where("thread_id = ? AND label_id = ?", threadID, labelID).Delete(&types.ThreadLabel{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("label is not on this thread")
+	}
+
+	return s.threads.GetThreadByID(threadID, userID)
+}
+
+// threadCounts counts the threads of the workspace outside the trash each of
+// the user's labels is on.
+func (s *LabelService) threadCounts(userID, workspaceID uint) map[uint]int {
+	var counts []struct {
+		LabelID     uint
+		ThreadCount int
+	}
+	inWorkspaceScope(s.db.Model(&types.ThreadLabel{}), workspaceID).
+		Select("thread_labels.label_id, COUNT(*) AS thread_count").
+		Joins("JOIN threads ON threads.id = thread_labels.thread_id AND threads.deleted_at IS NULL").
+		Joins("JOIN labels ON labels.id = thread_labels.label_id").
+		Where("labels.user_id = ?", userID).
+		Group("thread_labels.label_id").
+		Scan(&counts)
+
+	countByLabel := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		countByLabel[count.LabelID] = count.ThreadCount
+	}
+	return countByLabel
+}
+
+func (s *LabelService) findOwnLabel(labelID, userID uint) (*types.Label, error) {
+	var label types.Label
+	if err := s.db.Where("id = ? AND user_id = ?", labelID, userID).First(&label).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errLabelNotFound
+		}
+		return nil, err
+	}
+	return &label, nil
+}
+
+func (s *LabelService) checkNameFree(userID uint, name string, exceptID uint) error {
+	var count int64
+	s.db.Model(&types.Label{}).
+		Where("user_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", userID, name, exceptID).
+		Count(&count)
+	if count > 0 {
+		return errors.New("you already have a label with this name")
+	}
+	return nil
+}
+
+// parseLabelIDs reads the comma separated labels filter of a thread listing,
+// dropping duplicates.
+func parseLabelIDs(list string) ([]uint, error) {
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, ErrInvalidLabels
+		}
+		if !seen[uint(id)] {
+			seen[uint(id)] = true
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, nil
+}
+
+// attachLabels fills in the labels on each thread, from whoever added them.
+func attachLabels(db *gorm.DB, responses []types.ThreadResponse) error {
+	if len(responses) == 0 {
+		return nil
+	}
+
+	threadIDs := make([]uint, len(responses))
+	for i := range responses {
+		threadIDs[i] = responses[i].ID
+	}
+
+	var threadLabels []types.ThreadLabel
+	err := db.Joins("Label").
+		Where("thread_labels.thread_id IN ?", threadIDs).
+		Order("Label.name").
+		Find(&threadLabels).Error
+	if err != nil {
+		return err
+	}
+
+	byThread := make(map[uint][]types.ThreadLabelResponse)
+	for _, threadLabel := range threadLabels {
+		byThread[threadLabel.ThreadID] = append(byThread[threadLabel.ThreadID], types.ThreadLabelResponse{
+			ID:     threadLabel.Label.ID,
+			UserID: threadLabel.Label.UserID,
+			Name:   threadLabel.Label.Name,
+			Color:  threadLabel.Label.Color,
+		})
+	}
+
+	for i := range responses {
+		responses[i].Labels = byThread[responses[i].ID]
+		if responses[i].Labels == nil {
+			responses[i].Labels = []types.ThreadLabelResponse{}
+		}
+	}
+	return nil
+}
+
+func toLabelResponse(label *types.Label) types.LabelResponse {
+	return types.LabelResponse{
+		ID:        label.ID,
+		UserID:    label.UserID,
+		Name:      label.Name,
+		Color:     label.Color,
+		CreatedAt: label.CreatedAt,
+		UpdatedAt: label.UpdatedAt,
+	}
+}
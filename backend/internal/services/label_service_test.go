@@ -0,0 +1,157 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"markmywords-backend/internal/types"
+)
+
+func createLabel(t *testing.T, userID uint, name string) *types.LabelResponse {
+	t.Helper()
+
+	label, err := NewLabelService().CreateLabel(userID, &types.CreateLabelRequest{Name: name})
+	if err != nil {
+		t.Fatalf("create label %s: %v", name, err)
+	}
+	return label
+}
+
+func TestLabelNamesAreUniquePerUser(t *testing.T) {
+	db := newTestDB(t)
+	alice := createUser(t, db, "alice")
+	bob := createUser(t, db, "bob")
+	createLabel(t, alice.ID, "Work")
+	home := createLabel(t, alice.ID, "Home")
+
+	labels := NewLabelService()
+	cases := []struct {
+		name    string
+		userID  uint
+		label   string
+		wantErr bool
+	}{
+		{"same name", alice.ID, "Work", true},
+		{"different case", alice.ID, " work ", true},
+		{"another user", bob.ID, "Work", false},
+		{"blank", alice.ID, "  ", true},
+	}
+	for _, tc := range cases {
+		_, err := labels.CreateLabel(tc.userID, &types.CreateLabelRequest{Name: tc.label})
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v, want error: %v", tc.name, err, tc.wantErr)
+		}
+	}
+
+	if _, err := labels.UpdateLabel(home.ID, alice.ID, 0, &types.UpdateLabelRequest{Name: "WORK"}); err == nil {
+		t.Error("renamed a label onto another of the user's labels")
+	}
+	if _, err := labels.UpdateLabel(home.ID, bob.ID, 0, &types.UpdateLabelRequest{Name: "Mine"}); !errors.Is(err, errLabelNotFound) {
+		t.Errorf("renaming someone else's label: err = %v, want %v", err, errLabelNotFound)
+	}
+}
+
+func TestThreadLabels(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	viewer := createUser(t, db, "viewer")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: viewer.ID, Role: types.ThreadRoleViewer})
+	work := createLabel(t, owner.ID, "Work")
+	theirs := createLabel(t, viewer.ID, "Theirs")
+
+	labels := NewLabelService()
+	for i := 0; i < 2; i++ {
+		labelled, err := labels.AddThreadLabel(thread.ID, work.ID, owner.ID)
+		if err != nil {
+			t.Fatalf("add label: %v", err)
+		}
+		if len(labelled.Labels) != 1 || labelled.Labels[0].ID != work.ID {
+			t.Fatalf("labels = %+v, want only %s", labelled.Labels, work.Name)
+		}
+	}
+
+	if _, err := labels.AddThreadLabel(thread.ID, theirs.ID, viewer.ID); err == nil {
+		t.Error("a viewer labelled the thread")
+	}
+	if _, err := labels.AddThreadLabel(thread.ID, theirs.ID, owner.ID); !errors.Is(err, errLabelNotFound) {
+		t.Errorf("putting someone else's label on: err = %v, want %v", err, errLabelNotFound)
+	}
+
+	listed, err := labels.GetLabels(owner.ID, 0)
+	if err != nil {
+		t.Fatalf("get labels: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ThreadCount != 1 {
+		t.Fatalf("labels = %+v, want %s on one thread", listed, work.Name)
+	}
+
+	// Deleting the label takes it off the thread
+	if err := labels.DeleteLabel(work.ID, owner.ID); err != nil {
+		t.Fatalf("delete label: %v", err)
+	}
+	got, err := NewThreadService().GetThreadByID(thread.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("get thread: %v", err)
+	}
+	if len(got.Labels) != 0 {
+		t.Fatalf("labels after delete = %+v, want none", got.Labels)
+	}
+	if _, err := labels.RemoveThreadLabel(thread.ID, work.ID, owner.ID); err == nil {
+		t.Fatal("removed a label that is not on the thread")
+	}
+}
+
+func TestListThreadsFiltersByLabels(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	work := createLabel(t, owner.ID, "Work")
+	urgent := createLabel(t, owner.ID, "Urgent")
+	both := createThread(t, owner.ID, 0, "Both")
+	workOnly := createThread(t, owner.ID, 0, "Work only")
+	createThread(t, owner.ID, 0, "Unlabelled")
+
+	labels := NewLabelService()
+	for _, add := range []struct{ threadID, labelID uint }{
+		{both.ID, work.ID}, {both.ID, urgent.ID}, {workOnly.ID, work.ID},
+	} {
+		if _, err := labels.AddThreadLabel(add.threadID, add.labelID, owner.ID); err != nil {
+			t.Fatalf("add label: %v", err)
+		}
+	}
+
+	ids := func(labels ...*types.LabelResponse) string {
+		parts := make([]string, len(labels))
+		for i, label := range labels {
+			parts[i] = strconv.FormatUint(uint64(label.ID), 10)
+		}
+		return strings.Join(parts, ",")
+	}
+
+	cases := []struct {
+		name  string
+		query types.ThreadListQuery
+		want  []uint
+	}{
+		{"one label", types.ThreadListQuery{Labels: ids(work)}, []uint{both.ID, workOnly.ID}},
+		{"any of two", types.ThreadListQuery{Labels: ids(work, urgent)}, []uint{both.ID, workOnly.ID}},
+		{"all of two", types.ThreadListQuery{Labels: ids(work, urgent), LabelMatch: "all"}, []uint{both.ID}},
+		{"duplicates", types.ThreadListQuery{Labels: ids(urgent, urgent), LabelMatch: "all"}, []uint{both.ID}},
+	}
+	threads := NewThreadService()
+	for _, tc := range cases {
+		listed, _, err := threads.ListThreads(owner.ID, &tc.query)
+		if err != nil {
+			t.Fatalf("%s: list threads: %v", tc.name, err)
+		}
+		if got := threadIDs(listed); !sameIDs(got, tc.want) {
+			t.Errorf("%s: threads = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	if _, _, err := threads.ListThreads(owner.ID, &types.ThreadListQuery{Labels: "1,two"}); !errors.Is(err, ErrInvalidLabels) {
+		t.Fatalf("bad labels filter: err = %v, want %v", err, ErrInvalidLabels)
+	}
+}
@@ -2,87 +2,169 @@ package services
 
 import (
 	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"markmywords-backend/internal/types"
 	"markmywords-backend/pkg/database"
+	"markmywords-backend/pkg/diff"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	errRevisionNotFound = errors.New("revision not found")
+	errParentNotFound   = errors.New("the note replied to is not in this thread")
+	errReactionNotFound = errors.New("reaction not found")
+	errInvalidEmoji     = errors.New("emoji must be a unicode emoji or a :shortcode:")
+)
+
+var emojiShortcode = regexp.MustCompile(`^:[a-z0-9_+-]+:$`)
+
 type NoteService struct {
-	db *gorm.DB
+	db          *gorm.DB
+	permissions *PermissionChecker
 }
 
 func NewNoteService() *NoteService {
+	db := database.GetDB()
 	return &NoteService{
-		db: database.GetDB(),
+		db:          db,
+		permissions: NewPermissionChecker(db),
 	}
 }
 
 func (s *NoteService) CreateNote(req *types.CreateNoteRequest, userID uint) (*types.NoteResponse, error) {
-	// Check if user has access to the thread
-	var thread types.Thread
-	if err := s.db.First(&thread, req.ThreadID).Error; err != nil {
-		return nil, errors.New("thread not found")
+	// Check if user can add notes to this thread
+	if _, _, err := s.permissions.Authorize(req.ThreadID, userID, ActionPostNote); err != nil {
+		return nil, err
 	}
 
-	// Check if user can add notes to this thread
-	if thread.UserID != userID {
-		// Check if user is a collaborator
-		var collaborator types.ThreadCollaborator
-		if err := s.db.Where("thread_id = ? AND user_id = ?", req.ThreadID, userID).First(&collaborator).Error; err != nil {
-			return nil, errors.New("access denied")
+	// Replies stay in the thread of the note they reply to
+	if req.ParentNoteID != nil {
+		var parent types.Note
+		err := s.db.Where("id = ? AND thread_id = ?", *req.ParentNoteID, req.ThreadID).First(&parent).Error
+		if err != nil {
+			return nil, errParentNotFound
 		}
 	}
 
 	note := types.Note{
-		Content:  req.Content,
-		ThreadID: req.ThreadID,
-		UserID:   userID,
+		Content:      req.Content,
+		ThreadID:     req.ThreadID,
+		UserID:       userID,
+		ParentNoteID: req.ParentNoteID,
 	}
 
-	if err := s.db.Create(&note).Error; err != nil {
+	// The note as posted is its first revision
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+		return tx.Create(&types.NoteRevision{
+			NoteID:   note.ID,
+			Number:   1,
+			Content:  note.Content,
+			EditorID: userID,
+		}).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return s.GetNoteByID(note.ID, userID)
 }
 
-func (s *NoteService) GetThreadNotes(threadID, userID uint) ([]types.NoteResponse, error) {
-	// Check if user has access to the thread
-	var thread types.Thread
-	if err := s.db.First(&thread, threadID).Error; err != nil {
-		return nil, errors.New("thread not found")
-	}
+// noteSorts are the orderings of GetThreadNotes.
+var noteSorts = map[string][]sortKey{
+	"oldest": {
+		{column: "notes.created_at", isTime: true},
+		{column: "notes.id"},
+	},
+	"newest": {
+		{column: "notes.created_at", desc: true, isTime: true},
+		{column: "notes.id", desc: true},
+	},
+}
 
+// GetThreadNotes returns a page of the thread's top-level notes, or of all its
+// notes with IncludeReplies, and the cursor of the next page.
+func (s *NoteService) GetThreadNotes(threadID, userID uint, query *types.NoteListQuery) ([]types.NoteResponse, string, error) {
 	// Check if user can view notes in this thread
-	if thread.UserID != userID {
-		// Check if user is a collaborator
-		var collaborator types.ThreadCollaborator
-		if err := s.db.Where("thread_id = ? AND user_id = ?", threadID, userID).First(&collaborator).Error; err != nil {
-			return nil, errors.New("access denied")
-		}
+	if _, _, err := s.permissions.Authorize(threadID, userID, ActionViewThread); err != nil {
+		return nil, "", err
+	}
+
+	db := s.db.Where("notes.thread_id = ?", threadID)
+	if !query.IncludeReplies {
+		db = db.Where("notes.parent_note_id IS NULL")
+	}
+	return s.pageNotes(db, userID, query)
+}
+
+// GetReplies returns a page of the replies to a note, oldest first unless
+// sorted otherwise, and the cursor of the next page.
+func (s *NoteService) GetReplies(noteID, userID uint, query *types.NoteListQuery) ([]types.NoteResponse, string, error) {
+	note, err := s.findReadableNote(noteID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return s.pageNotes(s.db.Where("notes.parent_note_id = ?", note.ID), userID, query)
+}
+
+// pageNotes applies the list query to the notes selected by db, as seen by
+// the user.
+func (s *NoteService) pageNotes(db *gorm.DB, userID uint, query *types.NoteListQuery) ([]types.NoteResponse, string, error) {
+	if query.Author != 0 {
+		db = db.Where("notes.user_id = ?", query.Author)
+	}
+	if !query.UpdatedSince.IsZero() {
+		db = db.Where("notes.updated_at >= ?", query.UpdatedSince.Local())
+	}
+
+	sort := query.Sort
+	if sort == "" {
+		sort = "oldest"
+	}
+
+	db, err := orderByKeys(db, sort, noteSorts[sort], query.Cursor)
+	if err != nil {
+		return nil, "", err
 	}
 
+	limit := pageLimit(query.Limit, 100, 200)
+
 	var notes []types.Note
-	err := s.db.Where("thread_id = ?", threadID).
+	err = db.Limit(limit + 1).
 		Preload("User").
-		Order("created_at ASC").
 		Find(&notes).Error
 
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(notes) > limit {
+		notes = notes[:limit]
+		last := notes[len(notes)-1]
+		nextCursor = encodeCursor(sort, last.CreatedAt, last.ID)
 	}
 
-	var responses []types.NoteResponse
+	responses := []types.NoteResponse{}
 	for _, note := range notes {
 		response := types.NoteResponse{
-			ID:        note.ID,
-			Content:   note.Content,
-			ThreadID:  note.ThreadID,
-			UserID:    note.UserID,
-			CreatedAt: note.CreatedAt,
-			UpdatedAt: note.UpdatedAt,
+			ID:           note.ID,
+			Content:      note.Content,
+			ThreadID:     note.ThreadID,
+			UserID:       note.UserID,
+			ParentNoteID: note.ParentNoteID,
+			Version:      note.Version,
+			CreatedAt:    note.CreatedAt,
+			UpdatedAt:    note.UpdatedAt,
 		}
 
 		if note.User.ID != 0 {
@@ -92,6 +174,7 @@ func (s *NoteService) GetThreadNotes(threadID, userID uint) ([]types.NoteRespons
 				Username:  note.User.Username,
 				FirstName: note.User.FirstName,
 				LastName:  note.User.LastName,
+				AvatarURL: note.User.AvatarURL,
 				CreatedAt: note.User.CreatedAt,
 			}
 		}
@@ -99,7 +182,10 @@ func (s *NoteService) GetThreadNotes(threadID, userID uint) ([]types.NoteRespons
 		responses = append(responses, response)
 	}
 
-	return responses, nil
+	if err := attachNoteDetails(s.db, responses, userID); err != nil {
+		return nil, "", err
+	}
+	return responses, nextCursor, nil
 }
 
 func (s *NoteService) GetNoteByID(noteID, userID uint) (*types.NoteResponse, error) {
@@ -113,26 +199,19 @@ func (s *NoteService) GetNoteByID(noteID, userID uint) (*types.NoteResponse, err
 	}
 
 	// Check if user has access to this note (through thread access)
-	var thread types.Thread
-	if err := s.db.First(&thread, note.ThreadID).Error; err != nil {
-		return nil, errors.New("thread not found")
-	}
-
-	if thread.UserID != userID {
-		// Check if user is a collaborator
-		var collaborator types.ThreadCollaborator
-		if err := s.db.Where("thread_id = ? AND user_id = ?", note.ThreadID, userID).First(&collaborator).Error; err != nil {
-			return nil, errors.New("access denied")
-		}
+	if _, _, err := s.permissions.Authorize(note.ThreadID, userID, ActionViewThread); err != nil {
+		return nil, err
 	}
 
 	response := types.NoteResponse{
-		ID:        note.ID,
-		Content:   note.Content,
-		ThreadID:  note.ThreadID,
-		UserID:    note.UserID,
-		CreatedAt: note.CreatedAt,
-		UpdatedAt: note.UpdatedAt,
+		ID:           note.ID,
+		Content:      note.Content,
+		ThreadID:     note.ThreadID,
+		UserID:       note.UserID,
+		ParentNoteID: note.ParentNoteID,
+		Version:      note.Version,
+		CreatedAt:    note.CreatedAt,
+		UpdatedAt:    note.UpdatedAt,
 	}
 
 	if note.User.ID != 0 {
@@ -142,32 +221,471 @@ func (s *NoteService) GetNoteByID(noteID, userID uint) (*types.NoteResponse, err
 			Username:  note.User.Username,
 			FirstName: note.User.FirstName,
 			LastName:  note.User.LastName,
+			AvatarURL: note.User.AvatarURL,
 			CreatedAt: note.User.CreatedAt,
 		}
 	}
 
-	return &response, nil
+	responses := []types.NoteResponse{response}
+	if err := attachNoteDetails(s.db, responses, userID); err != nil {
+		return nil, err
+	}
+	return &responses[0], nil
+}
+
+// UpdateNote changes the note's content, keeping the old content as a
+// revision. The update only applies if the note is still at the given
+// version; 0 updates whatever version is current.
+func (s *NoteService) UpdateNote(noteID, userID, version uint, req *types.UpdateNoteRequest) (*types.NoteResponse, error) {
+	note, err := s.findEditableNote(noteID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && note.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	if err := s.saveContent(note, req.Content, userID); err != nil {
+		return nil, err
+	}
+
+	return s.GetNoteByID(noteID, userID)
 }
 
-func (s *NoteService) UpdateNote(noteID, userID uint, req *types.UpdateNoteRequest) (*types.NoteResponse, error) {
+// GetRevisions lists the note's revisions, the newest first.
+func (s *NoteService) GetRevisions(noteID, userID uint) ([]types.NoteRevisionResponse, error) {
+	note, err := s.findReadableNote(noteID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []types.NoteRevision
+	err = s.db.Where("note_id = ?", note.ID).
+		Preload("Editor").
+		Order("number DESC").
+		Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []types.NoteRevisionResponse{}
+	for i := range revisions {
+		revision := &revisions[i]
+		response := types.NoteRevisionResponse{
+			Number:    revision.Number,
+			Content:   revision.Content,
+			Current:   i == 0,
+			CreatedAt: revision.CreatedAt,
+		}
+		if revision.Editor.ID != 0 {
+			response.Editor = toUserResponse(&revision.Editor)
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// DiffRevisions compares a revision with another one, by default the one
+// before it. Revision 1 is compared with an empty note unless told otherwise.
+func (s *NoteService) DiffRevisions(noteID uint, number int, userID uint, query *types.NoteDiffQuery) (*types.NoteDiffResponse, error) {
+	note, err := s.findReadableNote(noteID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := s.findRevision(note.ID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	against := query.Against
+	if against == 0 {
+		against = number - 1
+	}
+	var fromContent string
+	if against > 0 {
+		from, err := s.findRevision(note.ID, against)
+		if err != nil {
+			return nil, err
+		}
+		fromContent = from.Content
+	}
+
+	mode := query.Mode
+	if mode == "" {
+		mode = "line"
+	}
+	var changes []diff.Change
+	if mode == "word" {
+		changes = diff.Words(fromContent, to.Content)
+	} else {
+		changes = diff.Lines(fromContent, to.Content)
+	}
+
+	response := &types.NoteDiffResponse{
+		NoteID:  note.ID,
+		From:    against,
+		To:      number,
+		Mode:    mode,
+		Changes: []types.DiffChange{},
+	}
+	for _, change := range changes {
+		response.Changes = append(response.Changes, types.DiffChange{
+			Op:   string(change.Op),
+			Text: change.Text,
+		})
+	}
+	return response, nil
+}
+
+// RestoreRevision rolls the note back to an earlier revision's content. The
+// rollback is itself a new revision, so nothing in between is lost.
+func (s *NoteService) RestoreRevision(noteID uint, number int, userID uint) (*types.NoteResponse, error) {
+	note, err := s.findEditableNote(noteID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := s.findRevision(note.ID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveContent(note, revision.Content, userID); err != nil {
+		return nil, err
+	}
+
+	return s.GetNoteByID(noteID, userID)
+}
+
+// findEditableNote loads a note the user may edit: only its author can, and
+// only while they may still post in the thread.
+func (s *NoteService) findEditableNote(noteID, userID uint) (*types.Note, error) {
 	var note types.Note
 	if err := s.db.First(&note, noteID).Error; err != nil {
 		return nil, err
 	}
 
-	// Only the note author can edit the note
 	if note.UserID != userID {
-		return nil, errors.New("access denied")
+		return nil, errAccessDenied
+	}
+	if _, _, err := s.permissions.Authorize(note.ThreadID, userID, ActionPostNote); err != nil {
+		return nil, err
 	}
+	return &note, nil
+}
 
-	// Update content
-	note.Content = req.Content
+func (s *NoteService) findReadableNote(noteID, userID uint) (*types.Note, error) {
+	var note types.Note
+	if err := s.db.First(&note, noteID).Error; err != nil {
+		return nil, err
+	}
 
-	if err := s.db.Save(&note).Error; err != nil {
+	if _, _, err := s.permissions.Authorize(note.ThreadID, userID, ActionViewThread); err != nil {
 		return nil, err
 	}
+	return &note, nil
+}
 
-	return s.GetNoteByID(noteID, userID)
+func (s *NoteService) findRevision(noteID uint, number int) (*types.NoteRevision, error) {
+	var revision types.NoteRevision
+	if err := s.db.Where("note_id = ? AND number = ?", noteID, number).First(&revision).Error; err != nil {
+		return nil, errRevisionNotFound
+	}
+	return &revision, nil
+}
+
+// saveContent replaces the note's content, unless someone else got there
+// first, and records it as the next revision. Saving the current content
+// again changes nothing.
+func (s *NoteService) saveContent(note *types.Note, content string, editorID uint) error {
+	if note.Content == content {
+		return nil
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&types.NoteRevision{}).Where("note_id = ?", note.ID).Select("COALESCE(MAX(number), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+
+		err := updateVersioned(tx, &types.Note{}, note.ID, note.Version, map[string]interface{}{
+			"content": content,
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Create(&types.NoteRevision{
+			NoteID:   note.ID,
+			Number:   latest + 1,
+			Content:  content,
+			EditorID: editorID,
+		}).Error
+	})
+}
+
+// attachNoteDetails fills in the revision and reply counts and the reactions
+// of each note, as seen by the user.
+func attachNoteDetails(db *gorm.DB, responses []types.NoteResponse, userID uint) error {
+	if err := attachRevisionCounts(db, responses); err != nil {
+		return err
+	}
+	if err := attachReplyCounts(db, responses); err != nil {
+		return err
+	}
+	return attachReactions(db, responses, userID)
+}
+
+// attachRevisionCounts fills in how many revisions each note has, and whether
+// it has been edited since it was posted.
+func attachRevisionCounts(db *gorm.DB, responses []types.NoteResponse) error {
+	if len(responses) == 0 {
+		return nil
+	}
+
+	noteIDs := make([]uint, len(responses))
+	for i := range responses {
+		noteIDs[i] = responses[i].ID
+	}
+
+	var counts []struct {
+		NoteID        uint
+		RevisionCount int
+	}
+	err := db.Model(&types.NoteRevision{}).
+		Select("note_id, COUNT(*) AS revision_count").
+		Where("note_id IN ?", noteIDs).
+		Group("note_id").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	countByNote := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		countByNote[count.NoteID] = count.RevisionCount
+	}
+
+	for i := range responses {
+		responses[i].RevisionCount = countByNote[responses[i].ID]
+		responses[i].Edited = responses[i].RevisionCount > 1
+	}
+	return nil
+}
+
+// attachReplyCounts fills in how many replies each note has.
+func attachReplyCounts(db *gorm.DB, responses []types.NoteResponse) error {
+	if len(responses) == 0 {
+		return nil
+	}
+
+	noteIDs := make([]uint, len(responses))
+	for i := range responses {
+		noteIDs[i] = responses[i].ID
+	}
+
+	var counts []struct {
+		ParentNoteID uint
+		ReplyCount   int
+	}
+	err := db.Model(&types.Note{}).
+		Select("parent_note_id, COUNT(*) AS reply_count").
+		Where("parent_note_id IN ?", noteIDs).
+		Group("parent_note_id").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	countByNote := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		countByNote[count.ParentNoteID] = count.ReplyCount
+	}
+
+	for i := range responses {
+		responses[i].ReplyCount = countByNote[responses[i].ID]
+	}
+	return nil
+}
+
+// attachReactions sums up the reactions to each note by emoji, in the order
+// they were first used, and flags the ones the user reacted with.
+func attachReactions(db *gorm.DB, responses []types.NoteResponse, userID uint) error {
+	if len(responses) == 0 {
+		return nil
+	}
+
+	noteIDs := make([]uint, len(responses))
+	for i := range responses {
+		noteIDs[i] = responses[i].ID
+		responses[i].Reactions = []types.ReactionSummary{}
+	}
+
+	var summaries []struct {
+		NoteID      uint
+		Emoji       string
+		Count       int
+		ReactedByMe bool
+	}
+	err := db.Model(&types.NoteReaction{}).
+		Select("note_id, emoji, COUNT(*) AS count, MAX(CASE WHEN user_id = ? THEN 1 ELSE 0 END) AS reacted_by_me", userID).
+		Where("note_id IN ?", noteIDs).
+		Group("note_id, emoji").
+		Order("MIN(id)").
+		Scan(&summaries).Error
+	if err != nil {
+		return err
+	}
+
+	indexByNote := make(map[uint]int, len(responses))
+	for i := range responses {
+		indexByNote[responses[i].ID] = i
+	}
+	for _, summary := range summaries {
+		response := &responses[indexByNote[summary.NoteID]]
+		response.Reactions = append(response.Reactions, types.ReactionSummary{
+			Emoji:       summary.Emoji,
+			Count:       summary.Count,
+			ReactedByMe: summary.ReactedByMe,
+		})
+	}
+	return nil
+}
+
+// AddReaction reacts to the note with an emoji. Reacting again with the same
+// emoji changes nothing and returns no message to broadcast.
+func (s *NoteService) AddReaction(noteID, userID uint, req *types.ReactionRequest) (*types.ReactionMessage, error) {
+	emoji, err := normalizeEmoji(req.Emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	var note types.Note
+	if err := s.db.First(&note, noteID).Error; err != nil {
+		return nil, err
+	}
+	if _, _, err := s.permissions.Authorize(note.ThreadID, userID, ActionPostNote); err != nil {
+		return nil, err
+	}
+
+	// The unique index decides between concurrent identical reactions
+	reaction := types.NoteReaction{
+		NoteID: note.ID,
+		UserID: userID,
+		Emoji:  emoji,
+	}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&reaction)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return s.reactionMessage(&note, userID, emoji)
+}
+
+// RemoveReaction takes back the user's reaction with an emoji.
+func (s *NoteService) RemoveReaction(noteID, userID uint, req *types.ReactionRequest) (*types.ReactionMessage, error) {
+	emoji, err := normalizeEmoji(req.Emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := s.findReadableNote(noteID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := s.db.Where("note_id = ? AND user_id = ? AND emoji = ?", note.ID, userID, emoji).
+		Delete(&types.NoteReaction{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errReactionNotFound
+	}
+
+	return s.reactionMessage(note, userID, emoji)
+}
+
+func (s *NoteService) reactionMessage(note *types.Note, userID uint, emoji string) (*types.ReactionMessage, error) {
+	var user types.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var count int64
+	err := s.db.Model(&types.NoteReaction{}).
+		Where("note_id = ? AND emoji = ?", note.ID, emoji).
+		Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ReactionMessage{
+		ThreadID: note.ThreadID,
+		NoteID:   note.ID,
+		UserID:   userID,
+		Username: user.Username,
+		Emoji:    emoji,
+		Count:    int(count),
+	}, nil
+}
+
+// normalizeEmoji accepts a lowercase ":shortcode:" or a short run of unicode
+// emoji, so that sequences such as flags, keycaps and skin tones work.
+func normalizeEmoji(emoji string) (string, error) {
+	emoji = strings.TrimSpace(emoji)
+	if strings.HasPrefix(emoji, ":") {
+		emoji = strings.ToLower(emoji)
+		if !emojiShortcode.MatchString(emoji) {
+			return "", errInvalidEmoji
+		}
+		return emoji, nil
+	}
+
+	if utf8.RuneCountInString(emoji) > 16 {
+		return "", errInvalidEmoji
+	}
+	hasSymbol := false
+	for _, r := range emoji {
+		switch {
+		case unicode.In(r, unicode.So, unicode.Me):
+			// pictographs, and the keycap that makes "1️⃣" an emoji
+			hasSymbol = true
+		case unicode.In(r, unicode.Mn, unicode.Sk, unicode.Cf):
+			// variation selectors, skin tones and joiners
+		case r == '#' || r == '*' || (r >= '0' && r <= '9'):
+			// keycap bases
+		default:
+			return "", errInvalidEmoji
+		}
+	}
+	if !hasSymbol {
+		return "", errInvalidEmoji
+	}
+	return emoji, nil
+}
+
+// ReplyRecipient is the author of the note replied to, if they are someone
+// else and can still read the thread. It is 0 when nobody is to be told.
+func (s *NoteService) ReplyRecipient(reply *types.NoteResponse) uint {
+	if reply.ParentNoteID == nil {
+		return 0
+	}
+
+	var parent types.Note
+	if err := s.db.First(&parent, *reply.ParentNoteID).Error; err != nil {
+		return 0
+	}
+	if parent.UserID == reply.UserID {
+		return 0
+	}
+	if _, _, err := s.permissions.Authorize(parent.ThreadID, parent.UserID, ActionViewThread); err != nil {
+		return 0
+	}
+	return parent.UserID
 }
 
 func (s *NoteService) DeleteNote(noteID, userID uint) error {
@@ -176,10 +694,16 @@ func (s *NoteService) DeleteNote(noteID, userID uint) error {
 		return err
 	}
 
-	// Only the note author can delete the note
-	if note.UserID != userID {
-		return errors.New("access denied")
+	// Authors can delete their own notes, admins can delete anyone's
+	action := ActionModerate
+	if note.UserID == userID {
+		action = ActionPostNote
+	}
+	if _, _, err := s.permissions.Authorize(note.ThreadID, userID, action); err != nil {
+		return err
 	}
 
-	return s.db.Delete(&note).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return trashNote(tx, &note)
+	})
 }
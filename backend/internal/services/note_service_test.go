@@ -0,0 +1,248 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"markmywords-backend/internal/types"
+)
+
+func createNote(t *testing.T, threadID, userID uint, parentID *uint) *types.NoteResponse {
+	t.Helper()
+
+	note, err := NewNoteService().CreateNote(&types.CreateNoteRequest{ThreadID: threadID, Content: "note", ParentNoteID: parentID}, userID)
+	if err != nil {
+		t.Fatalf("create note: %v", err)
+	}
+	return note
+}
+
+func noteIDs(notes []types.NoteResponse) []uint {
+	ids := make([]uint, len(notes))
+	for i := range notes {
+		ids[i] = notes[i].ID
+	}
+	return ids
+}
+
+func TestGetThreadNotesListsTopLevelNotes(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	thread := createThread(t, owner.ID, 0, "Plans")
+
+	first := createNote(t, thread.ID, owner.ID, nil)
+	second := createNote(t, thread.ID, owner.ID, nil)
+	reply := createNote(t, thread.ID, owner.ID, &first.ID)
+	nested := createNote(t, thread.ID, owner.ID, &reply.ID)
+
+	notes := NewNoteService()
+	topLevel, _, err := notes.GetThreadNotes(thread.ID, owner.ID, &types.NoteListQuery{})
+	if err != nil {
+		t.Fatalf("get thread notes: %v", err)
+	}
+	if got, want := noteIDs(topLevel), []uint{first.ID, second.ID}; !sameIDs(got, want) {
+		t.Fatalf("top-level notes = %v, want %v", got, want)
+	}
+	if topLevel[0].ReplyCount != 1 {
+		t.Fatalf("reply count = %d, want 1", topLevel[0].ReplyCount)
+	}
+
+	all, _, err := notes.GetThreadNotes(thread.ID, owner.ID, &types.NoteListQuery{IncludeReplies: true})
+	if err != nil {
+		t.Fatalf("get thread notes with replies: %v", err)
+	}
+	if got, want := noteIDs(all), []uint{first.ID, second.ID, reply.ID, nested.ID}; !sameIDs(got, want) {
+		t.Fatalf("all notes = %v, want %v", got, want)
+	}
+
+	replies, _, err := notes.GetReplies(first.ID, owner.ID, &types.NoteListQuery{})
+	if err != nil {
+		t.Fatalf("get replies: %v", err)
+	}
+	if got, want := noteIDs(replies), []uint{reply.ID}; !sameIDs(got, want) {
+		t.Fatalf("replies = %v, want %v", got, want)
+	}
+}
+
+func TestAddReactionIsIdempotent(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	note := createNote(t, thread.ID, owner.ID, nil)
+
+	notes := NewNoteService()
+	added, err := notes.AddReaction(note.ID, owner.ID, &types.ReactionRequest{Emoji: "👍"})
+	if err != nil {
+		t.Fatalf("add reaction: %v", err)
+	}
+	if added == nil || added.Count != 1 {
+		t.Fatalf("first reaction = %+v, want a message with count 1", added)
+	}
+
+	again, err := notes.AddReaction(note.ID, owner.ID, &types.ReactionRequest{Emoji: "👍"})
+	if err != nil {
+		t.Fatalf("add the same reaction again: %v", err)
+	}
+	if again != nil {
+		t.Fatalf("repeated reaction = %+v, want no message", again)
+	}
+
+	var count int64
+	db.Model(&types.NoteReaction{}).Where("note_id = ?", note.ID).Count(&count)
+	if count != 1 {
+		t.Fatalf("stored reactions = %d, want 1", count)
+	}
+}
+
+func TestDeleteNoteTakesRepliesAlong(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	thread := createThread(t, owner.ID, 0, "Plans")
+
+	parent := createNote(t, thread.ID, owner.ID, nil)
+	reply := createNote(t, thread.ID, owner.ID, &parent.ID)
+	nested := createNote(t, thread.ID, owner.ID, &reply.ID)
+	other := createNote(t, thread.ID, owner.ID, nil)
+
+	notes := NewNoteService()
+	if err := notes.DeleteNote(parent.ID, owner.ID); err != nil {
+		t.Fatalf("delete note: %v", err)
+	}
+
+	all, _, err := notes.GetThreadNotes(thread.ID, owner.ID, &types.NoteListQuery{IncludeReplies: true})
+	if err != nil {
+		t.Fatalf("get thread notes: %v", err)
+	}
+	if got, want := noteIDs(all), []uint{other.ID}; !sameIDs(got, want) {
+		t.Fatalf("notes after the delete = %v, want %v", got, want)
+	}
+
+	// The trash shows the deleted note once; its replies come back with it
+	trash := NewTrashService()
+	trashed, err := trash.GetTrash(owner.ID, 0)
+	if err != nil {
+		t.Fatalf("get trash: %v", err)
+	}
+	if len(trashed.Notes) != 1 || trashed.Notes[0].ID != parent.ID {
+		t.Fatalf("trashed notes = %+v, want only the deleted note", trashed.Notes)
+	}
+	if _, err := trash.RestoreNote(reply.ID, owner.ID); err == nil {
+		t.Fatal("restored a reply while the note it replies to is in the trash")
+	}
+	if _, err := trash.RestoreNote(parent.ID, owner.ID); err != nil {
+		t.Fatalf("restore note: %v", err)
+	}
+
+	all, _, err = notes.GetThreadNotes(thread.ID, owner.ID, &types.NoteListQuery{IncludeReplies: true})
+	if err != nil {
+		t.Fatalf("get thread notes: %v", err)
+	}
+	if got, want := noteIDs(all), []uint{parent.ID, reply.ID, nested.ID, other.ID}; !sameIDs(got, want) {
+		t.Fatalf("notes after the restore = %v, want %v", got, want)
+	}
+}
+
+// sides rebuilds both texts of a diff: the kept and deleted runs give the
+// old text, the kept and inserted ones the new.
+func sides(changes []types.DiffChange) (from, to string) {
+	for _, change := range changes {
+		if change.Op != "insert" {
+			from += change.Text
+		}
+		if change.Op != "delete" {
+			to += change.Text
+		}
+	}
+	return from, to
+}
+
+func TestNoteRevisions(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	viewer := createUser(t, db, "viewer")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: viewer.ID, Role: types.ThreadRoleViewer})
+	note := createNote(t, thread.ID, owner.ID, nil)
+
+	notes := NewNoteService()
+	for _, content := range []string{"note one two", "note one two", "note three"} {
+		if _, err := notes.UpdateNote(note.ID, owner.ID, 0, &types.UpdateNoteRequest{Content: content}); err != nil {
+			t.Fatalf("update note: %v", err)
+		}
+	}
+
+	// Saving the same content twice leaves a single revision for it
+	revisions, err := notes.GetRevisions(note.ID, viewer.ID)
+	if err != nil {
+		t.Fatalf("get revisions: %v", err)
+	}
+	if len(revisions) != 3 || revisions[0].Number != 3 || !revisions[0].Current || revisions[2].Content != "note" {
+		t.Fatalf("revisions = %+v, want 3, 2 and 1 with 3 current", revisions)
+	}
+
+	cases := []struct {
+		name     string
+		number   int
+		query    types.NoteDiffQuery
+		from, to string
+	}{
+		{"previous revision", 2, types.NoteDiffQuery{}, "note", "note one two"},
+		{"first revision", 1, types.NoteDiffQuery{}, "", "note"},
+		{"chosen revision", 3, types.NoteDiffQuery{Against: 1, Mode: "word"}, "note", "note three"},
+		{"newer revision", 2, types.NoteDiffQuery{Against: 3, Mode: "word"}, "note three", "note one two"},
+	}
+	for _, tc := range cases {
+		diff, err := notes.DiffRevisions(note.ID, tc.number, viewer.ID, &tc.query)
+		if err != nil {
+			t.Fatalf("%s: diff revisions: %v", tc.name, err)
+		}
+		if from, to := sides(diff.Changes); from != tc.from || to != tc.to {
+			t.Errorf("%s: diff goes from %q to %q, want %q to %q", tc.name, from, to, tc.from, tc.to)
+		}
+	}
+
+	if _, err := notes.DiffRevisions(note.ID, 9, viewer.ID, &types.NoteDiffQuery{}); !errors.Is(err, errRevisionNotFound) {
+		t.Errorf("diffing a missing revision: err = %v, want %v", err, errRevisionNotFound)
+	}
+	if _, err := notes.DiffRevisions(note.ID, 2, viewer.ID, &types.NoteDiffQuery{Against: 9}); !errors.Is(err, errRevisionNotFound) {
+		t.Errorf("diffing against a missing revision: err = %v, want %v", err, errRevisionNotFound)
+	}
+}
+
+func TestRestoreRevision(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	editor := createUser(t, db, "editor")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: editor.ID, Role: types.ThreadRoleEditor})
+	note := createNote(t, thread.ID, owner.ID, nil)
+
+	notes := NewNoteService()
+	if _, err := notes.UpdateNote(note.ID, owner.ID, 0, &types.UpdateNoteRequest{Content: "rewritten"}); err != nil {
+		t.Fatalf("update note: %v", err)
+	}
+
+	if _, err := notes.RestoreRevision(note.ID, 1, editor.ID); !errors.Is(err, errAccessDenied) {
+		t.Fatalf("restore by someone else: err = %v, want %v", err, errAccessDenied)
+	}
+	if _, err := notes.RestoreRevision(note.ID, 5, owner.ID); !errors.Is(err, errRevisionNotFound) {
+		t.Fatalf("restore a missing revision: err = %v, want %v", err, errRevisionNotFound)
+	}
+
+	restored, err := notes.RestoreRevision(note.ID, 1, owner.ID)
+	if err != nil {
+		t.Fatalf("restore revision: %v", err)
+	}
+	if restored.Content != "note" {
+		t.Fatalf("content = %q, want the first revision's", restored.Content)
+	}
+
+	// The rollback is a revision of its own
+	revisions, err := notes.GetRevisions(note.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("get revisions: %v", err)
+	}
+	if len(revisions) != 3 || revisions[0].Content != "note" || revisions[1].Content != "rewritten" {
+		t.Fatalf("revisions = %+v, want the rollback on top of the rewrite", revisions)
+	}
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/auth"
+	"markmywords-backend/pkg/database"
+	"markmywords-backend/pkg/oidc"
+
+	"gorm.io/gorm"
+)
+
+// LoginStateTTL bounds how long a user may take at the identity provider.
+const LoginStateTTL = 10 * time.Minute
+
+type OIDCService struct {
+	db        *gorm.DB
+	providers map[string]*oidc.Provider
+	sessions  *SessionService
+}
+
+func NewOIDCService() *OIDCService {
+	return &OIDCService{
+		db:        database.GetDB(),
+		providers: oidc.LoadProviders(),
+		sessions:  NewSessionService(),
+	}
+}
+
+func (s *OIDCService) GetProviders() []types.OIDCProviderResponse {
+	responses := []types.OIDCProviderResponse{}
+	for _, provider := range s.providers {
+		responses = append(responses, types.OIDCProviderResponse{
+			Name:        provider.Config.Name,
+			DisplayName: provider.Config.DisplayName,
+		})
+	}
+
+	sort.Slice(responses, func(i, j int) bool {
+		return responses[i].Name < responses[j].Name
+	})
+	return responses
+}
+
+// StartLogin records a new login attempt and returns the provider
+// authorization URL the client should be sent to, together with a secret the
+// browser must present again at the callback.
+func (s *OIDCService) StartLogin(providerName string) (string, string, error) {
+	return s.start(providerName, 0)
+}
+
+// StartLink is StartLogin for a signed in user: the identity the provider
+// returns is linked to their account instead of being looked up.
+func (s *OIDCService) StartLink(providerName string, userID uint) (string, string, error) {
+	return s.start(providerName, userID)
+}
+
+func (s *OIDCService) start(providerName string, userID uint) (string, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", errors.New("unknown identity provider")
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	binding, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Drop abandoned attempts while we are here
+	s.db.Where("expires_at < ?", time.Now()).Delete(&types.OIDCLoginState{})
+
+	loginState := types.OIDCLoginState{
+		State:        state,
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		BindingHash:  auth.HashToken(binding),
+		UserID:       userID,
+		ExpiresAt:    time.Now().Add(LoginStateTTL),
+	}
+	if err := s.db.Create(&loginState).Error; err != nil {
+		return "", "", err
+	}
+
+	return authURL, binding, nil
+}
+
+// CompleteLogin finishes the authorization code flow, provisioning the local
+// account or linking the identity to the one that started the flow, and
+// returns the user together with a session token. binding is the secret
+// handed to the browser when the flow started, so a callback cannot be
+// completed in another browser.
+func (s *OIDCService) CompleteLogin(providerName string, req *types.OIDCCallbackRequest, binding string, client types.ClientInfo) (*types.UserResponse, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, "", errors.New("unknown identity provider")
+	}
+
+	var loginState types.OIDCLoginState
+	if err := s.db.Where("state = ? AND provider = ? AND binding_hash = ?", req.State, providerName, auth.HashToken(binding)).
+		First(&loginState).Error; err != nil {
+		return nil, "", errors.New("invalid or expired login state")
+	}
+
+	// A state can only be redeemed once
+	s.db.Delete(&loginState)
+
+	if time.Now().After(loginState.ExpiresAt) {
+		return nil, "", errors.New("invalid or expired login state")
+	}
+
+	claims, err := provider.Exchange(req.Code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var user *types.User
+	if loginState.UserID != 0 {
+		user, err = s.linkIdentity(loginState.UserID, providerName, claims)
+	} else {
+		user, err = s.resolveUser(providerName, claims)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if user.DeactivatedAt != nil {
+		return nil, "", errAccountDeactivated
+	}
+
+	token, err := s.sessions.CreateSession(user.ID, user.Email, client)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &types.UserResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		AvatarURL: user.AvatarURL,
+		CreatedAt: user.CreatedAt,
+	}, token, nil
+}
+
+func (s *OIDCService) GetUserIdentities(userID uint) ([]types.ExternalIdentityResponse, error) {
+	var identities []types.ExternalIdentity
+	if err := s.db.Where("user_id = ?", userID).Find(&identities).Error; err != nil {
+		return nil, err
+	}
+
+	var responses []types.ExternalIdentityResponse
+	for _, identity := range identities {
+		responses = append(responses, types.ExternalIdentityResponse{
+			ID:        identity.ID,
+			Provider:  identity.Provider,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt,
+		})
+	}
+
+	return responses, nil
+}
+
+// resolveUser finds the user linked to the external identity, or provisions
+// a new account just in time. An identity is never linked to an existing
+// account by its email address alone, since local accounts do not prove they
+// own their address; the user links it from a signed in session instead.
+func (s *OIDCService) resolveUser(providerName string, claims *oidc.Claims) (*types.User, error) {
+	var identity types.ExternalIdentity
+	err := s.db.Where("provider = ? AND subject = ?", providerName, claims.Subject).
+		Preload("User").
+		First(&identity).Error
+	if err == nil {
+		if identity.User.ID == 0 {
+			return nil, errors.New("linked account no longer exists")
+		}
+		return &identity.User, nil
+	}
+
+	if claims.Email == "" {
+		return nil, errors.New("identity provider did not return an email address")
+	}
+
+	var user types.User
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&types.User{}).Where("email = ?", claims.Email).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("an account with this email already exists, sign in to it and link the identity from your account")
+		}
+
+		username, err := uniqueUsername(tx, claims.PreferredUsername, claims.Email)
+		if err != nil {
+			return err
+		}
+
+		user = types.User{
+			Email:     claims.Email,
+			Username:  username,
+			FirstName: claims.GivenName,
+			LastName:  claims.FamilyName,
+		}
+		if user.FirstName == "" && user.LastName == "" {
+			user.FirstName = claims.Name
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&types.ExternalIdentity{
+			UserID:   user.ID,
+			Provider: providerName,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// linkIdentity links the external identity to the user who started the flow
+// from their session.
+func (s *OIDCService) linkIdentity(userID uint, providerName string, claims *oidc.Claims) (*types.User, error) {
+	var user types.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, errors.New("linked account no longer exists")
+	}
+
+	var identity types.ExternalIdentity
+	err := s.db.Where("provider = ? AND subject = ?", providerName, claims.Subject).First(&identity).Error
+	if err == nil {
+		if identity.UserID != user.ID {
+			return nil, errors.New("this identity is already linked to another account")
+		}
+		return &user, nil
+	}
+
+	if err := s.db.Create(&types.ExternalIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
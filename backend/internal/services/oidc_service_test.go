@@ -0,0 +1,219 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/oidc"
+	"markmywords-backend/pkg/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+func newOIDCTestService(t *testing.T, db *gorm.DB) (*OIDCService, *oidctest.Server) {
+	t.Helper()
+
+	server, err := oidctest.NewServer("mmw-client", "mmw-secret")
+	if err != nil {
+		t.Fatalf("start provider: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	provider := oidc.NewProvider(oidc.ProviderConfig{
+		Name:         "mock",
+		IssuerURL:    server.Issuer(),
+		ClientID:     server.ClientID,
+		ClientSecret: server.ClientSecret,
+		RedirectURL:  "http://localhost/callback",
+	})
+	return &OIDCService{
+		db:        db,
+		providers: map[string]*oidc.Provider{"mock": provider},
+		sessions:  NewSessionService(),
+	}, server
+}
+
+// loginAttempt is a callback as the browser that started the flow sends it.
+type loginAttempt struct {
+	callback *types.OIDCCallbackRequest
+	binding  string
+}
+
+func (a loginAttempt) complete(s *OIDCService) (*types.UserResponse, string, error) {
+	return s.CompleteLogin("mock", a.callback, a.binding, types.ClientInfo{})
+}
+
+// authorize starts a login, or a link for a non-zero userID, and has the
+// provider approve it with the claims.
+func authorize(t *testing.T, s *OIDCService, server *oidctest.Server, userID uint, claims jwt.MapClaims) loginAttempt {
+	t.Helper()
+
+	authURL, binding, err := s.start("mock", userID)
+	if err != nil {
+		t.Fatalf("start login: %v", err)
+	}
+	code, state, err := server.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	return loginAttempt{&types.OIDCCallbackRequest{Code: code, State: state}, binding}
+}
+
+func TestCompleteLoginProvisionsUser(t *testing.T) {
+	db := newTestDB(t)
+	s, server := newOIDCTestService(t, db)
+
+	user, token, err := authorize(t, s, server, 0, jwt.MapClaims{"sub": "ada-1", "email": "ada@example.com", "given_name": "Ada"}).complete(s)
+	if err != nil {
+		t.Fatalf("complete login: %v", err)
+	}
+	if user.Email != "ada@example.com" || user.FirstName != "Ada" || token == "" {
+		t.Fatalf("user = %+v, token = %q", user, token)
+	}
+
+	// The second login finds the account through the linked identity
+	again, _, err := authorize(t, s, server, 0, jwt.MapClaims{"sub": "ada-1", "email": "ada@example.com"}).complete(s)
+	if err != nil {
+		t.Fatalf("complete second login: %v", err)
+	}
+	if again.ID != user.ID {
+		t.Fatalf("second login user = %d, want %d", again.ID, user.ID)
+	}
+}
+
+func TestCompleteLoginStateIsSingleUse(t *testing.T) {
+	db := newTestDB(t)
+	s, server := newOIDCTestService(t, db)
+
+	attempt := authorize(t, s, server, 0, jwt.MapClaims{"email": "ada@example.com"})
+	if _, _, err := attempt.complete(s); err != nil {
+		t.Fatalf("complete login: %v", err)
+	}
+	if _, _, err := attempt.complete(s); err == nil {
+		t.Fatal("a login state was redeemed twice")
+	}
+
+	attempt.callback = &types.OIDCCallbackRequest{Code: "code", State: "made-up"}
+	if _, _, err := attempt.complete(s); err == nil {
+		t.Fatal("an unknown login state was accepted")
+	}
+}
+
+func TestCompleteLoginRejectsExpiredState(t *testing.T) {
+	db := newTestDB(t)
+	s, server := newOIDCTestService(t, db)
+
+	attempt := authorize(t, s, server, 0, jwt.MapClaims{"email": "ada@example.com"})
+	db.Model(&types.OIDCLoginState{}).Where("state = ?", attempt.callback.State).Update("expires_at", time.Now().Add(-time.Minute))
+
+	if _, _, err := attempt.complete(s); err == nil {
+		t.Fatal("an expired login state was accepted")
+	}
+	var states int64
+	db.Model(&types.OIDCLoginState{}).Count(&states)
+	if states != 0 {
+		t.Fatal("the expired login state was kept")
+	}
+}
+
+func TestCompleteLoginSendsStoredVerifier(t *testing.T) {
+	db := newTestDB(t)
+	s, server := newOIDCTestService(t, db)
+
+	// The provider only redeems the code for the verifier of the login attempt
+	attempt := authorize(t, s, server, 0, jwt.MapClaims{"email": "ada@example.com"})
+	db.Model(&types.OIDCLoginState{}).Where("state = ?", attempt.callback.State).Update("code_verifier", "another-verifier")
+
+	if _, _, err := attempt.complete(s); err == nil {
+		t.Fatal("the code was redeemed with another PKCE verifier")
+	}
+}
+
+func TestCompleteLoginChecksNonce(t *testing.T) {
+	db := newTestDB(t)
+	s, server := newOIDCTestService(t, db)
+
+	attempt := authorize(t, s, server, 0, jwt.MapClaims{"email": "ada@example.com", "nonce": "replayed-nonce"})
+	if _, _, err := attempt.complete(s); err == nil {
+		t.Fatal("an id_token with another login's nonce was accepted")
+	}
+}
+
+func TestCompleteLoginChecksBrowserBinding(t *testing.T) {
+	db := newTestDB(t)
+	s, server := newOIDCTestService(t, db)
+
+	// An attacker's authorization must not complete in the victim's browser,
+	// even one holding the secret of a login it started itself
+	attempt := authorize(t, s, server, 0, jwt.MapClaims{"sub": "attacker", "email": "attacker@example.com"})
+	_, victimBinding, err := s.StartLogin("mock")
+	if err != nil {
+		t.Fatalf("start login: %v", err)
+	}
+
+	for _, binding := range []string{"", "another-browser", victimBinding} {
+		forged := attempt
+		forged.binding = binding
+		if _, _, err := forged.complete(s); err == nil {
+			t.Fatalf("binding %q completed another browser's login", binding)
+		}
+	}
+	if _, _, err := attempt.complete(s); err != nil {
+		t.Fatalf("complete login in the browser that started it: %v", err)
+	}
+}
+
+func TestCompleteLoginNeverLinksByEmail(t *testing.T) {
+	db := newTestDB(t)
+	s, server := newOIDCTestService(t, db)
+
+	// Someone registered the address locally before its owner used SSO
+	existing := createUser(t, db, "ada")
+
+	for _, verified := range []interface{}{nil, false, "false", true, "true"} {
+		claims := jwt.MapClaims{"sub": "ada-1", "email": existing.Email}
+		if verified != nil {
+			claims["email_verified"] = verified
+		}
+		if _, _, err := authorize(t, s, server, 0, claims).complete(s); err == nil {
+			t.Fatalf("email_verified %v logged in to the existing account", verified)
+		}
+	}
+
+	var count int64
+	db.Model(&types.ExternalIdentity{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("%d identities were linked by email", count)
+	}
+}
+
+func TestLinkIdentityFromSession(t *testing.T) {
+	db := newTestDB(t)
+	s, server := newOIDCTestService(t, db)
+	ada := createUser(t, db, "ada")
+	grace := createUser(t, db, "grace")
+
+	user, _, err := authorize(t, s, server, ada.ID, jwt.MapClaims{"sub": "ada-1", "email": "ada@corp.example.com"}).complete(s)
+	if err != nil {
+		t.Fatalf("link identity: %v", err)
+	}
+	if user.ID != ada.ID {
+		t.Fatalf("linked to %d, want %d", user.ID, ada.ID)
+	}
+
+	// The identity now logs in to the account it was linked to
+	user, _, err = authorize(t, s, server, 0, jwt.MapClaims{"sub": "ada-1", "email": "ada@corp.example.com"}).complete(s)
+	if err != nil {
+		t.Fatalf("log in with the linked identity: %v", err)
+	}
+	if user.ID != ada.ID {
+		t.Fatalf("logged in as %d, want %d", user.ID, ada.ID)
+	}
+
+	// It cannot be linked to a second account
+	if _, _, err := authorize(t, s, server, grace.ID, jwt.MapClaims{"sub": "ada-1"}).complete(s); err == nil {
+		t.Fatal("an identity linked to one account was linked to another")
+	}
+}
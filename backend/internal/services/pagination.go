@@ -0,0 +1,124 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed or were made
+// for a different sort.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// sortKey is one column of a keyset ordering. The last key of every ordering
+// must be unique so that pages never overlap.
+type sortKey struct {
+	column string
+	desc   bool
+	isTime bool
+}
+
+// pageCursor is the position after the last row of a page. It is handed to
+// clients as an opaque string and only valid for the sort it was made for.
+type pageCursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+func encodeCursor(sort string, values ...interface{}) string {
+	data, err := json.Marshal(pageCursor{Sort: sort, Values: values})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// orderByKeys orders the query by the keys and, when a cursor is given,
+// restricts it to the rows after the cursor.
+func orderByKeys(db *gorm.DB, sort string, keys []sortKey, cursor string) (*gorm.DB, error) {
+	if cursor != "" {
+		values, err := decodeCursor(cursor, sort, keys)
+		if err != nil {
+			return nil, err
+		}
+
+		// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+		var clauses []string
+		var args []interface{}
+		for i, key := range keys {
+			var parts []string
+			for j := 0; j < i; j++ {
+				parts = append(parts, keys[j].column+" = ?")
+				args = append(args, values[j])
+			}
+
+			op := " > ?"
+			if key.desc {
+				op = " < ?"
+			}
+			parts = append(parts, key.column+op)
+			args = append(args, values[i])
+
+			clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+		}
+
+		db = db.Where("("+strings.Join(clauses, " OR ")+")", args...)
+	}
+
+	for _, key := range keys {
+		if key.desc {
+			db = db.Order(key.column + " DESC")
+		} else {
+			db = db.Order(key.column + " ASC")
+		}
+	}
+
+	return db, nil
+}
+
+func decodeCursor(cursor, sort string, keys []sortKey) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var decoded pageCursor
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if decoded.Sort != sort || len(decoded.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+
+	for i, key := range keys {
+		if !key.isTime {
+			continue
+		}
+		text, ok := decoded.Values[i].(string)
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		t, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		decoded.Values[i] = t
+	}
+
+	return decoded.Values, nil
+}
+
+// pageLimit applies the default and maximum page size.
+func pageLimit(limit, fallback, max int) int {
+	if limit <= 0 {
+		return fallback
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"markmywords-backend/internal/types"
+)
+
+func TestListThreadsPagesEverySort(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	threads := NewThreadService()
+
+	// Shared timestamps leave the order to the tie breakers
+	same := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i, title := range []string{"Echo", "Alpha", "Delta", "Bravo", "Charlie"} {
+		thread := createThread(t, owner.ID, 0, title)
+		if i%2 == 0 {
+			db.Model(&types.Thread{}).Where("id = ?", thread.ID).UpdateColumns(map[string]interface{}{"created_at": same, "updated_at": same})
+		}
+		if i == 1 {
+			createNote(t, thread.ID, owner.ID, nil)
+		}
+	}
+
+	for _, sort := range []string{"custom", "updated", "created", "last_note", "title"} {
+		t.Run(sort, func(t *testing.T) {
+			all, next, err := threads.ListThreads(owner.ID, &types.ThreadListQuery{Sort: sort})
+			if err != nil {
+				t.Fatalf("list threads: %v", err)
+			}
+			if next != "" || len(all) != 5 {
+				t.Fatalf("unpaged listing has %d threads and cursor %q", len(all), next)
+			}
+
+			var paged []uint
+			cursor := ""
+			for page := 0; page < 5; page++ {
+				listed, next, err := threads.ListThreads(owner.ID, &types.ThreadListQuery{Sort: sort, Limit: 2, Cursor: cursor})
+				if err != nil {
+					t.Fatalf("list page %d: %v", page, err)
+				}
+				paged = append(paged, threadIDs(listed)...)
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			if want := threadIDs(all); !sameOrder(paged, want) {
+				t.Fatalf("paged = %v, want %v", paged, want)
+			}
+		})
+	}
+}
+
+func TestListThreadsRejectsBadCursors(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	createThread(t, owner.ID, 0, "One")
+	createThread(t, owner.ID, 0, "Two")
+	threads := NewThreadService()
+
+	_, titleCursor, err := threads.ListThreads(owner.ID, &types.ThreadListQuery{Sort: "title", Limit: 1})
+	if err != nil || titleCursor == "" {
+		t.Fatalf("list threads: cursor %q, err %v", titleCursor, err)
+	}
+
+	cases := []struct {
+		name   string
+		sort   string
+		cursor string
+	}{
+		{"not base64", "title", "%%%"},
+		{"not json", "title", "bm90IGpzb24"},
+		{"another sort", "created", titleCursor},
+		{"bad time", "created", encodeCursor("created", "yesterday", 1)},
+		{"too few values", "created", encodeCursor("created", time.Now())},
+	}
+	for _, tc := range cases {
+		if _, _, err := threads.ListThreads(owner.ID, &types.ThreadListQuery{Sort: tc.sort, Cursor: tc.cursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: err = %v, want ErrInvalidCursor", tc.name, err)
+		}
+	}
+}
+
+func TestGetThreadNotesPages(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	editor := createUser(t, db, "editor")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: editor.ID, Role: types.ThreadRoleEditor})
+
+	var oldest, byEditor []uint
+	for i := 0; i < 5; i++ {
+		author := owner.ID
+		if i%2 == 1 {
+			author = editor.ID
+		}
+		note := createNote(t, thread.ID, author, nil)
+		oldest = append(oldest, note.ID)
+		if author == editor.ID {
+			byEditor = append(byEditor, note.ID)
+		}
+	}
+	newest := make([]uint, len(oldest))
+	for i, id := range oldest {
+		newest[len(oldest)-1-i] = id
+	}
+
+	cases := []struct {
+		name  string
+		query types.NoteListQuery
+		want  []uint
+	}{
+		{"oldest", types.NoteListQuery{Limit: 2}, oldest},
+		{"newest", types.NoteListQuery{Sort: "newest", Limit: 2}, newest},
+		{"by author", types.NoteListQuery{Author: editor.ID, Limit: 1}, byEditor},
+	}
+	notes := NewNoteService()
+	for _, tc := range cases {
+		var paged []uint
+		query := tc.query
+		for page := 0; page < 6; page++ {
+			listed, next, err := notes.GetThreadNotes(thread.ID, owner.ID, &query)
+			if err != nil {
+				t.Fatalf("%s: get thread notes: %v", tc.name, err)
+			}
+			paged = append(paged, noteIDs(listed)...)
+			if next == "" {
+				break
+			}
+			query.Cursor = next
+		}
+		if !sameOrder(paged, tc.want) {
+			t.Errorf("%s: paged = %v, want %v", tc.name, paged, tc.want)
+		}
+	}
+}
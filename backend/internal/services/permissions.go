@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+
+	"markmywords-backend/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// ThreadAction is something a user may try to do in a thread.
+type ThreadAction string
+
+const (
+	ActionViewThread   ThreadAction = "view_thread"
+	ActionPostNote     ThreadAction = "post_note"
+	ActionEditThread   ThreadAction = "edit_thread"
+	ActionInvite       ThreadAction = "invite"
+	ActionModerate     ThreadAction = "moderate"
+	ActionManageThread ThreadAction = "manage_thread"
+)
+
+// requiredRoles maps each action to the lowest role allowed to perform it.
+var requiredRoles = map[ThreadAction]types.ThreadRole{
+	ActionViewThread:   types.ThreadRoleViewer,
+	ActionPostNote:     types.ThreadRoleCommenter,
+	ActionEditThread:   types.ThreadRoleEditor,
+	ActionInvite:       types.ThreadRoleAdmin,
+	ActionModerate:     types.ThreadRoleAdmin,
+	ActionManageThread: types.ThreadRoleOwner,
+}
+
+var (
+	errThreadNotFound = errors.New("thread not found")
+	errAccessDenied   = errors.New("access denied")
+)
+
+// PermissionChecker is the single place that decides what a user may do in a
+// thread.
+type PermissionChecker struct {
+	db *gorm.DB
+}
+
+func NewPermissionChecker(db *gorm.DB) *PermissionChecker {
+	return &PermissionChecker{db: db}
+}
+
+// Role returns the user's role on the thread, the higher of their own and any
+// of their groups', or "" when they are not a member. Users outside the
+// thread's workspace never have a role on it.
+func (p *PermissionChecker) Role(thread *types.Thread, userID uint) types.ThreadRole {
+	if !p.inWorkspace(thread, userID) {
+		return ""
+	}
+	if thread.UserID == userID {
+		return types.ThreadRoleOwner
+	}
+
+	role := groupRoles(p.db, userID, []uint{thread.ID})[thread.ID]
+
+	var collaborator types.ThreadCollaborator
+	if err := p.db.Where("thread_id = ? AND user_id = ?", thread.ID, userID).First(&collaborator).Error; err == nil && collaborator.Role.Rank() > role.Rank() {
+		role = collaborator.Role
+	}
+	return role
+}
+
+// Can reports whether the user may perform the action on the thread.
+func (p *PermissionChecker) Can(thread *types.Thread, userID uint, action ThreadAction) bool {
+	return p.inWorkspace(thread, userID) && allowed(thread, p.Role(thread, userID), action)
+}
+
+// Authorize loads the thread and checks that the user may perform the action.
+// Threads of other workspaces are reported as not found.
+func (p *PermissionChecker) Authorize(threadID, userID uint, action ThreadAction) (*types.Thread, types.ThreadRole, error) {
+	var thread types.Thread
+	if err := p.db.First(&thread, threadID).Error; err != nil {
+		return nil, "", errThreadNotFound
+	}
+	if !p.inWorkspace(&thread, userID) {
+		return nil, "", errThreadNotFound
+	}
+
+	role := p.Role(&thread, userID)
+	if !allowed(&thread, role, action) {
+		return nil, role, errAccessDenied
+	}
+
+	return &thread, role, nil
+}
+
+// inWorkspace reports whether the user belongs to the thread's workspace.
+// Personal threads are in no workspace and open to everyone they are shared
+// with.
+func (p *PermissionChecker) inWorkspace(thread *types.Thread, userID uint) bool {
+	if thread.WorkspaceID == nil {
+		return true
+	}
+	return workspaceRole(p.db, *thread.WorkspaceID, userID) != ""
+}
+
+// allowed reports whether a user with the given role may perform the action.
+// Public threads can be read by anyone, member or not. Callers check first that
+// the user belongs to the thread's workspace.
+func allowed(thread *types.Thread, role types.ThreadRole, action ThreadAction) bool {
+	if role.AtLeast(requiredRoles[action]) {
+		return true
+	}
+	return action == ActionViewThread && !thread.IsPrivate
+}
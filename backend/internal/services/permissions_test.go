@@ -0,0 +1,73 @@
+package services
+
+import (
+	"testing"
+
+	"markmywords-backend/internal/types"
+)
+
+func TestRoleLadder(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	thread := createThread(t, owner.ID, 0, "Plans")
+
+	users := map[types.ThreadRole]uint{types.ThreadRoleOwner: owner.ID}
+	for _, role := range []types.ThreadRole{types.ThreadRoleViewer, types.ThreadRoleCommenter, types.ThreadRoleEditor, types.ThreadRoleAdmin} {
+		user := createUser(t, db, string(role))
+		db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: user.ID, Role: role})
+		users[role] = user.ID
+	}
+	users[""] = createUser(t, db, "stranger").ID
+
+	// Each action against the roles, from viewer up to owner
+	cases := []struct {
+		action ThreadAction
+		want   map[types.ThreadRole]bool
+	}{
+		{ActionViewThread, map[types.ThreadRole]bool{types.ThreadRoleViewer: true, types.ThreadRoleCommenter: true, types.ThreadRoleEditor: true, types.ThreadRoleAdmin: true, types.ThreadRoleOwner: true}},
+		{ActionPostNote, map[types.ThreadRole]bool{types.ThreadRoleCommenter: true, types.ThreadRoleEditor: true, types.ThreadRoleAdmin: true, types.ThreadRoleOwner: true}},
+		{ActionEditThread, map[types.ThreadRole]bool{types.ThreadRoleEditor: true, types.ThreadRoleAdmin: true, types.ThreadRoleOwner: true}},
+		{ActionInvite, map[types.ThreadRole]bool{types.ThreadRoleAdmin: true, types.ThreadRoleOwner: true}},
+		{ActionModerate, map[types.ThreadRole]bool{types.ThreadRoleAdmin: true, types.ThreadRoleOwner: true}},
+		{ActionManageThread, map[types.ThreadRole]bool{types.ThreadRoleOwner: true}},
+	}
+
+	permissions := NewPermissionChecker(db)
+	for _, tc := range cases {
+		for role, userID := range users {
+			_, got, err := permissions.Authorize(thread.ID, userID, tc.action)
+			if got != role {
+				t.Errorf("%s: role of the %q user = %q", tc.action, role, got)
+			}
+			if allowed := err == nil; allowed != tc.want[role] {
+				t.Errorf("%s by %q: allowed = %v, want %v", tc.action, role, allowed, tc.want[role])
+			}
+			if err != nil && err != errAccessDenied {
+				t.Errorf("%s by %q: err = %v, want errAccessDenied", tc.action, role, err)
+			}
+		}
+	}
+}
+
+func TestPublicThreadsAreReadOnlyToStrangers(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	stranger := createUser(t, db, "stranger")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	permissions := NewPermissionChecker(db)
+
+	if _, _, err := permissions.Authorize(thread.ID, stranger.ID, ActionViewThread); err != errAccessDenied {
+		t.Fatalf("view a private thread: err = %v, want errAccessDenied", err)
+	}
+
+	db.Model(&types.Thread{}).Where("id = ?", thread.ID).Update("is_private", false)
+	if _, _, err := permissions.Authorize(thread.ID, stranger.ID, ActionViewThread); err != nil {
+		t.Fatalf("view a public thread: %v", err)
+	}
+	if _, _, err := permissions.Authorize(thread.ID, stranger.ID, ActionPostNote); err != errAccessDenied {
+		t.Fatalf("post to a public thread: err = %v, want errAccessDenied", err)
+	}
+	if _, _, err := permissions.Authorize(thread.ID+1, stranger.ID, ActionViewThread); err != errThreadNotFound {
+		t.Fatalf("view a missing thread: err = %v, want errThreadNotFound", err)
+	}
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// hasUnreadCondition matches threads with notes the user has not read yet.
+// It takes the user ID twice.
+const hasUnreadCondition = "EXISTS (SELECT 1 FROM notes WHERE notes.thread_id = threads.id AND notes.deleted_at IS NULL AND notes.user_id <> ? AND " +
+	"notes.id > COALESCE((SELECT thread_read_markers.last_read_note_id FROM thread_read_markers WHERE thread_read_markers.thread_id = threads.id AND thread_read_markers.user_id = ?), 0))"
+
+var errReadReceiptsDisabled = errors.New("read receipts are disabled for this thread")
+
+type ReadService struct {
+	db          *gorm.DB
+	permissions *PermissionChecker
+}
+
+func NewReadService() *ReadService {
+	db := database.GetDB()
+	return &ReadService{
+		db:          db,
+		permissions: NewPermissionChecker(db),
+	}
+}
+
+// MarkRead moves the user's read marker on a thread up to a note, or up to
+// the latest note when noteID is zero. The marker never moves back, so a late
+// event from another device can't mark read notes unread again. The returned
+// receipt is set when a member's marker moved on a thread with read receipts
+// on, and should be broadcast as a note_read event.
+func (s *ReadService) MarkRead(threadID, userID, noteID uint) (*types.ReadMarkerResponse, *types.NoteReadMessage, error) {
+	thread, role, err := s.permissions.Authorize(threadID, userID, ActionViewThread)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if noteID == 0 {
+		// Note IDs grow with creation time, so the highest is the latest
+		err := s.db.Model(&types.Note{}).
+			Select("COALESCE(MAX(id), 0)").
+			Where("thread_id = ?", threadID).
+			Scan(&noteID).Error
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		var note types.Note
+		if err := s.db.Where("id = ? AND thread_id = ?", noteID, threadID).First(&note).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil, errors.New("note not found in this thread")
+			}
+			return nil, nil, err
+		}
+	}
+
+	var marker types.ThreadReadMarker
+	var advanced bool
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		marker = types.ThreadReadMarker{ThreadID: threadID, UserID: userID}
+		if err := tx.Where(&marker).FirstOrCreate(&marker).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"last_read_at": time.Now()}
+		if noteID > marker.LastReadNoteID {
+			updates["last_read_note_id"] = noteID
+			advanced = true
+		}
+		return tx.Model(&marker).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response := &types.ReadMarkerResponse{
+		ThreadID:       threadID,
+		LastReadNoteID: marker.LastReadNoteID,
+		LastReadAt:     marker.LastReadAt,
+	}
+
+	unread, err := unreadCounts(s.db, userID, []uint{threadID})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(unread) > 0 {
+		response.UnreadCount = unread[0].UnreadCount
+		response.FirstUnreadNoteID = &unread[0].FirstUnreadNoteID
+	}
+
+	var receipt *types.NoteReadMessage
+	if advanced && thread.ReadReceiptsEnabled && role != "" {
+		var user types.User
+		if err := s.db.First(&user, userID).Error; err != nil {
+			return nil, nil, err
+		}
+		receipt = &types.NoteReadMessage{
+			ThreadID: threadID,
+			UserID:   userID,
+			Username: user.Username,
+			NoteID:   marker.LastReadNoteID,
+			ReadAt:   marker.LastReadAt,
+		}
+	}
+
+	return response, receipt, nil
+}
+
+// GetUnreadSummary counts the unread notes in every thread of the workspace
+// the user owns or that is shared with them, the thread with the newest
+// unread note first.
+func (s *ReadService) GetUnreadSummary(userID, workspaceID uint) (*types.UnreadSummary, error) {
+	memberThreads := inWorkspaceScope(s.db.Model(&types.Thread{}), workspaceID).
+		Select("threads.id").
+		Where("(threads.user_id = ? OR "+sharedCondition+")", userID, userID, userID)
+
+	unread, err := unreadCounts(s.db, userID, memberThreads)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &types.UnreadSummary{Threads: []types.ThreadUnread{}}
+	if len(unread) == 0 {
+		return summary, nil
+	}
+
+	threadIDs := make([]uint, len(unread))
+	for i := range unread {
+		threadIDs[i] = unread[i].ThreadID
+	}
+
+	var threads []types.Thread
+	if err := s.db.Where("id IN ?", threadIDs).Find(&threads).Error; err != nil {
+		return nil, err
+	}
+	titles := make(map[uint]string, len(threads))
+	for _, thread := range threads {
+		titles[thread.ID] = thread.Title
+	}
+
+	for _, row := range unread {
+		summary.TotalUnread += row.UnreadCount
+		summary.Threads = append(summary.Threads, types.ThreadUnread{
+			ThreadID:          row.ThreadID,
+			Title:             titles[row.ThreadID],
+			UnreadCount:       row.UnreadCount,
+			FirstUnreadNoteID: row.FirstUnreadNoteID,
+		})
+	}
+
+	return summary, nil
+}
+
+// GetNoteReceipts lists which members of the note's thread have read it, going
+// by each member's read marker.
+func (s *ReadService) GetNoteReceipts(noteID, userID uint) (*types.NoteReceiptsResponse, error) {
+	var note types.Note
+	if err := s.db.First(&note, noteID).Error; err != nil {
+		return nil, err
+	}
+
+	thread, _, err := s.permissions.Authorize(note.ThreadID, userID, ActionViewThread)
+	if err != nil {
+		return nil, err
+	}
+	if !thread.ReadReceiptsEnabled {
+		return nil, errReadReceiptsDisabled
+	}
+
+	var collaborators []types.ThreadCollaborator
+	if err := s.db.Where("thread_id = ?", thread.ID).Find(&collaborators).Error; err != nil {
+		return nil, err
+	}
+	memberIDs := []uint{thread.UserID}
+	for _, collab := range collaborators {
+		memberIDs = append(memberIDs, collab.UserID)
+	}
+	memberIDs = append(memberIDs, groupMemberIDs(s.db, thread.ID)...)
+
+	var members []types.User
+	if err := s.db.Where("id IN ? AND id <> ?", memberIDs, note.UserID).Order("id").Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	var markers []types.ThreadReadMarker
+	if err := s.db.Where("thread_id = ? AND last_read_note_id >= ?", thread.ID, note.ID).Find(&markers).Error; err != nil {
+		return nil, err
+	}
+	readAt := make(map[uint]time.Time, len(markers))
+	for _, marker := range markers {
+		readAt[marker.UserID] = marker.LastReadAt
+	}
+
+	response := &types.NoteReceiptsResponse{
+		NoteID:    note.ID,
+		ReadBy:    []types.NoteReceipt{},
+		NotReadBy: []types.UserResponse{},
+	}
+	for i := range members {
+		member := &members[i]
+		if at, ok := readAt[member.ID]; ok {
+			response.ReadBy = append(response.ReadBy, types.NoteReceipt{
+				User:   toUserResponse(member),
+				ReadAt: at,
+			})
+		} else {
+			response.NotReadBy = append(response.NotReadBy, toUserResponse(member))
+		}
+	}
+
+	return response, nil
+}
+
+type threadUnreadCount struct {
+	ThreadID          uint
+	UnreadCount       int
+	FirstUnreadNoteID uint
+}
+
+// unreadCounts counts the notes others wrote after the user's read marker in
+// each of the threads, which is a list of IDs or a subquery. Threads without
+// unread notes are left out.
+func unreadCounts(db *gorm.DB, userID uint, threads interface{}) ([]threadUnreadCount, error) {
+	var counts []threadUnreadCount
+	err := db.Model(&types.Note{}).
+		Select("notes.thread_id, COUNT(*) AS unread_count, MIN(notes.id) AS first_unread_note_id").
+		Joins("LEFT JOIN thread_read_markers ON thread_read_markers.thread_id = notes.thread_id AND thread_read_markers.user_id = ?", userID).
+		Where("notes.thread_id IN (?)", threads).
+		Where("notes.user_id <> ? AND notes.id > COALESCE(thread_read_markers.last_read_note_id, 0)", userID).
+		Group("notes.thread_id").
+		Order("MAX(notes.id) DESC").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// attachUnread fills in unread_count and first_unread_note_id for the user.
+func attachUnread(db *gorm.DB, userID uint, responses []types.ThreadResponse) error {
+	if len(responses) == 0 {
+		return nil
+	}
+
+	threadIDs := make([]uint, len(responses))
+	for i := range responses {
+		threadIDs[i] = responses[i].ID
+	}
+
+	counts, err := unreadCounts(db, userID, threadIDs)
+	if err != nil {
+		return err
+	}
+
+	byThread := make(map[uint]threadUnreadCount, len(counts))
+	for _, count := range counts {
+		byThread[count.ThreadID] = count
+	}
+
+	for i := range responses {
+		if count, ok := byThread[responses[i].ID]; ok {
+			responses[i].UnreadCount = count.UnreadCount
+			firstUnread := count.FirstUnreadNoteID
+			responses[i].FirstUnreadNoteID = &firstUnread
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"markmywords-backend/internal/types"
+)
+
+func TestMarkReadNeverMovesBack(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	reader := createUser(t, db, "reader")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	other := createThread(t, owner.ID, 0, "Other")
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: reader.ID, Role: types.ThreadRoleCommenter})
+
+	first := createNote(t, thread.ID, owner.ID, nil)
+	second := createNote(t, thread.ID, owner.ID, nil)
+	third := createNote(t, thread.ID, owner.ID, nil)
+	createNote(t, thread.ID, reader.ID, nil) // their own notes are never unread
+	elsewhere := createNote(t, other.ID, owner.ID, nil)
+
+	cases := []struct {
+		name        string
+		noteID      uint
+		wantMarker  uint
+		wantUnread  int
+		firstUnread uint
+	}{
+		{"up to a note", second.ID, second.ID, 1, third.ID},
+		{"an earlier note", first.ID, second.ID, 1, third.ID},
+		{"everything", 0, 0, 0, 0},
+	}
+	reads := NewReadService()
+	for _, tc := range cases {
+		marker, _, err := reads.MarkRead(thread.ID, reader.ID, tc.noteID)
+		if err != nil {
+			t.Fatalf("%s: mark read: %v", tc.name, err)
+		}
+		if tc.wantMarker != 0 && marker.LastReadNoteID != tc.wantMarker {
+			t.Errorf("%s: marker at %d, want %d", tc.name, marker.LastReadNoteID, tc.wantMarker)
+		}
+		if marker.UnreadCount != tc.wantUnread {
+			t.Errorf("%s: unread = %d, want %d", tc.name, marker.UnreadCount, tc.wantUnread)
+		}
+		if tc.firstUnread != 0 && (marker.FirstUnreadNoteID == nil || *marker.FirstUnreadNoteID != tc.firstUnread) {
+			t.Errorf("%s: first unread = %v, want %d", tc.name, marker.FirstUnreadNoteID, tc.firstUnread)
+		}
+	}
+
+	if _, _, err := reads.MarkRead(thread.ID, reader.ID, elsewhere.ID); err == nil {
+		t.Fatal("marked a thread read up to another thread's note")
+	}
+}
+
+func TestUnreadCountsInListings(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	reader := createUser(t, db, "reader")
+	unread := createThread(t, owner.ID, 0, "Unread")
+	read := createThread(t, owner.ID, 0, "Read")
+	for _, thread := range []*types.ThreadResponse{unread, read} {
+		db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: reader.ID, Role: types.ThreadRoleViewer})
+		createNote(t, thread.ID, owner.ID, nil)
+		createNote(t, thread.ID, owner.ID, nil)
+	}
+
+	reads := NewReadService()
+	if _, _, err := reads.MarkRead(read.ID, reader.ID, 0); err != nil {
+		t.Fatalf("mark read: %v", err)
+	}
+
+	threads := NewThreadService()
+	cases := []struct {
+		hasUnread string
+		want      []uint
+	}{
+		{"true", []uint{unread.ID}},
+		{"false", []uint{read.ID}},
+	}
+	for _, tc := range cases {
+		listed, _, err := threads.ListThreads(reader.ID, &types.ThreadListQuery{Scope: "shared", HasUnread: tc.hasUnread})
+		if err != nil {
+			t.Fatalf("list threads: %v", err)
+		}
+		if got := threadIDs(listed); !sameIDs(got, tc.want) {
+			t.Errorf("has_unread=%s: threads = %v, want %v", tc.hasUnread, got, tc.want)
+		}
+		if tc.hasUnread == "true" && len(listed) == 1 && listed[0].UnreadCount != 2 {
+			t.Errorf("unread_count = %d, want 2", listed[0].UnreadCount)
+		}
+	}
+
+	summary, err := reads.GetUnreadSummary(reader.ID, 0)
+	if err != nil {
+		t.Fatalf("get unread summary: %v", err)
+	}
+	if summary.TotalUnread != 2 || len(summary.Threads) != 1 || summary.Threads[0].ThreadID != unread.ID {
+		t.Fatalf("summary = %+v, want 2 unread notes in %d", summary, unread.ID)
+	}
+}
+
+func TestMarkReadReceipts(t *testing.T) {
+	cases := []struct {
+		name        string
+		receipts    bool
+		public      bool
+		member      bool
+		readTwice   bool
+		wantReceipt bool
+	}{
+		{name: "member with receipts on", receipts: true, member: true, wantReceipt: true},
+		{name: "receipts off", receipts: false, member: true},
+		{name: "stranger on a public thread", receipts: true, public: true},
+		{name: "marker did not move", receipts: true, member: true, readTwice: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestDB(t)
+			owner := createUser(t, db, "owner")
+			reader := createUser(t, db, "reader")
+			thread := createThread(t, owner.ID, 0, "Plans")
+			db.Model(&types.Thread{}).Where("id = ?", thread.ID).Updates(map[string]interface{}{
+				"read_receipts_enabled": tc.receipts,
+				"is_private":            !tc.public,
+			})
+			if tc.member {
+				db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: reader.ID, Role: types.ThreadRoleViewer})
+			}
+			note := createNote(t, thread.ID, owner.ID, nil)
+
+			reads := NewReadService()
+			if tc.readTwice {
+				if _, _, err := reads.MarkRead(thread.ID, reader.ID, note.ID); err != nil {
+					t.Fatalf("mark read: %v", err)
+				}
+			}
+			_, receipt, err := reads.MarkRead(thread.ID, reader.ID, note.ID)
+			if err != nil {
+				t.Fatalf("mark read: %v", err)
+			}
+
+			if (receipt != nil) != tc.wantReceipt {
+				t.Fatalf("receipt = %+v, want one: %v", receipt, tc.wantReceipt)
+			}
+			if receipt != nil && (receipt.NoteID != note.ID || receipt.UserID != reader.ID || receipt.Username != reader.Username) {
+				t.Fatalf("receipt = %+v, want %s reading note %d", receipt, reader.Username, note.ID)
+			}
+		})
+	}
+}
+
+func TestGetNoteReceipts(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	reader := createUser(t, db, "reader")
+	lurker := createUser(t, db, "lurker")
+	stranger := createUser(t, db, "stranger")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	for _, user := range []*types.User{reader, lurker} {
+		db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: user.ID, Role: types.ThreadRoleViewer})
+	}
+	note := createNote(t, thread.ID, owner.ID, nil)
+
+	db.Model(&types.Thread{}).Where("id = ?", thread.ID).Update("read_receipts_enabled", false)
+	reads := NewReadService()
+	if _, err := reads.GetNoteReceipts(note.ID, reader.ID); !errors.Is(err, errReadReceiptsDisabled) {
+		t.Fatalf("receipts while disabled: err = %v, want %v", err, errReadReceiptsDisabled)
+	}
+
+	db.Model(&types.Thread{}).Where("id = ?", thread.ID).Update("read_receipts_enabled", true)
+	if _, _, err := reads.MarkRead(thread.ID, reader.ID, 0); err != nil {
+		t.Fatalf("mark read: %v", err)
+	}
+
+	if _, err := reads.GetNoteReceipts(note.ID, stranger.ID); err == nil {
+		t.Fatal("a stranger saw the receipts of a private thread")
+	}
+
+	receipts, err := reads.GetNoteReceipts(note.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("get receipts: %v", err)
+	}
+	if len(receipts.ReadBy) != 1 || receipts.ReadBy[0].User.ID != reader.ID {
+		t.Errorf("read by = %+v, want only %s", receipts.ReadBy, reader.Username)
+	}
+	// The author is in neither list
+	if len(receipts.NotReadBy) != 1 || receipts.NotReadBy[0].ID != lurker.ID {
+		t.Errorf("not read by = %+v, want only %s", receipts.NotReadBy, lurker.Username)
+	}
+}
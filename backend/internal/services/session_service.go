@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/auth"
+	"markmywords-backend/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// lastSeenResolution limits how often validating a session writes to the database.
+const lastSeenResolution = 5 * time.Minute
+
+type SessionService struct {
+	db *gorm.DB
+}
+
+func NewSessionService() *SessionService {
+	return &SessionService{
+		db: database.GetDB(),
+	}
+}
+
+// CreateSession starts a login session and returns its signed token.
+func (s *SessionService) CreateSession(userID uint, email string, client types.ClientInfo) (string, error) {
+	now := time.Now()
+	session := types.Session{
+		UserID:     userID,
+		TokenID:    uuid.NewString(),
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+		ExpiresAt:  now.Add(auth.TokenTTL),
+		LastSeenAt: now,
+	}
+
+	if err := s.db.Create(&session).Error; err != nil {
+		return "", err
+	}
+
+	return auth.GenerateToken(userID, email, session.TokenID)
+}
+
+// ValidateSession checks that the session behind a token is still active.
+func (s *SessionService) ValidateSession(tokenID string, userID uint) (*types.Session, error) {
+	var session types.Session
+	if err := s.db.Where("token_id = ? AND user_id = ?", tokenID, userID).First(&session).Error; err != nil {
+		return nil, errors.New("session not found")
+	}
+
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return nil, errors.New("session is no longer active")
+	}
+
+	if now.Sub(session.LastSeenAt) > lastSeenResolution {
+		s.db.Model(&session).UpdateColumn("last_seen_at", now)
+	}
+
+	return &session, nil
+}
+
+func (s *SessionService) GetUserSessions(userID, currentSessionID uint) ([]types.SessionResponse, error) {
+	var sessions []types.Session
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []types.SessionResponse
+	for _, session := range sessions {
+		responses = append(responses, types.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			Current:    session.ID == currentSessionID,
+			ExpiresAt:  session.ExpiresAt,
+			LastSeenAt: session.LastSeenAt,
+			CreatedAt:  session.CreatedAt,
+		})
+	}
+
+	return responses, nil
+}
+
+func (s *SessionService) RevokeSession(sessionID, userID uint) error {
+	var session types.Session
+	if err := s.db.First(&session, sessionID).Error; err != nil {
+		return errors.New("session not found")
+	}
+
+	if session.UserID != userID {
+		return errors.New("access denied")
+	}
+
+	return s.db.Model(&session).Update("revoked_at", time.Now()).Error
+}
+
+// RevokeOtherSessions ends every active session of the user except keepSessionID.
+func (s *SessionService) RevokeOtherSessions(tx *gorm.DB, userID, keepSessionID uint) error {
+	return tx.Model(&types.Session{}).
+		Where("user_id = ? AND id != ? AND revoked_at IS NULL", userID, keepSessionID).
+		Update("revoked_at", time.Now()).Error
+}
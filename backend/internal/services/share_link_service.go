@@ -0,0 +1,276 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/auth"
+	"markmywords-backend/pkg/config"
+	"markmywords-backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var errInvalidShareLink = errors.New("share link is invalid or has expired")
+
+type ShareLinkService struct {
+	db          *gorm.DB
+	permissions *PermissionChecker
+	threads     *ThreadService
+}
+
+func NewShareLinkService() *ShareLinkService {
+	db := database.GetDB()
+	return &ShareLinkService{
+		db:          db,
+		permissions: NewPermissionChecker(db),
+		threads:     NewThreadService(),
+	}
+}
+
+// CreateLink creates a share link for the thread. Like invites, a link cannot
+// grant a role above its creator's.
+func (s *ShareLinkService) CreateLink(threadID, userID uint, req *types.CreateShareLinkRequest) (*types.CreateShareLinkResponse, error) {
+	thread, creatorRole, err := s.permissions.Authorize(threadID, userID, ActionInvite)
+	if err != nil {
+		return nil, err
+	}
+
+	if thread.WorkspaceID != nil && req.AllowAnonymous {
+		return nil, errors.New("workspace threads cannot be shared anonymously")
+	}
+
+	role := req.Role
+	if role == "" {
+		role = types.ThreadRoleViewer
+	}
+	if !creatorRole.AtLeast(role) {
+		return nil, errors.New("cannot grant a role higher than your own")
+	}
+
+	token, hash, err := auth.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	link := types.ShareLink{
+		ThreadID:       threadID,
+		CreatedByID:    userID,
+		TokenHash:      hash,
+		TokenPrefix:    token[:6],
+		Role:           role,
+		AllowAnonymous: req.AllowAnonymous,
+		MaxUses:        req.MaxUses,
+	}
+
+	if req.ExpiresInHours > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if err := s.db.Create(&link).Error; err != nil {
+		return nil, err
+	}
+	s.db.First(&link.CreatedBy, userID)
+
+	return &types.CreateShareLinkResponse{
+		ShareLinkResponse: toShareLinkResponse(&link),
+		Token:             token,
+		URL:               strings.TrimRight(config.Get("APP_BASE_URL", "http://localhost:3000"), "/") + "/share/" + url.PathEscape(token),
+	}, nil
+}
+
+func (s *ShareLinkService) GetThreadLinks(threadID, userID uint) ([]types.ShareLinkResponse, error) {
+	if _, _, err := s.permissions.Authorize(threadID, userID, ActionInvite); err != nil {
+		return nil, err
+	}
+
+	var links []types.ShareLink
+	err := s.db.Where("thread_id = ?", threadID).
+		Preload("CreatedBy").
+		Order("created_at DESC").
+		Find(&links).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []types.ShareLinkResponse
+	for i := range links {
+		responses = append(responses, toShareLinkResponse(&links[i]))
+	}
+
+	return responses, nil
+}
+
+func (s *ShareLinkService) RevokeLink(threadID, linkID, userID uint) error {
+	if _, _, err := s.permissions.Authorize(threadID, userID, ActionInvite); err != nil {
+		return err
+	}
+
+	var link types.ShareLink
+	if err := s.db.Where("id = ? AND thread_id = ?", linkID, threadID).First(&link).Error; err != nil {
+		return errors.New("share link not found")
+	}
+
+	if link.RevokedAt != nil {
+		return nil
+	}
+
+	return s.db.Model(&link).Update("revoked_at", time.Now()).Error
+}
+
+// JoinByLink adds the user to the link's thread with the link's role.
+func (s *ShareLinkService) JoinByLink(token string, userID uint) (*types.ThreadResponse, error) {
+	link, thread, err := s.resolve(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if thread.WorkspaceID != nil && workspaceRole(s.db, *thread.WorkspaceID, userID) == "" {
+		return nil, errors.New("this thread belongs to a workspace you are not a member of")
+	}
+	if s.permissions.Role(thread, userID) != "" {
+		return nil, errors.New("you are already a member of this thread")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Claim a use without racing other joins for the last one
+		result := tx.Model(&types.ShareLink{}).
+			Where("id = ? AND (max_uses = 0 OR use_count < max_uses)", link.ID).
+			UpdateColumn("use_count", gorm.Expr("use_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidShareLink
+		}
+
+		collaborator := types.ThreadCollaborator{
+			ThreadID: thread.ID,
+			UserID:   userID,
+			Role:     link.Role,
+		}
+		if err := tx.Create(&collaborator).Error; err != nil {
+			return err
+		}
+
+		return recordActivity(tx, thread.ID, userID, types.ActivityCollaboratorJoined, nil, "", link.Role)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.threads.GetThreadByID(thread.ID, userID)
+}
+
+// GetSharedThread shows what a link leads to without requiring a login.
+func (s *ShareLinkService) GetSharedThread(token string) (*types.SharedThreadResponse, error) {
+	link, thread, err := s.resolve(token)
+	if err != nil {
+		return nil, err
+	}
+
+	response := types.SharedThreadResponse{
+		Title:          thread.Title,
+		Description:    thread.Description,
+		Role:           link.Role,
+		AllowAnonymous: link.AllowAnonymous,
+	}
+
+	var owner types.User
+	if err := s.db.First(&owner, thread.UserID).Error; err == nil {
+		response.Owner = *toSharedAuthor(&owner)
+	}
+
+	// Workspace notes never leave the workspace
+	if !link.AllowAnonymous || thread.WorkspaceID != nil {
+		return &response, nil
+	}
+
+	var notes []types.Note
+	err = s.db.Where("thread_id = ?", thread.ID).
+		Preload("User").
+		Order("created_at ASC").
+		Find(&notes).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range notes {
+		note := &notes[i]
+		shared := types.SharedNoteResponse{
+			ID:        note.ID,
+			Content:   note.Content,
+			CreatedAt: note.CreatedAt,
+			UpdatedAt: note.UpdatedAt,
+		}
+		if note.User.ID != 0 {
+			shared.Author = toSharedAuthor(&note.User)
+		}
+		response.Notes = append(response.Notes, shared)
+	}
+
+	return &response, nil
+}
+
+// resolve finds a live link and its thread. Links stop working once their
+// creator can no longer invite to the thread. Usage limits only apply to
+// joining and are checked there.
+func (s *ShareLinkService) resolve(token string) (*types.ShareLink, *types.Thread, error) {
+	var link types.ShareLink
+	if err := s.db.Where("token_hash = ?", auth.HashToken(token)).First(&link).Error; err != nil {
+		return nil, nil, errInvalidShareLink
+	}
+
+	if link.RevokedAt != nil {
+		return nil, nil, errInvalidShareLink
+	}
+	if link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt) {
+		return nil, nil, errInvalidShareLink
+	}
+	var thread types.Thread
+	if err := s.db.First(&thread, link.ThreadID).Error; err != nil {
+		return nil, nil, errInvalidShareLink
+	}
+
+	if !s.permissions.Can(&thread, link.CreatedByID, ActionInvite) {
+		return nil, nil, errInvalidShareLink
+	}
+
+	return &link, &thread, nil
+}
+
+func toShareLinkResponse(link *types.ShareLink) types.ShareLinkResponse {
+	response := types.ShareLinkResponse{
+		ID:             link.ID,
+		ThreadID:       link.ThreadID,
+		TokenPrefix:    link.TokenPrefix,
+		Role:           link.Role,
+		AllowAnonymous: link.AllowAnonymous,
+		MaxUses:        link.MaxUses,
+		UseCount:       link.UseCount,
+		ExpiresAt:      link.ExpiresAt,
+		RevokedAt:      link.RevokedAt,
+		CreatedAt:      link.CreatedAt,
+	}
+
+	if link.CreatedBy.ID != 0 {
+		response.CreatedBy = toUserResponse(&link.CreatedBy)
+	}
+
+	return response
+}
+
+func toSharedAuthor(user *types.User) *types.SharedAuthor {
+	return &types.SharedAuthor{
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		AvatarURL: user.AvatarURL,
+	}
+}
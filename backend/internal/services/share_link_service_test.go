@@ -0,0 +1,148 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"markmywords-backend/internal/types"
+
+	"gorm.io/gorm"
+)
+
+func createLink(t *testing.T, threadID, userID uint, req *types.CreateShareLinkRequest) *types.CreateShareLinkResponse {
+	t.Helper()
+
+	link, err := NewShareLinkService().CreateLink(threadID, userID, req)
+	if err != nil {
+		t.Fatalf("create share link: %v", err)
+	}
+	return link
+}
+
+func TestCreateLinkRoleLimits(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	admin := createUser(t, db, "admin")
+	editor := createUser(t, db, "editor")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: admin.ID, Role: types.ThreadRoleAdmin})
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: editor.ID, Role: types.ThreadRoleEditor})
+
+	cases := []struct {
+		name   string
+		userID uint
+		role   types.ThreadRole
+		ok     bool
+	}{
+		{"owner shares as admin", owner.ID, types.ThreadRoleAdmin, true},
+		{"admin shares as editor", admin.ID, types.ThreadRoleEditor, true},
+		{"admin shares as owner", admin.ID, types.ThreadRoleOwner, false},
+		{"editor shares", editor.ID, types.ThreadRoleViewer, false},
+	}
+	links := NewShareLinkService()
+	for _, tc := range cases {
+		_, err := links.CreateLink(thread.ID, tc.userID, &types.CreateShareLinkRequest{Role: tc.role})
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v, want ok %v", tc.name, err, tc.ok)
+		}
+	}
+}
+
+func TestJoinByLinkUsageLimit(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	link := createLink(t, thread.ID, owner.ID, &types.CreateShareLinkRequest{Role: types.ThreadRoleCommenter, MaxUses: 2})
+
+	links := NewShareLinkService()
+	first := createUser(t, db, "first")
+	if _, err := links.JoinByLink(link.Token, first.ID); err != nil {
+		t.Fatalf("first join: %v", err)
+	}
+	// Joining again neither works nor uses the link up
+	if _, err := links.JoinByLink(link.Token, first.ID); err == nil {
+		t.Fatal("a member joined twice")
+	}
+	second := createUser(t, db, "second")
+	if _, err := links.JoinByLink(link.Token, second.ID); err != nil {
+		t.Fatalf("second join: %v", err)
+	}
+	third := createUser(t, db, "third")
+	if _, err := links.JoinByLink(link.Token, third.ID); err != errInvalidShareLink {
+		t.Fatalf("join past the limit: err = %v, want errInvalidShareLink", err)
+	}
+
+	var stored types.ShareLink
+	db.First(&stored, link.ID)
+	if stored.UseCount != 2 {
+		t.Fatalf("use count = %d, want 2", stored.UseCount)
+	}
+	var role types.ThreadCollaborator
+	db.Where("thread_id = ? AND user_id = ?", thread.ID, second.ID).First(&role)
+	if role.Role != types.ThreadRoleCommenter {
+		t.Fatalf("joined as %q, want the link's commenter role", role.Role)
+	}
+}
+
+func TestShareLinkStopsWorking(t *testing.T) {
+	cases := []struct {
+		name  string
+		spoil func(db *gorm.DB, link *types.CreateShareLinkResponse, threadID, creatorID uint)
+	}{
+		{"expired", func(db *gorm.DB, link *types.CreateShareLinkResponse, threadID, creatorID uint) {
+			db.Model(&types.ShareLink{}).Where("id = ?", link.ID).Update("expires_at", time.Now().Add(-time.Minute))
+		}},
+		{"revoked", func(db *gorm.DB, link *types.CreateShareLinkResponse, threadID, creatorID uint) {
+			NewShareLinkService().RevokeLink(threadID, link.ID, creatorID)
+		}},
+		{"creator demoted", func(db *gorm.DB, link *types.CreateShareLinkResponse, threadID, creatorID uint) {
+			db.Model(&types.ThreadCollaborator{}).Where("thread_id = ? AND user_id = ?", threadID, creatorID).Update("role", types.ThreadRoleEditor)
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestDB(t)
+			owner := createUser(t, db, "owner")
+			admin := createUser(t, db, "admin")
+			joiner := createUser(t, db, "joiner")
+			thread := createThread(t, owner.ID, 0, "Plans")
+			db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: admin.ID, Role: types.ThreadRoleAdmin})
+
+			link := createLink(t, thread.ID, admin.ID, &types.CreateShareLinkRequest{AllowAnonymous: true})
+			links := NewShareLinkService()
+			if _, err := links.GetSharedThread(link.Token); err != nil {
+				t.Fatalf("preview before: %v", err)
+			}
+
+			tc.spoil(db, link, thread.ID, admin.ID)
+			if _, err := links.GetSharedThread(link.Token); err != errInvalidShareLink {
+				t.Fatalf("preview: err = %v, want errInvalidShareLink", err)
+			}
+			if _, err := links.JoinByLink(link.Token, joiner.ID); err != errInvalidShareLink {
+				t.Fatalf("join: err = %v, want errInvalidShareLink", err)
+			}
+		})
+	}
+}
+
+func TestSharedThreadShowsNotesOnlyToAnonymousLinks(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	createNote(t, thread.ID, owner.ID, nil)
+
+	links := NewShareLinkService()
+	for _, anonymous := range []bool{false, true} {
+		link := createLink(t, thread.ID, owner.ID, &types.CreateShareLinkRequest{AllowAnonymous: anonymous})
+		shared, err := links.GetSharedThread(link.Token)
+		if err != nil {
+			t.Fatalf("get shared thread: %v", err)
+		}
+		if shared.Title != "Plans" {
+			t.Fatalf("title = %q, want Plans", shared.Title)
+		}
+		if got := len(shared.Notes) > 0; got != anonymous {
+			t.Fatalf("allow_anonymous %v: notes shown = %v", anonymous, got)
+		}
+	}
+}
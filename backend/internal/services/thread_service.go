@@ -2,6 +2,8 @@ package services
 
 import (
 	"errors"
+	"math"
+	"time"
 
 	"markmywords-backend/internal/types"
 	"markmywords-backend/pkg/database"
@@ -10,21 +12,48 @@ import (
 )
 
 type ThreadService struct {
-	db *gorm.DB
+	db          *gorm.DB
+	permissions *PermissionChecker
 }
 
 func NewThreadService() *ThreadService {
+	db := database.GetDB()
 	return &ThreadService{
-		db: database.GetDB(),
+		db:          db,
+		permissions: NewPermissionChecker(db),
 	}
 }
 
-func (s *ThreadService) CreateThread(req *types.CreateThreadRequest, userID uint) (*types.ThreadResponse, error) {
+// CreateThread creates a thread owned by the user, in the workspace if one is
+// given. The caller has checked that the user belongs to the workspace.
+func (s *ThreadService) CreateThread(req *types.CreateThreadRequest, userID, workspaceID uint) (*types.ThreadResponse, error) {
+	// Threads are private and show read receipts unless asked otherwise.
+	// Workspaces choose their own default visibility.
+	isPrivate := true
+	var threadWorkspaceID *uint
+	if workspaceID != 0 {
+		var workspace types.Workspace
+		if err := s.db.First(&workspace, workspaceID).Error; err != nil {
+			return nil, errWorkspaceNotFound
+		}
+		isPrivate = workspace.DefaultPrivate
+		threadWorkspaceID = &workspace.ID
+	}
+	if req.IsPrivate != nil {
+		isPrivate = *req.IsPrivate
+	}
+	readReceipts := true
+	if req.ReadReceiptsEnabled != nil {
+		readReceipts = *req.ReadReceiptsEnabled
+	}
+
 	thread := types.Thread{
-		Title:       req.Title,
-		Description: req.Description,
-		IsPrivate:   req.IsPrivate,
-		UserID:      userID,
+		Title:               req.Title,
+		Description:         req.Description,
+		IsPrivate:           isPrivate,
+		ReadReceiptsEnabled: readReceipts,
+		UserID:              userID,
+		WorkspaceID:         threadWorkspaceID,
 	}
 
 	if err := s.db.Create(&thread).Error; err != nil {
@@ -34,125 +63,240 @@ func (s *ThreadService) CreateThread(req *types.CreateThreadRequest, userID uint
 	return s.GetThreadByID(thread.ID, userID)
 }
 
-func (s *ThreadService) GetUserThreads(userID uint) ([]types.ThreadResponse, error) {
+// threadSorts are the orderings of ListThreads. "custom" puts pinned threads
+// first, then the user's own order, then the most recently updated.
+var threadSorts = map[string][]sortKey{
+	"custom": {
+		{column: "COALESCE(thread_preferences.pinned, 0)", desc: true},
+		{column: "COALESCE(thread_preferences.sort_order, 2147483647)"},
+		{column: "threads.updated_at", desc: true, isTime: true},
+		{column: "threads.id", desc: true},
+	},
+	"updated": {
+		{column: "threads.updated_at", desc: true, isTime: true},
+		{column: "threads.id", desc: true},
+	},
+	"created": {
+		{column: "threads.created_at", desc: true, isTime: true},
+		{column: "threads.id", desc: true},
+	},
+	"last_note": {
+		{column: lastNoteAtColumn, desc: true, isTime: true},
+		{column: "threads.id", desc: true},
+	},
+	"title": {
+		{column: "threads.title"},
+		{column: "threads.id"},
+	},
+}
+
+const lastNoteAtColumn = "COALESCE((SELECT MAX(notes.created_at) FROM notes WHERE notes.thread_id = threads.id AND notes.deleted_at IS NULL), threads.created_at)"
+
+// ListThreads returns a page of the threads the user owns ("owned"), has been
+// shared ("shared") or both ("all"), and the cursor of the next page.
+func (s *ThreadService) ListThreads(userID uint, query *types.ThreadListQuery) ([]types.ThreadResponse, string, error) {
+	db, err := s.applyListQuery(s.db, userID, query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch query.Scope {
+	case "shared":
+		db = db.Where(sharedCondition, userID, userID)
+	case "all":
+		db = db.Where("(threads.user_id = ? OR "+sharedCondition+")", userID, userID, userID)
+	default:
+		db = db.Where("threads.user_id = ?", userID)
+	}
+
+	db = inWorkspaceScope(db, query.WorkspaceID)
+
+	if query.Owner != 0 {
+		db = db.Where("threads.user_id = ?", query.Owner)
+	}
+	if !query.UpdatedSince.IsZero() {
+		since := query.UpdatedSince.Local()
+		db = db.Where("(threads.updated_at >= ? OR EXISTS (SELECT 1 FROM notes WHERE notes.thread_id = threads.id AND notes.updated_at >= ? AND notes.deleted_at IS NULL))", since, since)
+	}
+
+	sort := query.Sort
+	if sort == "" {
+		sort = "custom"
+	}
+	keys := threadSorts[sort]
+
+	db, err = orderByKeys(db, sort, keys, query.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	limit := pageLimit(query.Limit, 50, 100)
+
 	var threads []types.Thread
-	err := s.db.Where("user_id = ?", userID).
+	err = db.Limit(limit + 1).
 		Preload("User").
 		Preload("Collaborators.User").
-		Preload("Notes").
 		Find(&threads).Error
 
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	hasMore := len(threads) > limit
+	if hasMore {
+		threads = threads[:limit]
 	}
 
-	var responses []types.ThreadResponse
-	for _, thread := range threads {
-		response := types.ThreadResponse{
-			ID:          thread.ID,
-			Title:       thread.Title,
-			Description: thread.Description,
-			IsPrivate:   thread.IsPrivate,
-			UserID:      thread.UserID,
-			NotesCount:  len(thread.Notes),
-			CreatedAt:   thread.CreatedAt,
-			UpdatedAt:   thread.UpdatedAt,
-		}
-
-		if thread.User.ID != 0 {
-			response.User = types.UserResponse{
-				ID:        thread.User.ID,
-				Email:     thread.User.Email,
-				Username:  thread.User.Username,
-				FirstName: thread.User.FirstName,
-				LastName:  thread.User.LastName,
-				CreatedAt: thread.User.CreatedAt,
+	threadIDs := make([]uint, len(threads))
+	for i := range threads {
+		threadIDs[i] = threads[i].ID
+	}
+	roleByGroup := groupRoles(s.db, userID, threadIDs)
+
+	responses := []types.ThreadResponse{}
+	for i := range threads {
+		thread := &threads[i]
+
+		role := types.ThreadRoleOwner
+		if thread.UserID != userID {
+			role = roleByGroup[thread.ID]
+			for _, collab := range thread.Collaborators {
+				if collab.UserID == userID && collab.Role.Rank() > role.Rank() {
+					role = collab.Role
+				}
 			}
 		}
 
-		for _, collab := range thread.Collaborators {
-			response.Collaborators = append(response.Collaborators, types.UserResponse{
-				ID:        collab.User.ID,
-				Email:     collab.User.Email,
-				Username:  collab.User.Username,
-				FirstName: collab.User.FirstName,
-				LastName:  collab.User.LastName,
-				CreatedAt: collab.User.CreatedAt,
-			})
-		}
+		responses = append(responses, toThreadResponse(thread, role))
+	}
+
+	if err := s.attachDetails(userID, responses); err != nil {
+		return nil, "", err
+	}
 
-		responses = append(responses, response)
+	var nextCursor string
+	if hasMore {
+		nextCursor = threadCursor(sort, &responses[len(responses)-1])
 	}
 
-	return responses, nil
+	return responses, nextCursor, nil
 }
 
-func (s *ThreadService) GetThreadByID(threadID, userID uint) (*types.ThreadResponse, error) {
-	var thread types.Thread
-	err := s.db.Where("id = ?", threadID).
+// publicThreadSorts are the orderings of GetPublicThreads. "recent" orders by
+// the latest note, "popular" by the number of notes and members.
+var publicThreadSorts = map[string][]sortKey{
+	"recent": {
+		{column: lastNoteAtColumn, desc: true, isTime: true},
+		{column: "threads.id", desc: true},
+	},
+	"popular": {
+		{column: popularityColumn, desc: true},
+		{column: "threads.id", desc: true},
+	},
+}
+
+const popularityColumn = "((SELECT COUNT(*) FROM notes WHERE notes.thread_id = threads.id AND notes.deleted_at IS NULL) + " +
+	"(SELECT COUNT(*) FROM thread_collaborators WHERE thread_collaborators.thread_id = threads.id AND thread_collaborators.deleted_at IS NULL))"
+
+// GetPublicThreads browses the threads any user can read, or in a workspace
+// any of its members can read, and returns the cursor of the next page.
+func (s *ThreadService) GetPublicThreads(userID uint, query *types.PublicThreadsQuery) ([]types.ThreadResponse, string, error) {
+	db := inWorkspaceScope(s.db.Where("threads.is_private = ?", false), query.WorkspaceID)
+
+	if query.Q != "" {
+		db = db.Where("(threads.title LIKE ? OR threads.description LIKE ?)", "%"+query.Q+"%", "%"+query.Q+"%")
+	}
+
+	sort := query.Sort
+	if sort == "" {
+		sort = "recent"
+	}
+
+	db, err := orderByKeys(db, sort, publicThreadSorts[sort], query.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	limit := pageLimit(query.Limit, 20, 100)
+
+	var threads []types.Thread
+	err = db.Limit(limit + 1).
 		Preload("User").
 		Preload("Collaborators.User").
-		Preload("Notes").
-		First(&thread).Error
+		Find(&threads).Error
 
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	// Check if user has access to this thread
-	if thread.UserID != userID {
-		// Check if user is a collaborator
-		var collaborator types.ThreadCollaborator
-		if err := s.db.Where("thread_id = ? AND user_id = ?", threadID, userID).First(&collaborator).Error; err != nil {
-			return nil, errors.New("access denied")
-		}
+	hasMore := len(threads) > limit
+	if hasMore {
+		threads = threads[:limit]
 	}
 
-	response := types.ThreadResponse{
-		ID:          thread.ID,
-		Title:       thread.Title,
-		Description: thread.Description,
-		IsPrivate:   thread.IsPrivate,
-		UserID:      thread.UserID,
-		NotesCount:  len(thread.Notes),
-		CreatedAt:   thread.CreatedAt,
-		UpdatedAt:   thread.UpdatedAt,
+	responses := []types.ThreadResponse{}
+	for i := range threads {
+		thread := &threads[i]
+		responses = append(responses, toThreadResponse(thread, s.permissions.Role(thread, userID)))
 	}
 
-	if thread.User.ID != 0 {
-		response.User = types.UserResponse{
-			ID:        thread.User.ID,
-			Email:     thread.User.Email,
-			Username:  thread.User.Username,
-			FirstName: thread.User.FirstName,
-			LastName:  thread.User.LastName,
-			CreatedAt: thread.User.CreatedAt,
-		}
+	if err := s.attachDetails(userID, responses); err != nil {
+		return nil, "", err
 	}
 
-	for _, collab := range thread.Collaborators {
-		response.Collaborators = append(response.Collaborators, types.UserResponse{
-			ID:        collab.User.ID,
-			Email:     collab.User.Email,
-			Username:  collab.User.Username,
-			FirstName: collab.User.FirstName,
-			LastName:  collab.User.LastName,
-			CreatedAt: collab.User.CreatedAt,
-		})
+	var nextCursor string
+	if hasMore {
+		nextCursor = s.publicThreadCursor(sort, &responses[len(responses)-1])
 	}
 
-	return &response, nil
+	return responses, nextCursor, nil
 }
 
-func (s *ThreadService) UpdateThread(threadID, userID uint, req *types.UpdateThreadRequest) (*types.ThreadResponse, error) {
+func (s *ThreadService) GetThreadByID(threadID, userID uint) (*types.ThreadResponse, error) {
+	_, role, err := s.permissions.Authorize(threadID, userID, ActionViewThread)
+	if err != nil {
+		return nil, err
+	}
+
 	var thread types.Thread
-	if err := s.db.First(&thread, threadID).Error; err != nil {
+	err = s.db.Where("id = ?", threadID).
+		Preload("User").
+		Preload("Collaborators.User").
+		First(&thread).Error
+
+	if err != nil {
 		return nil, err
 	}
 
-	// Only the owner can update the thread
-	if thread.UserID != userID {
-		return nil, errors.New("access denied")
+	responses := []types.ThreadResponse{toThreadResponse(&thread, role)}
+	if err := s.attachDetails(userID, responses); err != nil {
+		return nil, err
+	}
+	return &responses[0], nil
+}
+
+// UpdateThread applies the update if the thread is still at the given
+// version; 0 updates whatever version is current.
+func (s *ThreadService) UpdateThread(threadID, userID, version uint, req *types.UpdateThreadRequest) (*types.ThreadResponse, error) {
+	thread, role, err := s.permissions.Authorize(threadID, userID, ActionEditThread)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		version = thread.Version
+	}
+	if thread.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	// Editors may change the content, only the owner may change visibility
+	// and read receipts
+	canManage := role.AtLeast(requiredRoles[ActionManageThread])
+	if req.IsPrivate != nil && *req.IsPrivate != thread.IsPrivate && !canManage {
+		return nil, errAccessDenied
+	}
+	if req.ReadReceiptsEnabled != nil && *req.ReadReceiptsEnabled != thread.ReadReceiptsEnabled && !canManage {
+		return nil, errAccessDenied
 	}
 
 	// Update fields
@@ -165,8 +309,17 @@ func (s *ThreadService) UpdateThread(threadID, userID uint, req *types.UpdateThr
 	if req.IsPrivate != nil {
 		thread.IsPrivate = *req.IsPrivate
 	}
+	if req.ReadReceiptsEnabled != nil {
+		thread.ReadReceiptsEnabled = *req.ReadReceiptsEnabled
+	}
 
-	if err := s.db.Save(&thread).Error; err != nil {
+	err = updateVersioned(s.db, &types.Thread{}, thread.ID, version, map[string]interface{}{
+		"title":                 thread.Title,
+		"description":           thread.Description,
+		"is_private":            thread.IsPrivate,
+		"read_receipts_enabled": thread.ReadReceiptsEnabled,
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -174,69 +327,356 @@ func (s *ThreadService) UpdateThread(threadID, userID uint, req *types.UpdateThr
 }
 
 func (s *ThreadService) DeleteThread(threadID, userID uint) error {
-	var thread types.Thread
-	if err := s.db.First(&thread, threadID).Error; err != nil {
+	thread, _, err := s.permissions.Authorize(threadID, userID, ActionManageThread)
+	if err != nil {
 		return err
 	}
 
-	// Only the owner can delete the thread
-	if thread.UserID != userID {
-		return errors.New("access denied")
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return trashThread(tx, thread)
+	})
+}
+
+// UpdatePreferences changes how the user has organized a thread for
+// themselves. Anyone who can read a thread may organize it.
+func (s *ThreadService) UpdatePreferences(threadID, userID uint, req *types.UpdateThreadPreferencesRequest) (*types.ThreadResponse, error) {
+	if _, _, err := s.permissions.Authorize(threadID, userID, ActionViewThread); err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Archived != nil {
+		updates["archived"] = *req.Archived
+	}
+	if req.Pinned != nil {
+		updates["pinned"] = *req.Pinned
+	}
+	if req.Starred != nil {
+		updates["starred"] = *req.Starred
+	}
+	if req.ClearSortOrder {
+		updates["sort_order"] = nil
+	} else if req.SortOrder != nil {
+		updates["sort_order"] = *req.SortOrder
+	}
+
+	if len(updates) > 0 {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			pref, err := findOrCreatePreference(tx, threadID, userID)
+			if err != nil {
+				return err
+			}
+			return tx.Model(pref).Updates(updates).Error
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return s.db.Delete(&thread).Error
+	return s.GetThreadByID(threadID, userID)
 }
 
-func (s *ThreadService) GetCollaborativeThreads(userID uint) ([]types.ThreadResponse, error) {
-	var threads []types.Thread
-	err := s.db.Joins("JOIN thread_collaborators ON threads.id = thread_collaborators.thread_id").
-		Where("thread_collaborators.user_id = ?", userID).
-		Preload("User").
-		Preload("Collaborators.User").
-		Preload("Notes").
-		Find(&threads).Error
+// ReorderThreads stores the user's custom order. Threads left out keep
+// their position, if they had one.
+func (s *ThreadService) ReorderThreads(userID uint, threadIDs []uint) error {
+	seen := make(map[uint]bool)
+	for _, threadID := range threadIDs {
+		if seen[threadID] {
+			return errors.New("thread_ids contains duplicates")
+		}
+		seen[threadID] = true
+
+		if _, _, err := s.permissions.Authorize(threadID, userID, ActionViewThread); err != nil {
+			return err
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for position, threadID := range threadIDs {
+			pref, err := findOrCreatePreference(tx, threadID, userID)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(pref).Update("sort_order", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
 
+// applyListQuery joins the user's preferences onto a thread listing and
+// applies the archived, pinned, starred, has_unread, labels and folder
+// filters.
+func (s *ThreadService) applyListQuery(db *gorm.DB, userID uint, query *types.ThreadListQuery) (*gorm.DB, error) {
+	db = db.Joins("LEFT JOIN thread_preferences ON thread_preferences.thread_id = threads.id AND thread_preferences.user_id = ?", userID)
+
+	switch query.Archived {
+	case "true":
+		db = db.Where("thread_preferences.archived = ?", true)
+	case "all":
+	default:
+		db = db.Where("COALESCE(thread_preferences.archived, ?) = ?", false, false)
+	}
+
+	if query.Pinned {
+		db = db.Where("thread_preferences.pinned = ?", true)
+	}
+	if query.Starred {
+		db = db.Where("thread_preferences.starred = ?", true)
+	}
+
+	switch query.HasUnread {
+	case "true":
+		db = db.Where(hasUnreadCondition, userID, userID)
+	case "false":
+		db = db.Where("NOT "+hasUnreadCondition, userID, userID)
+	}
+
+	labelIDs, err := parseLabelIDs(query.Labels)
 	if err != nil {
 		return nil, err
 	}
+	if len(labelIDs) > 0 {
+		if query.LabelMatch == "all" {
+			db = db.Where("(SELECT COUNT(DISTINCT thread_labels.label_id) FROM thread_labels WHERE thread_labels.thread_id = threads.id AND thread_labels.label_id IN ?) = ?", labelIDs, len(labelIDs))
+		} else {
+			db = db.Where("EXISTS (SELECT 1 FROM thread_labels WHERE thread_labels.thread_id = threads.id AND thread_labels.label_id IN ?)", labelIDs)
+		}
+	}
+
+	if query.Folder != "" {
+		folderIDs, err := folderFilter(s.db, userID, query.Folder, query.Recursive)
+		if err != nil {
+			return nil, err
+		}
+		if folderIDs == nil {
+			db = db.Where("thread_preferences.folder_id IS NULL")
+		} else {
+			db = db.Where("thread_preferences.folder_id IN ?", folderIDs)
+		}
+	}
 
-	var responses []types.ThreadResponse
-	for _, thread := range threads {
-		response := types.ThreadResponse{
-			ID:          thread.ID,
-			Title:       thread.Title,
-			Description: thread.Description,
-			IsPrivate:   thread.IsPrivate,
-			UserID:      thread.UserID,
-			NotesCount:  len(thread.Notes),
-			CreatedAt:   thread.CreatedAt,
-			UpdatedAt:   thread.UpdatedAt,
-		}
-
-		if thread.User.ID != 0 {
-			response.User = types.UserResponse{
-				ID:        thread.User.ID,
-				Email:     thread.User.Email,
-				Username:  thread.User.Username,
-				FirstName: thread.User.FirstName,
-				LastName:  thread.User.LastName,
-				CreatedAt: thread.User.CreatedAt,
+	return db, nil
+}
+
+// attachDetails fills in what a thread response carries besides the thread
+// itself, as seen by the user.
+func (s *ThreadService) attachDetails(userID uint, responses []types.ThreadResponse) error {
+	if err := s.attachPreferences(userID, responses); err != nil {
+		return err
+	}
+	if err := attachNoteStats(s.db, responses); err != nil {
+		return err
+	}
+	if err := attachUnread(s.db, userID, responses); err != nil {
+		return err
+	}
+	return attachLabels(s.db, responses)
+}
+
+// attachPreferences fills in the user's preferences and folder path on each
+// response.
+func (s *ThreadService) attachPreferences(userID uint, responses []types.ThreadResponse) error {
+	if len(responses) == 0 {
+		return nil
+	}
+
+	threadIDs := make([]uint, len(responses))
+	for i := range responses {
+		threadIDs[i] = responses[i].ID
+	}
+
+	var prefs []types.ThreadPreference
+	if err := s.db.Where("user_id = ? AND thread_id IN ?", userID, threadIDs).Find(&prefs).Error; err != nil {
+		return err
+	}
+
+	byThread := make(map[uint]*types.ThreadPreference, len(prefs))
+	filed := false
+	for i := range prefs {
+		byThread[prefs[i].ThreadID] = &prefs[i]
+		filed = filed || prefs[i].FolderID != nil
+	}
+
+	var folders *folderTree
+	if filed {
+		var err error
+		if folders, err = loadFolderTree(s.db, userID); err != nil {
+			return err
+		}
+	}
+
+	for i := range responses {
+		preferences := &types.ThreadPreferences{}
+		if pref, ok := byThread[responses[i].ID]; ok {
+			preferences.Archived = pref.Archived
+			preferences.Pinned = pref.Pinned
+			preferences.Starred = pref.Starred
+			preferences.SortOrder = pref.SortOrder
+			preferences.FolderID = pref.FolderID
+			if pref.FolderID != nil && folders != nil {
+				responses[i].FolderPath = folders.path(*pref.FolderID)
 			}
 		}
+		responses[i].Preferences = preferences
+	}
+	return nil
+}
+
+// attachNoteStats fills in notes_count and last_note_at with one aggregate
+// query each instead of loading the notes. last_note_at is the time the
+// last_note sort orders by.
+func attachNoteStats(db *gorm.DB, responses []types.ThreadResponse) error {
+	if len(responses) == 0 {
+		return nil
+	}
+
+	threadIDs := make([]uint, len(responses))
+	for i := range responses {
+		threadIDs[i] = responses[i].ID
+	}
+
+	var counts []struct {
+		ThreadID   uint
+		NotesCount int
+	}
+	err := db.Model(&types.Note{}).
+		Select("thread_id, COUNT(*) AS notes_count").
+		Where("thread_id IN ?", threadIDs).
+		Group("thread_id").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	// The notes at each thread's MAX(created_at), so the time is read from
+	// the column rather than the aggregate
+	var latest []types.Note
+	err = db.Joins("JOIN (?) AS latest ON latest.thread_id = notes.thread_id AND latest.created_at = notes.created_at",
+		db.Model(&types.Note{}).
+			Select("thread_id, MAX(created_at) AS created_at").
+			Where("thread_id IN ?", threadIDs).
+			Group("thread_id")).
+		Find(&latest).Error
+	if err != nil {
+		return err
+	}
+
+	countByThread := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		countByThread[count.ThreadID] = count.NotesCount
+	}
+	latestByThread := make(map[uint]time.Time, len(latest))
+	for _, note := range latest {
+		latestByThread[note.ThreadID] = note.CreatedAt
+	}
+
+	for i := range responses {
+		responses[i].NotesCount = countByThread[responses[i].ID]
+		if createdAt, ok := latestByThread[responses[i].ID]; ok {
+			responses[i].LastNoteAt = &createdAt
+		}
+	}
+	return nil
+}
+
+// threadCursor is the cursor pointing after the given thread.
+func threadCursor(sort string, thread *types.ThreadResponse) string {
+	switch sort {
+	case "updated":
+		return encodeCursor(sort, thread.UpdatedAt, thread.ID)
+	case "created":
+		return encodeCursor(sort, thread.CreatedAt, thread.ID)
+	case "last_note":
+		lastNoteAt := thread.CreatedAt
+		if thread.LastNoteAt != nil {
+			lastNoteAt = *thread.LastNoteAt
+		}
+		return encodeCursor(sort, lastNoteAt, thread.ID)
+	case "title":
+		return encodeCursor(sort, thread.Title, thread.ID)
+	}
 
-		for _, collab := range thread.Collaborators {
-			response.Collaborators = append(response.Collaborators, types.UserResponse{
+	pinned, sortOrder := 0, math.MaxInt32
+	if thread.Preferences != nil {
+		if thread.Preferences.Pinned {
+			pinned = 1
+		}
+		if thread.Preferences.SortOrder != nil {
+			sortOrder = *thread.Preferences.SortOrder
+		}
+	}
+	return encodeCursor(sort, pinned, sortOrder, thread.UpdatedAt, thread.ID)
+}
+
+func (s *ThreadService) publicThreadCursor(sort string, thread *types.ThreadResponse) string {
+	if sort == "popular" {
+		var popularity int64
+		s.db.Model(&types.Thread{}).Select(popularityColumn).Where("threads.id = ?", thread.ID).Scan(&popularity)
+		return encodeCursor(sort, popularity, thread.ID)
+	}
+
+	lastNoteAt := thread.CreatedAt
+	if thread.LastNoteAt != nil {
+		lastNoteAt = *thread.LastNoteAt
+	}
+	return encodeCursor(sort, lastNoteAt, thread.ID)
+}
+
+func findOrCreatePreference(tx *gorm.DB, threadID, userID uint) (*types.ThreadPreference, error) {
+	pref := types.ThreadPreference{ThreadID: threadID, UserID: userID}
+	if err := tx.Where(&pref).FirstOrCreate(&pref).Error; err != nil {
+		return nil, err
+	}
+	return &pref, nil
+}
+
+// toThreadResponse builds the response for a thread loaded with its User and
+// Collaborators.User. role is the requesting user's role. Note statistics and
+// unread counts are filled in separately by attachNoteStats and attachUnread.
+func toThreadResponse(thread *types.Thread, role types.ThreadRole) types.ThreadResponse {
+	response := types.ThreadResponse{
+		ID:                  thread.ID,
+		Title:               thread.Title,
+		Description:         thread.Description,
+		IsPrivate:           thread.IsPrivate,
+		ReadReceiptsEnabled: thread.ReadReceiptsEnabled,
+		UserID:              thread.UserID,
+		WorkspaceID:         thread.WorkspaceID,
+		Role:                role,
+		Version:             thread.Version,
+		CreatedAt:           thread.CreatedAt,
+		UpdatedAt:           thread.UpdatedAt,
+	}
+
+	if thread.User.ID != 0 {
+		response.User = types.UserResponse{
+			ID:        thread.User.ID,
+			Email:     thread.User.Email,
+			Username:  thread.User.Username,
+			FirstName: thread.User.FirstName,
+			LastName:  thread.User.LastName,
+			AvatarURL: thread.User.AvatarURL,
+			CreatedAt: thread.User.CreatedAt,
+		}
+	}
+
+	for _, collab := range thread.Collaborators {
+		response.Collaborators = append(response.Collaborators, types.CollaboratorResponse{
+			UserResponse: types.UserResponse{
 				ID:        collab.User.ID,
 				Email:     collab.User.Email,
 				Username:  collab.User.Username,
 				FirstName: collab.User.FirstName,
 				LastName:  collab.User.LastName,
+				AvatarURL: collab.User.AvatarURL,
 				CreatedAt: collab.User.CreatedAt,
-			})
-		}
-
-		responses = append(responses, response)
+			},
+			Role:     collab.Role,
+			JoinedAt: collab.CreatedAt,
+		})
 	}
 
-	return responses, nil
+	return response
 }
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"markmywords-backend/internal/types"
+)
+
+// sameOrder reports whether got lists the IDs of want in the same order.
+func sameOrder(got, want []uint) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGetPublicThreadsPagesWithCursor(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	reader := createUser(t, db, "reader")
+	threads := NewThreadService()
+
+	public := false
+	var want []uint
+	for i, title := range []string{"One", "Two", "Three", "Four", "Five"} {
+		thread := createThread(t, owner.ID, 0, title)
+		if _, err := threads.UpdateThread(thread.ID, owner.ID, 0, &types.UpdateThreadRequest{IsPrivate: &public}); err != nil {
+			t.Fatalf("make thread public: %v", err)
+		}
+		// Notes make the popular order differ from the recent one
+		for j := 0; j < i%3; j++ {
+			createNote(t, thread.ID, owner.ID, nil)
+		}
+		want = append(want, thread.ID)
+	}
+	createThread(t, owner.ID, 0, "Private")
+
+	for _, sort := range []string{"recent", "popular"} {
+		t.Run(sort, func(t *testing.T) {
+			var got []uint
+			seen := make(map[uint]bool)
+			cursor := ""
+			for page := 0; page < 5; page++ {
+				listed, next, err := threads.GetPublicThreads(reader.ID, &types.PublicThreadsQuery{Sort: sort, Limit: 2, Cursor: cursor})
+				if err != nil {
+					t.Fatalf("get public threads: %v", err)
+				}
+				for _, thread := range listed {
+					if seen[thread.ID] {
+						t.Fatalf("thread %d listed twice", thread.ID)
+					}
+					seen[thread.ID] = true
+					got = append(got, thread.ID)
+				}
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			if !sameIDs(got, want) {
+				t.Fatalf("paged threads = %v, want %v", got, want)
+			}
+		})
+	}
+
+	// A cursor only works with the sort it was made for
+	_, next, err := threads.GetPublicThreads(reader.ID, &types.PublicThreadsQuery{Sort: "recent", Limit: 2})
+	if err != nil {
+		t.Fatalf("get public threads: %v", err)
+	}
+	if _, _, err := threads.GetPublicThreads(reader.ID, &types.PublicThreadsQuery{Sort: "popular", Cursor: next}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("cursor of another sort: err = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestThreadPreferencesFilterListings(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	threads := NewThreadService()
+
+	yes := true
+	archived := createThread(t, owner.ID, 0, "Archived")
+	pinned := createThread(t, owner.ID, 0, "Pinned")
+	starred := createThread(t, owner.ID, 0, "Starred")
+	plain := createThread(t, owner.ID, 0, "Plain")
+	for threadID, req := range map[uint]*types.UpdateThreadPreferencesRequest{
+		archived.ID: {Archived: &yes},
+		pinned.ID:   {Pinned: &yes},
+		starred.ID:  {Starred: &yes},
+	} {
+		if _, err := threads.UpdatePreferences(threadID, owner.ID, req); err != nil {
+			t.Fatalf("update preferences: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name  string
+		query types.ThreadListQuery
+		want  []uint
+	}{
+		{"default hides archived", types.ThreadListQuery{}, []uint{pinned.ID, starred.ID, plain.ID}},
+		{"archived only", types.ThreadListQuery{Archived: "true"}, []uint{archived.ID}},
+		{"archived too", types.ThreadListQuery{Archived: "all"}, []uint{archived.ID, pinned.ID, starred.ID, plain.ID}},
+		{"pinned", types.ThreadListQuery{Pinned: true}, []uint{pinned.ID}},
+		{"starred", types.ThreadListQuery{Starred: true}, []uint{starred.ID}},
+	}
+	for _, tc := range cases {
+		listed, _, err := threads.ListThreads(owner.ID, &tc.query)
+		if err != nil {
+			t.Fatalf("%s: list threads: %v", tc.name, err)
+		}
+		if got := threadIDs(listed); !sameIDs(got, tc.want) {
+			t.Errorf("%s: threads = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCustomOrderIsPerUser(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	viewer := createUser(t, db, "viewer")
+	threads := NewThreadService()
+
+	var all []uint
+	for _, title := range []string{"One", "Two", "Three", "Four"} {
+		thread := createThread(t, owner.ID, 0, title)
+		db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: viewer.ID, Role: types.ThreadRoleViewer})
+		all = append(all, thread.ID)
+	}
+
+	// Pinned threads come first, then the custom order, then the rest
+	yes := true
+	if _, err := threads.UpdatePreferences(all[3], owner.ID, &types.UpdateThreadPreferencesRequest{Pinned: &yes}); err != nil {
+		t.Fatalf("pin thread: %v", err)
+	}
+	if err := threads.ReorderThreads(owner.ID, []uint{all[2], all[0]}); err != nil {
+		t.Fatalf("reorder threads: %v", err)
+	}
+	if err := threads.ReorderThreads(owner.ID, []uint{all[0], all[0]}); err == nil {
+		t.Fatal("reordered with duplicate threads")
+	}
+
+	listed, _, err := threads.ListThreads(owner.ID, &types.ThreadListQuery{Sort: "custom"})
+	if err != nil {
+		t.Fatalf("list threads: %v", err)
+	}
+	if got, want := threadIDs(listed), []uint{all[3], all[2], all[0], all[1]}; !sameOrder(got, want) {
+		t.Fatalf("custom order = %v, want %v", got, want)
+	}
+
+	// The viewer's listing is untouched by the owner's preferences
+	listed, _, err = threads.ListThreads(viewer.ID, &types.ThreadListQuery{Scope: "shared", Pinned: true})
+	if err != nil {
+		t.Fatalf("list threads: %v", err)
+	}
+	if len(listed) != 0 {
+		t.Fatalf("viewer sees %v as pinned", threadIDs(listed))
+	}
+}
+
+func TestLastNoteAtFollowsCreationTime(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	threads := NewThreadService()
+
+	// The older thread gets its notes first, but the first one is dated last,
+	// as imported or clock-skewed notes can be
+	older := createThread(t, owner.ID, 0, "Older")
+	newer := createThread(t, owner.ID, 0, "Newer")
+	backdated := createNote(t, older.ID, owner.ID, nil)
+	createNote(t, older.ID, owner.ID, nil)
+	createNote(t, newer.ID, owner.ID, nil)
+	latest := time.Now().Add(time.Hour).Truncate(time.Second)
+	db.Model(&types.Note{}).Where("id = ?", backdated.ID).UpdateColumn("created_at", latest)
+
+	got, err := threads.GetThreadByID(older.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("get thread: %v", err)
+	}
+	if got.LastNoteAt == nil || !got.LastNoteAt.Equal(latest) {
+		t.Fatalf("last_note_at = %v, want %v", got.LastNoteAt, latest)
+	}
+	if got.NotesCount != 2 {
+		t.Fatalf("notes_count = %d, want 2", got.NotesCount)
+	}
+
+	// The cursor carries the same time the sort uses, so paging neither
+	// skips nor repeats
+	var paged []uint
+	cursor := ""
+	for page := 0; page < 3; page++ {
+		listed, next, err := threads.ListThreads(owner.ID, &types.ThreadListQuery{Sort: "last_note", Limit: 1, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("list threads: %v", err)
+		}
+		paged = append(paged, threadIDs(listed)...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if want := []uint{older.ID, newer.ID}; !sameOrder(paged, want) {
+		t.Fatalf("paged threads = %v, want %v", paged, want)
+	}
+}
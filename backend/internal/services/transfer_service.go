@@ -0,0 +1,296 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type TransferService struct {
+	db          *gorm.DB
+	permissions *PermissionChecker
+}
+
+func NewTransferService() *TransferService {
+	db := database.GetDB()
+	return &TransferService{
+		db:          db,
+		permissions: NewPermissionChecker(db),
+	}
+}
+
+// RequestTransfer offers the thread's ownership to one of its collaborators.
+func (s *TransferService) RequestTransfer(threadID, toUserID, userID uint) (*types.ThreadTransferResponse, error) {
+	thread, _, err := s.permissions.Authorize(threadID, userID, ActionManageThread)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.offer(thread, toUserID, userID)
+}
+
+// GetUserTransfers lists the pending transfers the user sent, received or
+// started on someone else's behalf.
+func (s *TransferService) GetUserTransfers(userID uint) ([]types.ThreadTransferResponse, error) {
+	var transfers []types.ThreadTransfer
+	err := s.db.Where("(to_user_id = ? OR from_user_id = ? OR initiated_by_id = ?) AND status = ?",
+		userID, userID, userID, types.TransferStatusPending).
+		Preload("Thread").
+		Preload("FromUser").
+		Preload("ToUser").
+		Preload("InitiatedBy").
+		Order("created_at DESC").
+		Find(&transfers).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []types.ThreadTransferResponse
+	for i := range transfers {
+		responses = append(responses, toTransferResponse(&transfers[i]))
+	}
+
+	return responses, nil
+}
+
+// AcceptTransfer makes the recipient the owner. The previous owner stays on
+// the thread as an admin.
+func (s *TransferService) AcceptTransfer(transferID, userID uint) (*types.ThreadTransferResponse, error) {
+	transfer, err := s.findPending(transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	if transfer.ToUserID != userID {
+		return nil, errAccessDenied
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var thread types.Thread
+		if err := tx.First(&thread, transfer.ThreadID).Error; err != nil {
+			return errThreadNotFound
+		}
+		if thread.UserID != transfer.FromUserID {
+			return errors.New("thread owner has changed since the transfer was offered")
+		}
+
+		err := tx.Model(transfer).Updates(map[string]interface{}{
+			"status":       types.TransferStatusAccepted,
+			"responded_at": time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return transferOwnership(tx, &thread, userID, transfer.InitiatedByID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transfer.Thread.UserID = userID
+	response := toTransferResponse(transfer)
+	return &response, nil
+}
+
+func (s *TransferService) DeclineTransfer(transferID, userID uint) error {
+	transfer, err := s.findPending(transferID)
+	if err != nil {
+		return err
+	}
+
+	if transfer.ToUserID != userID {
+		return errAccessDenied
+	}
+
+	return s.db.Model(transfer).Updates(map[string]interface{}{
+		"status":       types.TransferStatusDeclined,
+		"responded_at": time.Now(),
+	}).Error
+}
+
+// CancelTransfer withdraws an offer. Either the owner or whoever started the
+// transfer may cancel it.
+func (s *TransferService) CancelTransfer(transferID, userID uint) error {
+	transfer, err := s.findPending(transferID)
+	if err != nil {
+		return err
+	}
+
+	if transfer.FromUserID != userID && transfer.InitiatedByID != userID {
+		return errAccessDenied
+	}
+
+	return s.db.Model(transfer).Updates(map[string]interface{}{
+		"status":       types.TransferStatusCancelled,
+		"responded_at": time.Now(),
+	}).Error
+}
+
+// offer creates a pending transfer of the thread to a collaborator on behalf
+// of initiatedByID, who is either the owner or a site admin.
+func (s *TransferService) offer(thread *types.Thread, toUserID, initiatedByID uint) (*types.ThreadTransferResponse, error) {
+	if toUserID == thread.UserID {
+		return nil, errors.New("user already owns this thread")
+	}
+
+	if err := checkSuccessor(s.db, thread, toUserID); err != nil {
+		return nil, err
+	}
+
+	var pending int64
+	s.db.Model(&types.ThreadTransfer{}).
+		Where("thread_id = ? AND status = ?", thread.ID, types.TransferStatusPending).
+		Count(&pending)
+	if pending > 0 {
+		return nil, errors.New("thread already has a pending transfer")
+	}
+
+	transfer := types.ThreadTransfer{
+		ThreadID:      thread.ID,
+		FromUserID:    thread.UserID,
+		ToUserID:      toUserID,
+		InitiatedByID: initiatedByID,
+		Status:        types.TransferStatusPending,
+	}
+	if err := s.db.Create(&transfer).Error; err != nil {
+		return nil, err
+	}
+
+	created, err := s.findPending(transfer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := toTransferResponse(created)
+	return &response, nil
+}
+
+// transferOwnership makes the user the owner of the thread, on behalf of
+// actorID. The new owner's collaborator row, if any, gives way to ownership,
+// the previous owner stays on as an admin and the thread's other pending
+// offers are withdrawn.
+func transferOwnership(tx *gorm.DB, thread *types.Thread, toUserID, actorID uint) error {
+	if err := checkSuccessor(tx, thread, toUserID); err != nil {
+		return err
+	}
+	previousRole := NewPermissionChecker(tx).Role(thread, toUserID)
+	previousOwnerID := thread.UserID
+
+	if err := tx.Unscoped().Where("thread_id = ? AND user_id = ?", thread.ID, toUserID).Delete(&types.ThreadCollaborator{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(thread).Update("user_id", toUserID).Error; err != nil {
+		return err
+	}
+
+	previousOwner := types.ThreadCollaborator{
+		ThreadID: thread.ID,
+		UserID:   previousOwnerID,
+		Role:     types.ThreadRoleAdmin,
+	}
+	if err := tx.Create(&previousOwner).Error; err != nil {
+		return err
+	}
+
+	// Any offers still open were made by the old owner
+	err := tx.Model(&types.ThreadTransfer{}).
+		Where("thread_id = ? AND status = ?", thread.ID, types.TransferStatusPending).
+		Updates(map[string]interface{}{
+			"status":       types.TransferStatusCancelled,
+			"responded_at": time.Now(),
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	return recordActivity(tx, thread.ID, actorID, types.ActivityOwnershipTransferred, &toUserID, previousRole, types.ThreadRoleOwner)
+}
+
+// checkSuccessor reports why the user cannot take over the thread, if they
+// can't. Members through a group qualify as much as direct collaborators.
+func checkSuccessor(db *gorm.DB, thread *types.Thread, userID uint) error {
+	if userID == thread.UserID {
+		return errors.New("user already owns this thread")
+	}
+	if NewPermissionChecker(db).Role(thread, userID) == "" {
+		return errors.New("ownership can only be transferred to a collaborator")
+	}
+
+	var user types.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return errors.New("user not found")
+	}
+	if user.DeactivatedAt != nil {
+		return errors.New("cannot transfer ownership to a deactivated account")
+	}
+	return nil
+}
+
+// successor picks who inherits a thread from its departing owner: the longest
+// standing collaborator who can take it over, else a member through one of
+// its groups. It returns 0 when nobody qualifies.
+func successor(db *gorm.DB, thread *types.Thread) uint {
+	var candidates []uint
+	db.Model(&types.ThreadCollaborator{}).
+		Where("thread_id = ?", thread.ID).
+		Order("created_at ASC, id ASC").
+		Pluck("user_id", &candidates)
+	candidates = append(candidates, groupMemberIDs(db, thread.ID)...)
+
+	for _, userID := range candidates {
+		if checkSuccessor(db, thread, userID) == nil {
+			return userID
+		}
+	}
+	return 0
+}
+
+func (s *TransferService) findPending(transferID uint) (*types.ThreadTransfer, error) {
+	var transfer types.ThreadTransfer
+	err := s.db.Preload("Thread").
+		Preload("FromUser").
+		Preload("ToUser").
+		Preload("InitiatedBy").
+		First(&transfer, transferID).Error
+
+	if err != nil {
+		return nil, errors.New("transfer not found")
+	}
+
+	if transfer.Status != types.TransferStatusPending {
+		return nil, errors.New("transfer is not pending")
+	}
+
+	return &transfer, nil
+}
+
+func toTransferResponse(transfer *types.ThreadTransfer) types.ThreadTransferResponse {
+	response := types.ThreadTransferResponse{
+		ID:          transfer.ID,
+		ThreadID:    transfer.ThreadID,
+		Status:      transfer.Status,
+		RespondedAt: transfer.RespondedAt,
+		CreatedAt:   transfer.CreatedAt,
+	}
+
+	if transfer.Thread.ID != 0 {
+		response.Thread = toThreadResponse(&transfer.Thread, "")
+	}
+	if transfer.FromUser.ID != 0 {
+		response.FromUser = toUserResponse(&transfer.FromUser)
+	}
+	if transfer.ToUser.ID != 0 {
+		response.ToUser = toUserResponse(&transfer.ToUser)
+	}
+	if transfer.InitiatedBy.ID != 0 {
+		response.InitiatedBy = toUserResponse(&transfer.InitiatedBy)
+	}
+
+	return response
+}
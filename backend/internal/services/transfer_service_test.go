@@ -0,0 +1,247 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"markmywords-backend/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// deleteAccount deletes the user's account, handing their threads on.
+func deleteAccount(t *testing.T, db *gorm.DB, userID uint) {
+	t.Helper()
+
+	// Test users have no real password hash
+	db.Model(&types.User{}).Where("id = ?", userID).Update("password", "")
+
+	users := &UserService{db: db, sessions: NewSessionService(), exports: &ExportService{db: db}}
+	if err := users.DeleteAccount(userID, &types.DeleteAccountRequest{OwnedThreads: types.OwnedThreadsTransfer}); err != nil {
+		t.Fatalf("delete account: %v", err)
+	}
+}
+
+func threadOwner(t *testing.T, db *gorm.DB, threadID uint) uint {
+	t.Helper()
+
+	var thread types.Thread
+	if err := db.Unscoped().First(&thread, threadID).Error; err != nil {
+		t.Fatalf("load thread: %v", err)
+	}
+	if thread.DeletedAt.Valid {
+		return 0
+	}
+	return thread.UserID
+}
+
+func TestDeleteAccountSkipsDeactivatedSuccessors(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	gone := createUser(t, db, "gone")
+	editor := createUser(t, db, "editor")
+	viewer := createUser(t, db, "viewer")
+	thread := createThread(t, owner.ID, 0, "Plans")
+
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: gone.ID, Role: types.ThreadRoleAdmin})
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: editor.ID, Role: types.ThreadRoleEditor})
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: viewer.ID, Role: types.ThreadRoleViewer})
+	db.Model(gone).Update("deactivated_at", time.Now())
+
+	if _, err := NewTransferService().RequestTransfer(thread.ID, viewer.ID, owner.ID); err != nil {
+		t.Fatalf("request transfer: %v", err)
+	}
+
+	deleteAccount(t, db, owner.ID)
+
+	if got := threadOwner(t, db, thread.ID); got != editor.ID {
+		t.Fatalf("owner = %d, want the editor %d", got, editor.ID)
+	}
+	if count := countActivity(t, db, thread.ID, types.ActivityOwnershipTransferred); count != 1 {
+		t.Fatalf("recorded %d ownership_transferred activities, want 1", count)
+	}
+	var pending int64
+	db.Model(&types.ThreadTransfer{}).Where("thread_id = ? AND status = ?", thread.ID, types.TransferStatusPending).Count(&pending)
+	if pending != 0 {
+		t.Fatalf("%d transfers still pending after the handover", pending)
+	}
+
+	var rows int64
+	db.Model(&types.ThreadCollaborator{}).Where("thread_id = ? AND user_id IN ?", thread.ID, []uint{owner.ID, editor.ID}).Count(&rows)
+	if rows != 0 {
+		t.Fatalf("%d collaborator rows left for the old and new owner", rows)
+	}
+}
+
+func TestDeleteAccountHandsThreadToGroupMember(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	member := createUser(t, db, "member")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	grantThroughGroup(t, owner.ID, member.ID, thread.ID, types.ThreadRoleEditor)
+
+	deleteAccount(t, db, owner.ID)
+
+	if got := threadOwner(t, db, thread.ID); got != member.ID {
+		t.Fatalf("owner = %d, want the group member %d", got, member.ID)
+	}
+
+	var activity types.ThreadActivity
+	db.Where("thread_id = ? AND action = ?", thread.ID, types.ActivityOwnershipTransferred).First(&activity)
+	if activity.FromRole != types.ThreadRoleEditor || activity.TargetUserID == nil || *activity.TargetUserID != member.ID {
+		t.Fatalf("activity = %+v, want the member's move from editor to owner", activity)
+	}
+}
+
+func TestDeleteAccountTrashesThreadWithoutSuccessor(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	gone := createUser(t, db, "gone")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: gone.ID, Role: types.ThreadRoleEditor})
+	db.Model(gone).Update("deactivated_at", time.Now())
+
+	deleteAccount(t, db, owner.ID)
+
+	if got := threadOwner(t, db, thread.ID); got != 0 {
+		t.Fatalf("thread went to %d, want it trashed", got)
+	}
+}
+
+func TestAcceptTransferFromGroupMember(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	member := createUser(t, db, "member")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	grantThroughGroup(t, owner.ID, member.ID, thread.ID, types.ThreadRoleCommenter)
+
+	transfers := NewTransferService()
+	offered, err := transfers.RequestTransfer(thread.ID, member.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("request transfer: %v", err)
+	}
+	if _, err := transfers.AcceptTransfer(offered.ID, member.ID); err != nil {
+		t.Fatalf("accept transfer: %v", err)
+	}
+
+	if got := threadOwner(t, db, thread.ID); got != member.ID {
+		t.Fatalf("owner = %d, want %d", got, member.ID)
+	}
+	var previous types.ThreadCollaborator
+	if err := db.Where("thread_id = ? AND user_id = ?", thread.ID, owner.ID).First(&previous).Error; err != nil || previous.Role != types.ThreadRoleAdmin {
+		t.Fatalf("previous owner row = %+v (%v), want admin", previous, err)
+	}
+	if count := countActivity(t, db, thread.ID, types.ActivityOwnershipTransferred); count != 1 {
+		t.Fatalf("recorded %d ownership_transferred activities, want 1", count)
+	}
+}
+
+func TestRequestTransferChecks(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	editor := createUser(t, db, "editor")
+	viewer := createUser(t, db, "viewer")
+	stranger := createUser(t, db, "stranger")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: editor.ID, Role: types.ThreadRoleEditor})
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: viewer.ID, Role: types.ThreadRoleViewer})
+
+	transfers := NewTransferService()
+	cases := []struct {
+		name     string
+		toUserID uint
+		byUserID uint
+		ok       bool
+	}{
+		{"not the owner", viewer.ID, editor.ID, false},
+		{"to the owner", owner.ID, owner.ID, false},
+		{"to a stranger", stranger.ID, owner.ID, false},
+		{"to a collaborator", viewer.ID, owner.ID, true},
+		{"while one is pending", editor.ID, owner.ID, false},
+	}
+	for _, tc := range cases {
+		_, err := transfers.RequestTransfer(thread.ID, tc.toUserID, tc.byUserID)
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v, want ok %v", tc.name, err, tc.ok)
+		}
+	}
+}
+
+func TestRespondToTransfer(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	editor := createUser(t, db, "editor")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: editor.ID, Role: types.ThreadRoleEditor})
+	transfers := NewTransferService()
+
+	offer := func() uint {
+		t.Helper()
+		offered, err := transfers.RequestTransfer(thread.ID, editor.ID, owner.ID)
+		if err != nil {
+			t.Fatalf("request transfer: %v", err)
+		}
+		return offered.ID
+	}
+
+	declined := offer()
+	if err := transfers.DeclineTransfer(declined, owner.ID); err == nil {
+		t.Fatal("the owner declined on the recipient's behalf")
+	}
+	if err := transfers.DeclineTransfer(declined, editor.ID); err != nil {
+		t.Fatalf("decline transfer: %v", err)
+	}
+	if _, err := transfers.AcceptTransfer(declined, editor.ID); err == nil {
+		t.Fatal("accepted a declined transfer")
+	}
+
+	cancelled := offer()
+	if err := transfers.CancelTransfer(cancelled, editor.ID); err == nil {
+		t.Fatal("the recipient cancelled the offer")
+	}
+	if err := transfers.CancelTransfer(cancelled, owner.ID); err != nil {
+		t.Fatalf("cancel transfer: %v", err)
+	}
+	if _, err := transfers.AcceptTransfer(cancelled, editor.ID); err == nil {
+		t.Fatal("accepted a cancelled transfer")
+	}
+
+	accepted := offer()
+	if _, err := transfers.AcceptTransfer(accepted, owner.ID); err == nil {
+		t.Fatal("the owner accepted on the recipient's behalf")
+	}
+	if _, err := transfers.AcceptTransfer(accepted, editor.ID); err != nil {
+		t.Fatalf("accept transfer: %v", err)
+	}
+	if got := threadOwner(t, db, thread.ID); got != editor.ID {
+		t.Fatalf("owner = %d, want %d", got, editor.ID)
+	}
+}
+
+func TestAdminTransferThread(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	editor := createUser(t, db, "editor")
+	admin := createUser(t, db, "admin")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	db.Create(&types.ThreadCollaborator{ThreadID: thread.ID, UserID: editor.ID, Role: types.ThreadRoleEditor})
+	admins := NewAdminService()
+
+	if _, err := admins.TransferThread(thread.ID, editor.ID, admin.ID); err == nil {
+		t.Fatal("an admin transferred the thread of an active owner")
+	}
+
+	db.Model(owner).Update("deactivated_at", time.Now())
+	offered, err := admins.TransferThread(thread.ID, editor.ID, admin.ID)
+	if err != nil {
+		t.Fatalf("admin transfer: %v", err)
+	}
+
+	// The admin who started it may withdraw it, but only the recipient accepts
+	if _, err := NewTransferService().AcceptTransfer(offered.ID, admin.ID); err == nil {
+		t.Fatal("the admin accepted on the recipient's behalf")
+	}
+	if err := NewTransferService().CancelTransfer(offered.ID, admin.ID); err != nil {
+		t.Fatalf("cancel as the admin who started it: %v", err)
+	}
+}
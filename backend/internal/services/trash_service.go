@@ -0,0 +1,402 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/config"
+	"markmywords-backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// threadChildren are the soft deletable rows that go to the trash and come
+// back together with their thread.
+var threadChildren = []interface{}{
+	&types.Note{},
+	&types.ThreadCollaborator{},
+	&types.Invite{},
+}
+
+type TrashService struct {
+	db          *gorm.DB
+	permissions *PermissionChecker
+	threads     *ThreadService
+	notes       *NoteService
+	retention   time.Duration
+}
+
+func NewTrashService() *TrashService {
+	db := database.GetDB()
+	return &TrashService{
+		db:          db,
+		permissions: NewPermissionChecker(db),
+		threads:     NewThreadService(),
+		notes:       NewNoteService(),
+		retention:   time.Duration(config.GetInt("TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour,
+	}
+}
+
+// GetTrash lists what the user can restore in the workspace.
+func (s *TrashService) GetTrash(userID, workspaceID uint) (*types.TrashResponse, error) {
+	response := types.TrashResponse{
+		Threads: []types.TrashedThreadResponse{},
+		Notes:   []types.TrashedNoteResponse{},
+	}
+	retentions := s.workspaceRetentions()
+
+	var threads []types.Thread
+	err := inWorkspaceScope(s.db.Unscoped(), workspaceID).
+		Where("threads.user_id = ? AND threads.deleted_at IS NOT NULL", userID).
+		Preload("User").
+		Order("deleted_at DESC").
+		Find(&threads).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range threads {
+		thread := &threads[i]
+		trashed := types.TrashedThreadResponse{
+			ThreadResponse: toThreadResponse(thread, types.ThreadRoleOwner),
+			DeletedAt:      thread.DeletedAt.Time,
+			PurgeAt:        thread.DeletedAt.Time.Add(s.retentionOf(thread.WorkspaceID, retentions)),
+		}
+
+		var notesCount int64
+		s.db.Unscoped().Model(&types.Note{}).
+			Where("thread_id = ? AND deleted_at = ?", thread.ID, thread.DeletedAt.Time).
+			Count(&notesCount)
+		trashed.NotesCount = int(notesCount)
+
+		response.Threads = append(response.Threads, trashed)
+	}
+
+	// Notes the user wrote, or could have deleted as an admin of the thread
+	var notes []types.Note
+	err = inWorkspaceScope(s.db.Unscoped(), workspaceID).
+		Joins("JOIN threads ON threads.id = notes.thread_id AND threads.deleted_at IS NULL").
+		Where("notes.deleted_at IS NOT NULL").
+		// Replies deleted with the note they reply to are restored with it
+		Where("NOT EXISTS (SELECT 1 FROM notes parents WHERE parents.id = notes.parent_note_id AND parents.deleted_at = notes.deleted_at)").
+		Where("notes.user_id = ? OR threads.user_id = ? OR EXISTS (SELECT 1 FROM thread_collaborators WHERE thread_collaborators.thread_id = notes.thread_id AND thread_collaborators.user_id = ? AND thread_collaborators.role = ? AND thread_collaborators.deleted_at IS NULL) "+
+			"OR EXISTS (SELECT 1 FROM thread_group_grants JOIN user_group_members ON user_group_members.group_id = thread_group_grants.group_id WHERE thread_group_grants.thread_id = notes.thread_id AND user_group_members.user_id = ? AND thread_group_grants.role = ?)",
+			userID, userID, userID, types.ThreadRoleAdmin, userID, types.ThreadRoleAdmin).
+		Preload("User").
+		Preload("Thread").
+		Order("notes.deleted_at DESC").
+		Find(&notes).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range notes {
+		note := &notes[i]
+		trashed := types.TrashedNoteResponse{
+			NoteResponse: types.NoteResponse{
+				ID:           note.ID,
+				Content:      note.Content,
+				ThreadID:     note.ThreadID,
+				UserID:       note.UserID,
+				ParentNoteID: note.ParentNoteID,
+				Version:      note.Version,
+				CreatedAt:    note.CreatedAt,
+				UpdatedAt:    note.UpdatedAt,
+			},
+			ThreadTitle: note.Thread.Title,
+			DeletedAt:   note.DeletedAt.Time,
+			PurgeAt:     note.DeletedAt.Time.Add(s.retentionOf(note.Thread.WorkspaceID, retentions)),
+		}
+		if note.User.ID != 0 {
+			trashed.User = toUserResponse(&note.User)
+		}
+
+		response.Notes = append(response.Notes, trashed)
+	}
+
+	return &response, nil
+}
+
+// RestoreThread brings back a deleted thread and everything that was deleted
+// with it. Notes deleted on their own before the thread stay in the trash.
+func (s *TrashService) RestoreThread(threadID, userID uint) (*types.ThreadResponse, error) {
+	var thread types.Thread
+	if err := s.db.Unscoped().First(&thread, threadID).Error; err != nil {
+		return nil, errThreadNotFound
+	}
+
+	if thread.UserID != userID {
+		return nil, errAccessDenied
+	}
+	if thread.WorkspaceID != nil && workspaceRole(s.db, *thread.WorkspaceID, userID) == "" {
+		return nil, errNotWorkspaceMember
+	}
+	if !thread.DeletedAt.Valid {
+		return nil, errors.New("thread is not in the trash")
+	}
+
+	deletedAt := thread.DeletedAt.Time
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range threadChildren {
+			err := tx.Unscoped().Model(model).
+				Where("thread_id = ? AND deleted_at = ?", thread.ID, deletedAt).
+				UpdateColumn("deleted_at", nil).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Model(&thread).UpdateColumn("deleted_at", nil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.threads.GetThreadByID(thread.ID, userID)
+}
+
+// RestoreNote brings back a note deleted on its own, with the replies deleted
+// along with it. The same people who may delete a note may restore it.
+func (s *TrashService) RestoreNote(noteID, userID uint) (*types.NoteResponse, error) {
+	var note types.Note
+	if err := s.db.Unscoped().First(&note, noteID).Error; err != nil {
+		return nil, errors.New("note not found")
+	}
+
+	if !note.DeletedAt.Valid {
+		return nil, errors.New("note is not in the trash")
+	}
+
+	action := ActionModerate
+	if note.UserID == userID {
+		action = ActionPostNote
+	}
+	if _, _, err := s.permissions.Authorize(note.ThreadID, userID, action); err != nil {
+		return nil, err
+	}
+
+	// A reply cannot come back under a note that is still in the trash
+	if note.ParentNoteID != nil {
+		var parent int64
+		s.db.Model(&types.Note{}).Where("id = ?", *note.ParentNoteID).Count(&parent)
+		if parent == 0 {
+			return nil, errors.New("restore the note this replies to first")
+		}
+	}
+
+	// Replies deleted together with the note come back with it
+	deletedAt := note.DeletedAt.Time
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		ids, err := noteTree(tx, note.ID, &deletedAt)
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&types.Note{}).Where("id IN ?", ids).UpdateColumn("deleted_at", nil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.notes.GetNoteByID(note.ID, userID)
+}
+
+// RunPurge permanently deletes whatever has been in the trash longer than the
+// retention period, every interval.
+func (s *TrashService) RunPurge(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.purgeAll(time.Now()); err != nil {
+			log.Printf("Trash purge failed: %v", err)
+		}
+	}
+}
+
+// purgeAll purges the trash of workspaces with their own retention period
+// by it, and everything else by the server's.
+func (s *TrashService) purgeAll(now time.Time) error {
+	retentions := s.workspaceRetentions()
+
+	custom := make([]uint, 0, len(retentions))
+	for workspaceID, retention := range retentions {
+		custom = append(custom, workspaceID)
+		workspaceID := workspaceID
+		err := s.purge(now.Add(-retention), func(db *gorm.DB) *gorm.DB {
+			return db.Where("workspace_id = ?", workspaceID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.purge(now.Add(-s.retention), func(db *gorm.DB) *gorm.DB {
+		if len(custom) == 0 {
+			return db
+		}
+		return db.Where("(workspace_id IS NULL OR workspace_id NOT IN ?)", custom)
+	})
+}
+
+// purge deletes what was trashed before the cutoff from the threads inScope
+// selects.
+func (s *TrashService) purge(cutoff time.Time, inScope func(*gorm.DB) *gorm.DB) error {
+	var threadIDs []uint
+	err := inScope(s.db.Unscoped().Model(&types.Thread{})).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Pluck("id", &threadIDs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, threadID := range threadIDs {
+		if err := s.purgeThread(threadID); err != nil {
+			return err
+		}
+	}
+
+	// Rows deleted on their own, outside of a deleted thread
+	threads := inScope(s.db.Unscoped().Model(&types.Thread{}).Select("id"))
+	notes := s.db.Unscoped().Model(&types.Note{}).Select("id").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Where("thread_id IN (?)", threads)
+	if err := s.db.Where("note_id IN (?)", notes).Delete(&types.NoteRevision{}).Error; err != nil {
+		return err
+	}
+	if err := s.db.Where("note_id IN (?)", notes).Delete(&types.NoteReaction{}).Error; err != nil {
+		return err
+	}
+	// Replies to purged notes become top-level notes
+	err = s.db.Unscoped().Model(&types.Note{}).
+		Where("parent_note_id IN (?)", notes).
+		UpdateColumn("parent_note_id", nil).Error
+	if err != nil {
+		return err
+	}
+	for _, model := range threadChildren {
+		err := s.db.Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Where("thread_id IN (?)", threads).
+			Delete(model).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(threadIDs) > 0 {
+		log.Printf("Purged %d threads from the trash", len(threadIDs))
+	}
+	return nil
+}
+
+// workspaceRetentions are the retention periods of workspaces that set
+// their own.
+func (s *TrashService) workspaceRetentions() map[uint]time.Duration {
+	var workspaces []types.Workspace
+	s.db.Where("trash_retention_days > 0").Find(&workspaces)
+
+	retentions := make(map[uint]time.Duration, len(workspaces))
+	for _, workspace := range workspaces {
+		retentions[workspace.ID] = time.Duration(workspace.TrashRetentionDays) * 24 * time.Hour
+	}
+	return retentions
+}
+
+// retentionOf is how long a thread of the workspace stays in the trash.
+func (s *TrashService) retentionOf(workspaceID *uint, retentions map[uint]time.Duration) time.Duration {
+	if workspaceID != nil {
+		if retention, ok := retentions[*workspaceID]; ok {
+			return retention
+		}
+	}
+	return s.retention
+}
+
+// purgeThread hard deletes a thread with everything that refers to it.
+func (s *TrashService) purgeThread(threadID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		notes := tx.Unscoped().Model(&types.Note{}).Select("id").Where("thread_id = ?", threadID)
+		if err := tx.Where("note_id IN (?)", notes).Delete(&types.NoteRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("note_id IN (?)", notes).Delete(&types.NoteReaction{}).Error; err != nil {
+			return err
+		}
+
+		related := append([]interface{}{
+			&types.ShareLink{},
+			&types.ThreadTransfer{},
+			&types.ThreadActivity{},
+			&types.ThreadPreference{},
+			&types.ThreadReadMarker{},
+			&types.ThreadLabel{},
+			&types.ThreadGroupGrant{},
+		}, threadChildren...)
+
+		for _, model := range related {
+			if err := tx.Unscoped().Where("thread_id = ?", threadID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&types.Thread{}, threadID).Error
+	})
+}
+
+// trashThread soft deletes a thread and its children with one shared
+// timestamp, so a restore brings back exactly what went away with it.
+// Pending ownership transfers are cancelled.
+func trashThread(tx *gorm.DB, thread *types.Thread) error {
+	now := time.Now()
+
+	for _, model := range threadChildren {
+		if err := tx.Model(model).Where("thread_id = ?", thread.ID).UpdateColumn("deleted_at", now).Error; err != nil {
+			return err
+		}
+	}
+
+	err := tx.Model(&types.ThreadTransfer{}).
+		Where("thread_id = ? AND status = ?", thread.ID, types.TransferStatusPending).
+		Updates(map[string]interface{}{
+			"status":       types.TransferStatusCancelled,
+			"responded_at": now,
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(thread).UpdateColumn("deleted_at", now).Error
+}
+
+// trashNote deletes the note together with its replies at any depth, at the
+// same moment so that they are restored together.
+func trashNote(tx *gorm.DB, note *types.Note) error {
+	ids, err := noteTree(tx, note.ID, nil)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&types.Note{}).Where("id IN ?", ids).UpdateColumn("deleted_at", time.Now()).Error
+}
+
+// noteTree returns the note and its replies at any depth: the live ones, or
+// with deletedAt set, those deleted at that moment.
+func noteTree(tx *gorm.DB, noteID uint, deletedAt *time.Time) ([]uint, error) {
+	ids := []uint{noteID}
+	parents := ids
+	for len(parents) > 0 {
+		query := tx.Model(&types.Note{}).Where("parent_note_id IN ?", parents)
+		if deletedAt != nil {
+			query = tx.Unscoped().Model(&types.Note{}).Where("parent_note_id IN ? AND deleted_at = ?", parents, *deletedAt)
+		}
+
+		var replies []uint
+		if err := query.Pluck("id", &replies).Error; err != nil {
+			return nil, err
+		}
+		ids = append(ids, replies...)
+		parents = replies
+	}
+	return ids, nil
+}
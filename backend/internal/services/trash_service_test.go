@@ -0,0 +1,172 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"markmywords-backend/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// deletedAt returns when the row was deleted, or the zero time if it is live.
+func deletedAt(t *testing.T, db *gorm.DB, model interface{}, id uint) time.Time {
+	t.Helper()
+
+	var deleted gorm.DeletedAt
+	if err := db.Unscoped().Model(model).Where("id = ?", id).Pluck("deleted_at", &deleted).Error; err != nil {
+		t.Fatalf("load deleted_at: %v", err)
+	}
+	return deleted.Time
+}
+
+func TestDeleteAndRestoreThread(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	editor := createUser(t, db, "editor")
+	invitee := createUser(t, db, "invitee")
+	thread := createThread(t, owner.ID, 0, "Plans")
+
+	collaborator := types.ThreadCollaborator{ThreadID: thread.ID, UserID: editor.ID, Role: types.ThreadRoleEditor}
+	db.Create(&collaborator)
+	invite := types.Invite{ThreadID: thread.ID, FromUserID: owner.ID, ToUserID: invitee.ID, Role: types.ThreadRoleViewer}
+	db.Create(&invite)
+	kept := createNote(t, thread.ID, editor.ID, nil)
+	deletedBefore := createNote(t, thread.ID, editor.ID, nil)
+
+	notes := NewNoteService()
+	if err := notes.DeleteNote(deletedBefore.ID, editor.ID); err != nil {
+		t.Fatalf("delete note: %v", err)
+	}
+	noteDeletedAt := deletedAt(t, db, &types.Note{}, deletedBefore.ID)
+	time.Sleep(10 * time.Millisecond)
+
+	if err := NewThreadService().DeleteThread(thread.ID, owner.ID); err != nil {
+		t.Fatalf("delete thread: %v", err)
+	}
+
+	// Everything goes to the trash at the thread's moment, apart from the
+	// note that was already there
+	threadDeletedAt := deletedAt(t, db, &types.Thread{}, thread.ID)
+	if threadDeletedAt.IsZero() {
+		t.Fatal("the thread was not deleted")
+	}
+	cascade := []struct {
+		name  string
+		model interface{}
+		id    uint
+		want  time.Time
+	}{
+		{"note", &types.Note{}, kept.ID, threadDeletedAt},
+		{"collaborator", &types.ThreadCollaborator{}, collaborator.ID, threadDeletedAt},
+		{"invite", &types.Invite{}, invite.ID, threadDeletedAt},
+		{"note deleted before", &types.Note{}, deletedBefore.ID, noteDeletedAt},
+	}
+	for _, tc := range cascade {
+		if got := deletedAt(t, db, tc.model, tc.id); !got.Equal(tc.want) {
+			t.Errorf("%s deleted at %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	trash := NewTrashService()
+	trashed, err := trash.GetTrash(owner.ID, 0)
+	if err != nil {
+		t.Fatalf("get trash: %v", err)
+	}
+	if len(trashed.Threads) != 1 || trashed.Threads[0].NotesCount != 1 {
+		t.Fatalf("trashed threads = %+v, want the thread with its 1 note", trashed.Threads)
+	}
+
+	if _, err := trash.RestoreThread(thread.ID, editor.ID); err == nil {
+		t.Fatal("a collaborator restored the owner's thread")
+	}
+	if _, err := trash.RestoreThread(thread.ID, owner.ID); err != nil {
+		t.Fatalf("restore thread: %v", err)
+	}
+
+	restored := []struct {
+		name  string
+		model interface{}
+		id    uint
+		live  bool
+	}{
+		{"thread", &types.Thread{}, thread.ID, true},
+		{"note", &types.Note{}, kept.ID, true},
+		{"collaborator", &types.ThreadCollaborator{}, collaborator.ID, true},
+		{"invite", &types.Invite{}, invite.ID, true},
+		{"note deleted before", &types.Note{}, deletedBefore.ID, false},
+	}
+	for _, tc := range restored {
+		if live := deletedAt(t, db, tc.model, tc.id).IsZero(); live != tc.live {
+			t.Errorf("%s live after the restore = %v, want %v", tc.name, live, tc.live)
+		}
+	}
+	if _, _, err := NewPermissionChecker(db).Authorize(thread.ID, editor.ID, ActionPostNote); err != nil {
+		t.Fatalf("the editor lost access after the restore: %v", err)
+	}
+}
+
+func TestPurgeRespectsRetention(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	workspace, err := NewWorkspaceService().CreateWorkspace(owner.ID, &types.CreateWorkspaceRequest{Name: "Team", TrashRetentionDays: 7})
+	if err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+
+	day := 24 * time.Hour
+	cases := []struct {
+		name        string
+		workspaceID uint
+		age         time.Duration
+		purged      bool
+	}{
+		{"personal, past the server retention", 0, 31 * day, true},
+		{"personal, within the server retention", 0, 10 * day, false},
+		{"workspace, past its retention", workspace.ID, 8 * day, true},
+		{"workspace, within its retention", workspace.ID, 3 * day, false},
+	}
+
+	threads := NewThreadService()
+	threadIDs := make([]uint, len(cases))
+	for i, tc := range cases {
+		thread := createThread(t, owner.ID, tc.workspaceID, tc.name)
+		createNote(t, thread.ID, owner.ID, nil)
+		if err := threads.DeleteThread(thread.ID, owner.ID); err != nil {
+			t.Fatalf("delete thread: %v", err)
+		}
+		when := time.Now().Add(-tc.age)
+		db.Unscoped().Model(&types.Thread{}).Where("id = ?", thread.ID).UpdateColumn("deleted_at", when)
+		db.Unscoped().Model(&types.Note{}).Where("thread_id = ?", thread.ID).UpdateColumn("deleted_at", when)
+		threadIDs[i] = thread.ID
+	}
+
+	// Notes deleted on their own from a live thread follow the same rules
+	live := createThread(t, owner.ID, 0, "Live")
+	oldNote := createNote(t, live.ID, owner.ID, nil)
+	recentNote := createNote(t, live.ID, owner.ID, nil)
+	db.Model(&types.Note{}).Where("id = ?", oldNote.ID).UpdateColumn("deleted_at", time.Now().Add(-31*day))
+	db.Model(&types.Note{}).Where("id = ?", recentNote.ID).UpdateColumn("deleted_at", time.Now().Add(-day))
+
+	if err := NewTrashService().purgeAll(time.Now()); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+
+	for i, tc := range cases {
+		var threads, notes int64
+		db.Unscoped().Model(&types.Thread{}).Where("id = ?", threadIDs[i]).Count(&threads)
+		db.Unscoped().Model(&types.Note{}).Where("thread_id = ?", threadIDs[i]).Count(&notes)
+		if purged := threads == 0 && notes == 0; purged != tc.purged {
+			t.Errorf("%s: purged = %v, want %v", tc.name, purged, tc.purged)
+		}
+	}
+	var count int64
+	db.Unscoped().Model(&types.Note{}).Where("id = ?", oldNote.ID).Count(&count)
+	if count != 0 {
+		t.Error("a note deleted past the retention was kept")
+	}
+	db.Unscoped().Model(&types.Note{}).Where("id = ?", recentNote.ID).Count(&count)
+	if count != 1 {
+		t.Error("a note deleted within the retention was purged")
+	}
+}
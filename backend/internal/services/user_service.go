@@ -1,22 +1,65 @@
 package services
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
 
 	"markmywords-backend/internal/types"
 	"markmywords-backend/pkg/auth"
+	"markmywords-backend/pkg/config"
 	"markmywords-backend/pkg/database"
+	"markmywords-backend/pkg/imaging"
+	"markmywords-backend/pkg/mailer"
+	"markmywords-backend/pkg/storage"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// emailChangeTTL is how long an email verification link stays valid.
+const emailChangeTTL = 24 * time.Hour
+
+// avatarSizes are the square sizes, in pixels, every avatar is stored in.
+// avatar_url points at the largest; the others sit next to it.
+var avatarSizes = []int{64, 128, 256}
+
+var errAccountDeactivated = errors.New("account is deactivated")
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
 type UserService struct {
-	db *gorm.DB
+	db             *gorm.DB
+	authenticators []Authenticator
+	sessions       *SessionService
+	mailer         mailer.Mailer
+	store          storage.BlobStore
+	exports        *ExportService
 }
 
 func NewUserService() *UserService {
+	db := database.GetDB()
+
+	// Local accounts are checked first, then any configured directory
+	authenticators := []Authenticator{NewPasswordAuthenticator(db)}
+	if ldapAuth := NewLDAPAuthenticator(db); ldapAuth != nil {
+		authenticators = append(authenticators, ldapAuth)
+	}
+
 	return &UserService{
-		db: database.GetDB(),
+		db:             db,
+		authenticators: authenticators,
+		sessions:       NewSessionService(),
+		mailer:         mailer.New(),
+		store:          storage.Default(),
+		exports:        NewExportService(),
 	}
 }
 
@@ -52,22 +95,34 @@ func (s *UserService) Register(req *types.RegisterRequest) (*types.UserResponse,
 		Username:  user.Username,
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
+		AvatarURL: user.AvatarURL,
 		CreatedAt: user.CreatedAt,
 	}, nil
 }
 
-func (s *UserService) Login(req *types.LoginRequest) (*types.UserResponse, string, error) {
-	var user types.User
-	if err := s.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		return nil, "", errors.New("invalid credentials")
+func (s *UserService) Login(req *types.LoginRequest, client types.ClientInfo) (*types.UserResponse, string, error) {
+	var user *types.User
+	for _, authenticator := range s.authenticators {
+		authenticated, err := authenticator.Authenticate(req.Email, req.Password)
+		if errors.Is(err, errUnknownUser) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		user = authenticated
+		break
 	}
 
-	if !auth.CheckPassword(req.Password, user.Password) {
+	if user == nil {
 		return nil, "", errors.New("invalid credentials")
 	}
+	if user.DeactivatedAt != nil {
+		return nil, "", errAccountDeactivated
+	}
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(user.ID, user.Email)
+	// Start a session and generate its JWT token
+	token, err := s.sessions.CreateSession(user.ID, user.Email, client)
 	if err != nil {
 		return nil, "", err
 	}
@@ -78,6 +133,7 @@ func (s *UserService) Login(req *types.LoginRequest) (*types.UserResponse, strin
 		Username:  user.Username,
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
+		AvatarURL: user.AvatarURL,
 		CreatedAt: user.CreatedAt,
 	}, token, nil
 }
@@ -94,16 +150,22 @@ func (s *UserService) GetUserByID(userID uint) (*types.UserResponse, error) {
 		Username:  user.Username,
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
+		AvatarURL: user.AvatarURL,
 		CreatedAt: user.CreatedAt,
 	}, nil
 }
 
-func (s *UserService) SearchUsers(query string, currentUserID uint) ([]types.UserResponse, error) {
+// SearchUsers finds users to share with. In a workspace only its members are
+// found.
+func (s *UserService) SearchUsers(query string, currentUserID, workspaceID uint) ([]types.UserResponse, error) {
+	db := s.db.Where("(username LIKE ? OR email LIKE ? OR first_name LIKE ? OR last_name LIKE ?) AND id != ?",
+		"%"+query+"%", "%"+query+"%", "%"+query+"%", "%"+query+"%", currentUserID)
+	if workspaceID != 0 {
+		db = db.Where("id IN (?)", s.db.Model(&types.WorkspaceMember{}).Select("user_id").Where("workspace_id = ?", workspaceID))
+	}
+
 	var users []types.User
-	err := s.db.Where("(username LIKE ? OR email LIKE ? OR first_name LIKE ? OR last_name LIKE ?) AND id != ?",
-		"%"+query+"%", "%"+query+"%", "%"+query+"%", "%"+query+"%", currentUserID).
-		Limit(10).
-		Find(&users).Error
+	err := db.Limit(10).Find(&users).Error
 
 	if err != nil {
 		return nil, err
@@ -117,9 +179,382 @@ func (s *UserService) SearchUsers(query string, currentUserID uint) ([]types.Use
 			Username:  user.Username,
 			FirstName: user.FirstName,
 			LastName:  user.LastName,
+			AvatarURL: user.AvatarURL,
 			CreatedAt: user.CreatedAt,
 		})
 	}
 
 	return responses, nil
 }
+
+func (s *UserService) UpdateProfile(userID uint, req *types.UpdateProfileRequest) (*types.UserResponse, error) {
+	var user types.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	if user.AuthSource == types.AuthSourceLDAP && (req.FirstName != nil || req.LastName != nil) {
+		return nil, errors.New("name is managed by the directory")
+	}
+
+	if req.Username != nil && *req.Username != user.Username {
+		var count int64
+		s.db.Model(&types.User{}).Unscoped().Where("username = ? AND id != ?", *req.Username, userID).Count(&count)
+		if count > 0 {
+			return nil, errors.New("username is already taken")
+		}
+		user.Username = *req.Username
+	}
+	if req.FirstName != nil {
+		user.FirstName = *req.FirstName
+	}
+	if req.LastName != nil {
+		user.LastName = *req.LastName
+	}
+
+	if err := s.db.Save(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetUserByID(userID)
+}
+
+// UpdateAvatar resizes the uploaded image and replaces the user's avatar.
+func (s *UserService) UpdateAvatar(userID uint, data []byte) (*types.UserResponse, error) {
+	var user types.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	renditions, err := imaging.SquareRenditions(data, avatarSizes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every upload gets fresh keys so the media can be cached forever
+	ctx := context.Background()
+	prefix := fmt.Sprintf("avatars/%d/%s", userID, uuid.NewString())
+	var key string
+	for _, rendition := range renditions {
+		key = fmt.Sprintf("%s/%d%s", prefix, rendition.Size, rendition.Extension)
+		err := s.store.Put(ctx, key, bytes.NewReader(rendition.Data), int64(len(rendition.Data)), rendition.ContentType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	oldKey := user.AvatarKey
+	err = s.db.Model(&user).Updates(map[string]interface{}{
+		"avatar_key": key,
+		"avatar_url": storage.PublicURL(key),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	s.deleteAvatarFiles(oldKey)
+
+	return s.GetUserByID(userID)
+}
+
+func (s *UserService) DeleteAvatar(userID uint) error {
+	var user types.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	avatarKey := user.AvatarKey
+	if avatarKey == "" {
+		return errors.New("no avatar to delete")
+	}
+
+	err := s.db.Model(&user).Updates(map[string]interface{}{
+		"avatar_key": "",
+		"avatar_url": "",
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	s.deleteAvatarFiles(avatarKey)
+	return nil
+}
+
+// deleteAvatarFiles removes every size of the avatar stored at key. Failures
+// are only logged; the user record no longer points at the files.
+func (s *UserService) deleteAvatarFiles(key string) {
+	if key == "" {
+		return
+	}
+
+	dir, ext := path.Dir(key), path.Ext(key)
+	for _, size := range avatarSizes {
+		if err := s.store.Delete(context.Background(), fmt.Sprintf("%s/%d%s", dir, size, ext)); err != nil {
+			log.Printf("Failed to delete avatar %s: %v", key, err)
+		}
+	}
+}
+
+// ChangePassword replaces the password and signs out every other session.
+func (s *UserService) ChangePassword(userID, currentSessionID uint, req *types.ChangePasswordRequest) error {
+	var user types.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	if user.AuthSource == types.AuthSourceLDAP {
+		return errors.New("password is managed by the directory")
+	}
+
+	if !auth.CheckPassword(req.CurrentPassword, user.Password) {
+		return errors.New("current password is incorrect")
+	}
+
+	hashedPassword, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+		return s.sessions.RevokeOtherSessions(tx, userID, currentSessionID)
+	})
+}
+
+// RequestEmailChange emails a verification link to the new address. The
+// email is only changed once the link is confirmed.
+func (s *UserService) RequestEmailChange(userID uint, req *types.ChangeEmailRequest) error {
+	var user types.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	if user.AuthSource == types.AuthSourceLDAP {
+		return errors.New("email is managed by the directory")
+	}
+
+	// Accounts created through single sign-on may not have a password
+	if user.Password != "" && !auth.CheckPassword(req.Password, user.Password) {
+		return errors.New("password is incorrect")
+	}
+
+	if strings.EqualFold(req.NewEmail, user.Email) {
+		return errors.New("new email is the same as the current email")
+	}
+
+	var count int64
+	s.db.Model(&types.User{}).Where("email = ?", req.NewEmail).Count(&count)
+	if count > 0 {
+		return errors.New("email is already in use")
+	}
+
+	token, hash, err := auth.GenerateSecret()
+	if err != nil {
+		return err
+	}
+
+	change := types.EmailChange{
+		UserID:    userID,
+		NewEmail:  req.NewEmail,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(emailChangeTTL),
+	}
+
+	if err := s.db.Create(&change).Error; err != nil {
+		return err
+	}
+
+	link := config.Get("APP_BASE_URL", "http://localhost:3000") + "/confirm-email?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Hi %s,\n\nConfirm your new MarkMyWords email address by opening this link:\n\n%s\n\nThe link expires in 24 hours. If you did not request this change, you can ignore this email.\n",
+		user.FirstName, link)
+
+	return s.mailer.Send(req.NewEmail, "Confirm your new email address", body)
+}
+
+func (s *UserService) ConfirmEmailChange(req *types.ConfirmEmailRequest) (*types.UserResponse, error) {
+	var change types.EmailChange
+	if err := s.db.Where("token_hash = ?", auth.HashToken(req.Token)).First(&change).Error; err != nil {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	if change.ConfirmedAt != nil || time.Now().After(change.ExpiresAt) {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		tx.Model(&types.User{}).Where("email = ? AND id != ?", change.NewEmail, change.UserID).Count(&count)
+		if count > 0 {
+			return errors.New("email is already in use")
+		}
+
+		if err := tx.Model(&types.User{}).Where("id = ?", change.UserID).Update("email", change.NewEmail).Error; err != nil {
+			return err
+		}
+		return tx.Model(&change).Update("confirmed_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetUserByID(change.UserID)
+}
+
+// DeleteAccount removes the user. Owned threads are transferred or deleted
+// as requested, memberships and pending invites are dropped, and the account
+// is scrubbed so authored notes remain but are no longer attributed.
+func (s *UserService) DeleteAccount(userID uint, req *types.DeleteAccountRequest) error {
+	var user types.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	if user.AuthSource != types.AuthSourceLDAP && user.Password != "" && !auth.CheckPassword(req.Password, user.Password) {
+		return errors.New("password is incorrect")
+	}
+
+	avatarKey := user.AvatarKey
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var threads []types.Thread
+		if err := tx.Where("user_id = ?", userID).Find(&threads).Error; err != nil {
+			return err
+		}
+
+		for i := range threads {
+			thread := &threads[i]
+
+			if req.OwnedThreads == types.OwnedThreadsTransfer {
+				// The admin row this leaves behind goes with the other memberships below
+				if successorID := successor(tx, thread); successorID != 0 {
+					if err := transferOwnership(tx, thread, successorID, userID); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+
+			if err := trashThread(tx, thread); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&types.ThreadCollaborator{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("(from_user_id = ? OR to_user_id = ?) AND status = ?", userID, userID, types.InviteStatusPending).
+			Delete(&types.Invite{}).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&types.ThreadTransfer{}).
+			Where("(from_user_id = ? OR to_user_id = ?) AND status = ?", userID, userID, types.TransferStatusPending).
+			Updates(map[string]interface{}{
+				"status":       types.TransferStatusCancelled,
+				"responded_at": time.Now(),
+			}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&types.ThreadPreference{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&types.ThreadReadMarker{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("label_id IN (?)", tx.Model(&types.Label{}).Select("id").Where("user_id = ?", userID)).
+			Delete(&types.ThreadLabel{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&types.Label{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&types.Folder{}).Error; err != nil {
+			return err
+		}
+		var groupIDs []uint
+		if err := tx.Model(&types.UserGroupMember{}).Where("user_id = ?", userID).Pluck("group_id", &groupIDs).Error; err != nil {
+			return err
+		}
+		if err := leaveGroups(tx, userID, groupIDs); err != nil {
+			return err
+		}
+		if err := leaveWorkspaces(tx, userID); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&types.PersonalAccessToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&types.ExternalIdentity{}).Error; err != nil {
+			return err
+		}
+		if err := s.sessions.RevokeOtherSessions(tx, userID, 0); err != nil {
+			return err
+		}
+
+		// Free the unique email and username and drop personal data
+		err = tx.Model(&user).Updates(map[string]interface{}{
+			"email":       fmt.Sprintf("deleted-%d@deleted.invalid", userID),
+			"username":    fmt.Sprintf("deleted-%d", userID),
+			"first_name":  "Deleted",
+			"last_name":   "User",
+			"password":    "",
+			"external_dn": "",
+			"avatar_key":  "",
+			"avatar_url":  "",
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&user).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.deleteAvatarFiles(avatarKey)
+	return s.exports.DeleteUserExports(userID)
+}
+
+func toUserResponse(user *types.User) types.UserResponse {
+	return types.UserResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		AvatarURL: user.AvatarURL,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+// uniqueUsername derives an unused username from a preferred name, falling
+// back to the local part of the email address.
+func uniqueUsername(tx *gorm.DB, preferred, email string) (string, error) {
+	base := preferred
+	if base == "" || strings.Contains(base, "@") {
+		base = strings.SplitN(email, "@", 2)[0]
+	}
+	base = usernameInvalidChars.ReplaceAllString(strings.ToLower(base), "")
+	if len(base) > 24 {
+		base = base[:24]
+	}
+	for len(base) < 3 {
+		base += "_"
+	}
+
+	candidate := base
+	for i := 1; i < 100; i++ {
+		var count int64
+		if err := tx.Model(&types.User{}).Unscoped().Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+
+	return "", errors.New("could not allocate a username")
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrVersionMismatch is returned for an update made against a version of a
+// thread or note that is no longer the current one.
+var ErrVersionMismatch = errors.New("it has been changed since you last read it")
+
+// updateVersioned applies the updates only while the row is still at the
+// expected version, moving it on to the next one. Whoever saves first wins;
+// the other update fails instead of silently overwriting it.
+func updateVersioned(tx *gorm.DB, model interface{}, id, version uint, updates map[string]interface{}) error {
+	updates["version"] = version + 1
+
+	result := tx.Model(model).Where("id = ? AND version = ?", id, version).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionMismatch
+	}
+	return nil
+}
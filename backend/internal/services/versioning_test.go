@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"markmywords-backend/internal/types"
+)
+
+func TestUpdateThreadChecksVersion(t *testing.T) {
+	cases := []struct {
+		name        string
+		version     uint
+		wantErr     error
+		wantVersion uint
+	}{
+		{"current version", 2, nil, 3},
+		{"any version", 0, nil, 3},
+		{"stale version", 1, ErrVersionMismatch, 2},
+		{"future version", 5, ErrVersionMismatch, 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestDB(t)
+			owner := createUser(t, db, "owner")
+			thread := createThread(t, owner.ID, 0, "Plans")
+
+			threads := NewThreadService()
+			if _, err := threads.UpdateThread(thread.ID, owner.ID, thread.Version, &types.UpdateThreadRequest{Title: "Draft"}); err != nil {
+				t.Fatalf("first update: %v", err)
+			}
+
+			_, err := threads.UpdateThread(thread.ID, owner.ID, tc.version, &types.UpdateThreadRequest{Title: "Final"})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+
+			got, err := threads.GetThreadByID(thread.ID, owner.ID)
+			if err != nil {
+				t.Fatalf("get thread: %v", err)
+			}
+			wantTitle := "Final"
+			if tc.wantErr != nil {
+				wantTitle = "Draft"
+			}
+			if got.Version != tc.wantVersion || got.Title != wantTitle {
+				t.Fatalf("thread at version %d titled %q, want %d titled %q", got.Version, got.Title, tc.wantVersion, wantTitle)
+			}
+		})
+	}
+}
+
+func TestUpdateNoteChecksVersion(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	thread := createThread(t, owner.ID, 0, "Plans")
+	note := createNote(t, thread.ID, owner.ID, nil)
+
+	notes := NewNoteService()
+	updated, err := notes.UpdateNote(note.ID, owner.ID, note.Version, &types.UpdateNoteRequest{Content: "first"})
+	if err != nil {
+		t.Fatalf("update note: %v", err)
+	}
+	if updated.Version != note.Version+1 {
+		t.Fatalf("version = %d, want %d", updated.Version, note.Version+1)
+	}
+
+	// A second editor still holding the original version loses
+	if _, err := notes.UpdateNote(note.ID, owner.ID, note.Version, &types.UpdateNoteRequest{Content: "second"}); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("stale update: err = %v, want %v", err, ErrVersionMismatch)
+	}
+
+	got, err := notes.GetNoteByID(note.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("get note: %v", err)
+	}
+	if got.Content != "first" || got.Version != updated.Version {
+		t.Fatalf("note = %q at version %d, want the first update", got.Content, got.Version)
+	}
+
+	revisions, err := notes.GetRevisions(note.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("get revisions: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("%d revisions, want the rejected update left out", len(revisions))
+	}
+}
+
+func TestUpdateVersionedFirstSaveWins(t *testing.T) {
+	db := newTestDB(t)
+	owner := createUser(t, db, "owner")
+	thread := createThread(t, owner.ID, 0, "Plans")
+
+	// Both saves read version 1; only the first applies
+	first := updateVersioned(db, &types.Thread{}, thread.ID, thread.Version, map[string]interface{}{"title": "Mine"})
+	second := updateVersioned(db, &types.Thread{}, thread.ID, thread.Version, map[string]interface{}{"title": "Theirs"})
+	if first != nil || !errors.Is(second, ErrVersionMismatch) {
+		t.Fatalf("saves returned %v and %v, want the second to mismatch", first, second)
+	}
+
+	var saved types.Thread
+	if err := db.First(&saved, thread.ID).Error; err != nil {
+		t.Fatalf("load thread: %v", err)
+	}
+	if saved.Title != "Mine" || saved.Version != thread.Version+1 {
+		t.Fatalf("thread = %q at version %d, want the first save", saved.Title, saved.Version)
+	}
+}
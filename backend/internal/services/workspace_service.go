@@ -0,0 +1,476 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	errWorkspaceNotFound  = errors.New("workspace not found")
+	errNotWorkspaceMember = errors.New("user is not a member of this thread's workspace")
+)
+
+type WorkspaceService struct {
+	db *gorm.DB
+}
+
+func NewWorkspaceService() *WorkspaceService {
+	return &WorkspaceService{
+		db: database.GetDB(),
+	}
+}
+
+// IsMember reports whether the user belongs to the workspace.
+func (s *WorkspaceService) IsMember(workspaceID, userID uint) bool {
+	return workspaceRole(s.db, workspaceID, userID) != ""
+}
+
+// GetUserWorkspaces lists the workspaces the user belongs to, by name.
+func (s *WorkspaceService) GetUserWorkspaces(userID uint) ([]types.WorkspaceResponse, error) {
+	var workspaces []types.Workspace
+	err := s.db.Joins("JOIN workspace_members ON workspace_members.workspace_id = workspaces.id AND workspace_members.user_id = ?", userID).
+		Preload("Members").
+		Order("workspaces.name").
+		Find(&workspaces).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []types.WorkspaceResponse{}
+	for i := range workspaces {
+		responses = append(responses, toWorkspaceResponse(&workspaces[i], userID))
+	}
+	return responses, nil
+}
+
+// CreateWorkspace creates a workspace owned by the user.
+func (s *WorkspaceService) CreateWorkspace(userID uint, req *types.CreateWorkspaceRequest) (*types.WorkspaceResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	// Threads are private unless the workspace says otherwise
+	defaultPrivate := true
+	if req.DefaultPrivate != nil {
+		defaultPrivate = *req.DefaultPrivate
+	}
+
+	workspace := types.Workspace{
+		Name:               name,
+		OwnerID:            userID,
+		DefaultPrivate:     defaultPrivate,
+		TrashRetentionDays: req.TrashRetentionDays,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&workspace).Error; err != nil {
+			return err
+		}
+		return tx.Create(&types.WorkspaceMember{
+			WorkspaceID: workspace.ID,
+			UserID:      userID,
+			Role:        types.WorkspaceRoleOwner,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetWorkspace(workspace.ID, userID)
+}
+
+func (s *WorkspaceService) GetWorkspace(workspaceID, userID uint) (*types.WorkspaceResponse, error) {
+	workspace, _, err := s.authorize(workspaceID, userID, types.WorkspaceRoleMember)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Where("workspace_id = ?", workspace.ID).Find(&workspace.Members).Error; err != nil {
+		return nil, err
+	}
+
+	response := toWorkspaceResponse(workspace, userID)
+	return &response, nil
+}
+
+// UpdateWorkspace changes the workspace's name and defaults. Admins and the
+// owner may change them.
+func (s *WorkspaceService) UpdateWorkspace(workspaceID, userID uint, req *types.UpdateWorkspaceRequest) (*types.WorkspaceResponse, error) {
+	workspace, _, err := s.authorize(workspaceID, userID, types.WorkspaceRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		workspace.Name = name
+	}
+	if req.DefaultPrivate != nil {
+		workspace.DefaultPrivate = *req.DefaultPrivate
+	}
+	if req.TrashRetentionDays != nil {
+		workspace.TrashRetentionDays = *req.TrashRetentionDays
+	}
+
+	if err := s.db.Save(workspace).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetWorkspace(workspace.ID, userID)
+}
+
+// DeleteWorkspace deletes an empty workspace. Threads in its trash are
+// purged with it.
+func (s *WorkspaceService) DeleteWorkspace(workspaceID, userID uint) error {
+	workspace, _, err := s.authorize(workspaceID, userID, types.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	var liveThreads int64
+	s.db.Model(&types.Thread{}).Where("workspace_id = ?", workspace.ID).Count(&liveThreads)
+	if liveThreads > 0 {
+		return errors.New("delete the workspace's threads first")
+	}
+
+	var trashed []uint
+	if err := s.db.Unscoped().Model(&types.Thread{}).Where("workspace_id = ?", workspace.ID).Pluck("id", &trashed).Error; err != nil {
+		return err
+	}
+	trash := NewTrashService()
+	for _, threadID := range trashed {
+		if err := trash.purgeThread(threadID); err != nil {
+			return err
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return deleteWorkspace(tx, workspace)
+	})
+}
+
+// GetMembers lists the workspace's members, the longest standing first.
+func (s *WorkspaceService) GetMembers(workspaceID, userID uint) ([]types.WorkspaceMemberResponse, error) {
+	if _, _, err := s.authorize(workspaceID, userID, types.WorkspaceRoleMember); err != nil {
+		return nil, err
+	}
+
+	var members []types.WorkspaceMember
+	err := s.db.Where("workspace_id = ?", workspaceID).
+		Preload("User").
+		Order("created_at ASC").
+		Find(&members).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []types.WorkspaceMemberResponse{}
+	for i := range members {
+		responses = append(responses, toWorkspaceMemberResponse(&members[i]))
+	}
+	return responses, nil
+}
+
+// AddMember adds a user to the workspace. Admins add members, and only the
+// owner adds admins.
+func (s *WorkspaceService) AddMember(workspaceID, userID uint, req *types.AddWorkspaceMemberRequest) (*types.WorkspaceMemberResponse, error) {
+	_, actorRole, err := s.authorize(workspaceID, userID, types.WorkspaceRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = types.WorkspaceRoleMember
+	}
+	if role == types.WorkspaceRoleAdmin && actorRole != types.WorkspaceRoleOwner {
+		return nil, errors.New("only the owner can add admins")
+	}
+
+	var user types.User
+	if err := s.db.First(&user, req.UserID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.DeactivatedAt != nil {
+		return nil, errors.New("user is deactivated")
+	}
+	if workspaceRole(s.db, workspaceID, user.ID) != "" {
+		return nil, errors.New("user is already a member of this workspace")
+	}
+
+	member := types.WorkspaceMember{
+		WorkspaceID: workspaceID,
+		UserID:      user.ID,
+		Role:        role,
+	}
+	if err := s.db.Create(&member).Error; err != nil {
+		return nil, err
+	}
+	member.User = user
+
+	response := toWorkspaceMemberResponse(&member)
+	return &response, nil
+}
+
+// UpdateMemberRole makes a member an admin or back. Only the owner manages
+// admins, and the owner's own role cannot change.
+func (s *WorkspaceService) UpdateMemberRole(workspaceID, targetUserID, userID uint, role types.WorkspaceRole) (*types.WorkspaceMemberResponse, error) {
+	_, actorRole, err := s.authorize(workspaceID, userID, types.WorkspaceRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.findMember(workspaceID, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == types.WorkspaceRoleOwner {
+		return nil, errors.New("the owner's role cannot be changed")
+	}
+	if (member.Role == types.WorkspaceRoleAdmin || role == types.WorkspaceRoleAdmin) && actorRole != types.WorkspaceRoleOwner {
+		return nil, errors.New("only the owner can manage admins")
+	}
+
+	if err := s.db.Model(member).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+
+	response := toWorkspaceMemberResponse(member)
+	return &response, nil
+}
+
+// RemoveMember takes a user out of the workspace, or lets a member leave.
+// They lose their place on every thread and group of the workspace; threads
+// they own must be transferred first. Returns the threads they were removed from.
+func (s *WorkspaceService) RemoveMember(workspaceID, targetUserID, userID uint) ([]uint, error) {
+	required := types.WorkspaceRoleAdmin
+	if targetUserID == userID {
+		required = types.WorkspaceRoleMember
+	}
+	_, actorRole, err := s.authorize(workspaceID, userID, required)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.findMember(workspaceID, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == types.WorkspaceRoleOwner {
+		return nil, errors.New("the owner cannot leave or be removed from the workspace")
+	}
+	if targetUserID != userID && member.Role == types.WorkspaceRoleAdmin && actorRole != types.WorkspaceRoleOwner {
+		return nil, errors.New("only the owner can remove admins")
+	}
+
+	// Threads in the trash count too, or they could be restored later
+	var owned int64
+	if err := s.db.Unscoped().Model(&types.Thread{}).Where("workspace_id = ? AND user_id = ?", workspaceID, targetUserID).Count(&owned).Error; err != nil {
+		return nil, err
+	}
+	if owned > 0 {
+		return nil, errors.New("the member still owns threads in this workspace; transfer them first")
+	}
+
+	workspaceThreads := s.db.Unscoped().Model(&types.Thread{}).Select("id").Where("workspace_id = ?", workspaceID)
+
+	var threadIDs []uint
+	err = s.db.Model(&types.ThreadCollaborator{}).
+		Where("user_id = ? AND thread_id IN (?)", targetUserID, workspaceThreads).
+		Pluck("thread_id", &threadIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var groupIDs []uint
+	err = s.db.Model(&types.UserGroupMember{}).
+		Where("user_id = ? AND group_id IN (?)", targetUserID, s.db.Model(&types.UserGroup{}).Select("id").Where("workspace_id = ?", workspaceID)).
+		Pluck("group_id", &groupIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	var groupThreadIDs []uint
+	if len(groupIDs) > 0 {
+		if err := s.db.Model(&types.ThreadGroupGrant{}).Where("group_id IN ?", groupIDs).Pluck("thread_id", &groupThreadIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := leaveGroups(tx, targetUserID, groupIDs); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ? AND thread_id IN (?)", targetUserID, workspaceThreads).
+			Delete(&types.ThreadCollaborator{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("to_user_id = ? AND status = ? AND thread_id IN (?)", targetUserID, types.InviteStatusPending, workspaceThreads).
+			Delete(&types.Invite{}).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&types.ThreadTransfer{}).
+			Where("to_user_id = ? AND status = ? AND thread_id IN (?)", targetUserID, types.TransferStatusPending, workspaceThreads).
+			Updates(map[string]interface{}{
+				"status":       types.TransferStatusCancelled,
+				"responded_at": time.Now(),
+			}).Error
+		if err != nil {
+			return err
+		}
+		for _, threadID := range threadIDs {
+			if err := recordActivity(tx, threadID, userID, types.ActivityCollaboratorRemoved, &targetUserID, "", ""); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(member).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make(map[uint]bool, len(threadIDs))
+	for _, threadID := range threadIDs {
+		removed[threadID] = true
+	}
+	for _, threadID := range groupThreadIDs {
+		if !removed[threadID] {
+			removed[threadID] = true
+			threadIDs = append(threadIDs, threadID)
+		}
+	}
+	return threadIDs, nil
+}
+
+// authorize loads the workspace and checks the user's role on it. Outsiders
+// are told the workspace does not exist.
+func (s *WorkspaceService) authorize(workspaceID, userID uint, min types.WorkspaceRole) (*types.Workspace, types.WorkspaceRole, error) {
+	role := workspaceRole(s.db, workspaceID, userID)
+	if role == "" {
+		return nil, "", errWorkspaceNotFound
+	}
+	if !role.AtLeast(min) {
+		return nil, role, errAccessDenied
+	}
+
+	var workspace types.Workspace
+	if err := s.db.First(&workspace, workspaceID).Error; err != nil {
+		return nil, "", errWorkspaceNotFound
+	}
+	return &workspace, role, nil
+}
+
+func (s *WorkspaceService) findMember(workspaceID, userID uint) (*types.WorkspaceMember, error) {
+	var member types.WorkspaceMember
+	if err := s.db.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).Preload("User").First(&member).Error; err != nil {
+		return nil, errors.New("member not found")
+	}
+	return &member, nil
+}
+
+// workspaceRole returns the user's role in the workspace, or "" when they do
+// not belong to it.
+func workspaceRole(db *gorm.DB, workspaceID, userID uint) types.WorkspaceRole {
+	var member types.WorkspaceMember
+	if err := db.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&member).Error; err != nil {
+		return ""
+	}
+	return member.Role
+}
+
+// inWorkspaceScope limits a thread query to one workspace, or to personal
+// threads when workspaceID is zero.
+func inWorkspaceScope(db *gorm.DB, workspaceID uint) *gorm.DB {
+	if workspaceID == 0 {
+		return db.Where("threads.workspace_id IS NULL")
+	}
+	return db.Where("threads.workspace_id = ?", workspaceID)
+}
+
+// leaveWorkspaces takes a deleted account out of its workspaces. Workspaces
+// it owned pass to their longest standing admin, or member, and are deleted
+// when nobody else is left.
+func leaveWorkspaces(tx *gorm.DB, userID uint) error {
+	var owned []types.Workspace
+	if err := tx.Where("owner_id = ?", userID).Find(&owned).Error; err != nil {
+		return err
+	}
+
+	for i := range owned {
+		workspace := &owned[i]
+
+		var successor types.WorkspaceMember
+		err := tx.Where("workspace_id = ? AND user_id <> ?", workspace.ID, userID).
+			Order("CASE WHEN role = 'admin' THEN 0 ELSE 1 END, created_at ASC").
+			First(&successor).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := deleteWorkspace(tx, workspace); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&successor).Update("role", types.WorkspaceRoleOwner).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(workspace).Update("owner_id", successor.UserID).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Where("user_id = ?", userID).Delete(&types.WorkspaceMember{}).Error
+}
+
+// deleteWorkspace deletes the workspace with its members and groups.
+func deleteWorkspace(tx *gorm.DB, workspace *types.Workspace) error {
+	var groupIDs []uint
+	if err := tx.Model(&types.UserGroup{}).Where("workspace_id = ?", workspace.ID).Pluck("id", &groupIDs).Error; err != nil {
+		return err
+	}
+	if err := deleteGroups(tx, groupIDs); err != nil {
+		return err
+	}
+	if err := tx.Where("workspace_id = ?", workspace.ID).Delete(&types.WorkspaceMember{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(workspace).Error
+}
+
+func toWorkspaceResponse(workspace *types.Workspace, userID uint) types.WorkspaceResponse {
+	response := types.WorkspaceResponse{
+		ID:                 workspace.ID,
+		Name:               workspace.Name,
+		OwnerID:            workspace.OwnerID,
+		DefaultPrivate:     workspace.DefaultPrivate,
+		TrashRetentionDays: workspace.TrashRetentionDays,
+		MembersCount:       len(workspace.Members),
+		CreatedAt:          workspace.CreatedAt,
+		UpdatedAt:          workspace.UpdatedAt,
+	}
+
+	for _, member := range workspace.Members {
+		if member.UserID == userID {
+			response.Role = member.Role
+		}
+	}
+
+	return response
+}
+
+func toWorkspaceMemberResponse(member *types.WorkspaceMember) types.WorkspaceMemberResponse {
+	return types.WorkspaceMemberResponse{
+		UserResponse: toUserResponse(&member.User),
+		Role:         member.Role,
+		JoinedAt:     member.CreatedAt,
+	}
+}
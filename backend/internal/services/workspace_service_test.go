@@ -0,0 +1,331 @@
+package services
+
+import (
+	"testing"
+
+	"markmywords-backend/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// workspaceFixture is two workspaces. Alice belongs to both, Bob only to
+// Beta and Carol only to Alpha.
+type workspaceFixture struct {
+	db                 *gorm.DB
+	alice, bob, carol  *types.User
+	alpha, beta        uint
+	alphaThread        *types.ThreadResponse
+	betaThread         *types.ThreadResponse
+	alphaThreadTrashed *types.ThreadResponse
+	betaThreadTrashed  *types.ThreadResponse
+}
+
+func newWorkspaceFixture(t *testing.T) *workspaceFixture {
+	t.Helper()
+
+	f := &workspaceFixture{db: newTestDB(t)}
+	f.alice = createUser(t, f.db, "alice")
+	f.bob = createUser(t, f.db, "bob")
+	f.carol = createUser(t, f.db, "carol")
+
+	workspaces := NewWorkspaceService()
+	alpha, err := workspaces.CreateWorkspace(f.alice.ID, &types.CreateWorkspaceRequest{Name: "Alpha"})
+	if err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+	beta, err := workspaces.CreateWorkspace(f.bob.ID, &types.CreateWorkspaceRequest{Name: "Beta"})
+	if err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+	f.alpha, f.beta = alpha.ID, beta.ID
+
+	if _, err := workspaces.AddMember(f.alpha, f.alice.ID, &types.AddWorkspaceMemberRequest{UserID: f.carol.ID}); err != nil {
+		t.Fatalf("add carol to alpha: %v", err)
+	}
+	if _, err := workspaces.AddMember(f.beta, f.bob.ID, &types.AddWorkspaceMemberRequest{UserID: f.alice.ID}); err != nil {
+		t.Fatalf("add alice to beta: %v", err)
+	}
+
+	f.alphaThread = createThread(t, f.alice.ID, f.alpha, "Alpha plans")
+	f.betaThread = createThread(t, f.bob.ID, f.beta, "Beta plans")
+	f.alphaThreadTrashed = createThread(t, f.alice.ID, f.alpha, "Old alpha plans")
+	f.betaThreadTrashed = createThread(t, f.alice.ID, f.beta, "Old beta plans")
+
+	// Carol comments in Alpha, Alice edits in Beta
+	f.db.Create(&types.ThreadCollaborator{ThreadID: f.alphaThread.ID, UserID: f.carol.ID, Role: types.ThreadRoleCommenter})
+	f.db.Create(&types.ThreadCollaborator{ThreadID: f.betaThread.ID, UserID: f.alice.ID, Role: types.ThreadRoleEditor})
+	return f
+}
+
+func (f *workspaceFixture) postNote(t *testing.T, threadID, userID uint) {
+	t.Helper()
+
+	if _, err := NewNoteService().CreateNote(&types.CreateNoteRequest{ThreadID: threadID, Content: "news"}, userID); err != nil {
+		t.Fatalf("create note: %v", err)
+	}
+}
+
+func sameIDs(got, want []uint) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := make(map[uint]bool, len(got))
+	for _, id := range got {
+		seen[id] = true
+	}
+	for _, id := range want {
+		if !seen[id] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestListThreadsStaysInWorkspace(t *testing.T) {
+	f := newWorkspaceFixture(t)
+	threads := NewThreadService()
+
+	cases := []struct {
+		name        string
+		workspaceID uint
+		want        []uint
+	}{
+		{"alpha", f.alpha, []uint{f.alphaThread.ID, f.alphaThreadTrashed.ID}},
+		{"beta", f.beta, []uint{f.betaThread.ID, f.betaThreadTrashed.ID}},
+		{"personal", 0, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			listed, _, err := threads.ListThreads(f.alice.ID, &types.ThreadListQuery{Scope: "all", WorkspaceID: tc.workspaceID})
+			if err != nil {
+				t.Fatalf("list threads: %v", err)
+			}
+			if got := threadIDs(listed); !sameIDs(got, tc.want) {
+				t.Fatalf("threads = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetThreadByIDRefusesOutsiders(t *testing.T) {
+	f := newWorkspaceFixture(t)
+	threads := NewThreadService()
+
+	// Visibility does not open a workspace thread to non-members
+	public := false
+	if _, err := threads.UpdateThread(f.betaThread.ID, f.bob.ID, 0, &types.UpdateThreadRequest{IsPrivate: &public}); err != nil {
+		t.Fatalf("make thread public: %v", err)
+	}
+
+	if _, err := threads.GetThreadByID(f.betaThread.ID, f.carol.ID); err == nil {
+		t.Fatal("carol read a thread of a workspace she is not in")
+	}
+	if _, err := threads.GetThreadByID(f.betaThread.ID, f.alice.ID); err != nil {
+		t.Fatalf("alice cannot read a thread of her own workspace: %v", err)
+	}
+}
+
+func TestSearchUsersStaysInWorkspace(t *testing.T) {
+	f := newWorkspaceFixture(t)
+	users := NewUserService()
+
+	cases := []struct {
+		name        string
+		workspaceID uint
+		want        []uint
+	}{
+		{"alpha", f.alpha, []uint{f.carol.ID}},
+		{"beta", f.beta, []uint{f.bob.ID}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			found, err := users.SearchUsers("example.com", f.alice.ID, tc.workspaceID)
+			if err != nil {
+				t.Fatalf("search users: %v", err)
+			}
+			var got []uint
+			for _, user := range found {
+				got = append(got, user.ID)
+			}
+			if !sameIDs(got, tc.want) {
+				t.Fatalf("users = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUnreadSummaryStaysInWorkspace(t *testing.T) {
+	f := newWorkspaceFixture(t)
+	f.postNote(t, f.alphaThread.ID, f.carol.ID)
+	f.postNote(t, f.betaThread.ID, f.bob.ID)
+	f.postNote(t, f.betaThread.ID, f.bob.ID)
+
+	reads := NewReadService()
+	cases := []struct {
+		name        string
+		workspaceID uint
+		thread      uint
+		unread      int
+	}{
+		{"alpha", f.alpha, f.alphaThread.ID, 1},
+		{"beta", f.beta, f.betaThread.ID, 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			summary, err := reads.GetUnreadSummary(f.alice.ID, tc.workspaceID)
+			if err != nil {
+				t.Fatalf("unread summary: %v", err)
+			}
+			if len(summary.Threads) != 1 || summary.Threads[0].ThreadID != tc.thread {
+				t.Fatalf("unread threads = %+v, want only thread %d", summary.Threads, tc.thread)
+			}
+			if summary.TotalUnread != tc.unread {
+				t.Fatalf("total unread = %d, want %d", summary.TotalUnread, tc.unread)
+			}
+		})
+	}
+
+	summary, err := reads.GetUnreadSummary(f.alice.ID, 0)
+	if err != nil {
+		t.Fatalf("unread summary: %v", err)
+	}
+	if summary.TotalUnread != 0 {
+		t.Fatalf("personal unread = %d, want 0", summary.TotalUnread)
+	}
+}
+
+func TestTrashStaysInWorkspace(t *testing.T) {
+	f := newWorkspaceFixture(t)
+	threads := NewThreadService()
+	if err := threads.DeleteThread(f.alphaThreadTrashed.ID, f.alice.ID); err != nil {
+		t.Fatalf("delete thread: %v", err)
+	}
+	if err := threads.DeleteThread(f.betaThreadTrashed.ID, f.alice.ID); err != nil {
+		t.Fatalf("delete thread: %v", err)
+	}
+
+	trash := NewTrashService()
+	cases := []struct {
+		name        string
+		workspaceID uint
+		want        []uint
+	}{
+		{"alpha", f.alpha, []uint{f.alphaThreadTrashed.ID}},
+		{"beta", f.beta, []uint{f.betaThreadTrashed.ID}},
+		{"personal", 0, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			trashed, err := trash.GetTrash(f.alice.ID, tc.workspaceID)
+			if err != nil {
+				t.Fatalf("get trash: %v", err)
+			}
+			var got []uint
+			for _, thread := range trashed.Threads {
+				got = append(got, thread.ID)
+			}
+			if !sameIDs(got, tc.want) {
+				t.Fatalf("trashed threads = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTrashedThreadsStayInWorkspace(t *testing.T) {
+	f := newWorkspaceFixture(t)
+	thread := createThread(t, f.carol.ID, f.alpha, "Carol's plans")
+	if err := NewThreadService().DeleteThread(thread.ID, f.carol.ID); err != nil {
+		t.Fatalf("delete thread: %v", err)
+	}
+
+	// A thread in the trash still has to be handed over before its owner goes
+	if _, err := NewWorkspaceService().RemoveMember(f.alpha, f.carol.ID, f.alice.ID); err == nil {
+		t.Fatal("removed a member who owns a trashed thread")
+	}
+
+	// Should they leave anyway, the thread cannot come back through them
+	f.db.Where("workspace_id = ? AND user_id = ?", f.alpha, f.carol.ID).Delete(&types.WorkspaceMember{})
+	if _, err := NewTrashService().RestoreThread(thread.ID, f.carol.ID); err != errNotWorkspaceMember {
+		t.Fatalf("restore by a former member: err = %v, want errNotWorkspaceMember", err)
+	}
+}
+
+func TestLabelAndFolderCountsStayInWorkspace(t *testing.T) {
+	f := newWorkspaceFixture(t)
+
+	labels := NewLabelService()
+	label, err := labels.CreateLabel(f.alice.ID, &types.CreateLabelRequest{Name: "urgent"})
+	if err != nil {
+		t.Fatalf("create label: %v", err)
+	}
+	folders := NewFolderService()
+	folder, err := folders.CreateFolder(f.alice.ID, &types.CreateFolderRequest{Name: "Projects"})
+	if err != nil {
+		t.Fatalf("create folder: %v", err)
+	}
+
+	for _, threadID := range []uint{f.alphaThread.ID, f.betaThread.ID, f.betaThreadTrashed.ID} {
+		if _, err := labels.AddThreadLabel(threadID, label.ID, f.alice.ID); err != nil {
+			t.Fatalf("label thread %d: %v", threadID, err)
+		}
+		if _, err := folders.MoveThread(threadID, f.alice.ID, &folder.ID); err != nil {
+			t.Fatalf("file thread %d: %v", threadID, err)
+		}
+	}
+
+	cases := []struct {
+		name        string
+		workspaceID uint
+		want        int
+	}{
+		{"alpha", f.alpha, 1},
+		{"beta", f.beta, 2},
+		{"personal", 0, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			listedLabels, err := labels.GetLabels(f.alice.ID, tc.workspaceID)
+			if err != nil {
+				t.Fatalf("get labels: %v", err)
+			}
+			if len(listedLabels) != 1 || listedLabels[0].ThreadCount != tc.want {
+				t.Fatalf("labels = %+v, want one label on %d threads", listedLabels, tc.want)
+			}
+
+			listedFolders, err := folders.GetFolders(f.alice.ID, tc.workspaceID)
+			if err != nil {
+				t.Fatalf("get folders: %v", err)
+			}
+			if len(listedFolders) != 1 || listedFolders[0].ThreadCount != tc.want {
+				t.Fatalf("folders = %+v, want one folder with %d threads", listedFolders, tc.want)
+			}
+		})
+	}
+}
+
+func TestJoinByLinkRequiresWorkspaceMembership(t *testing.T) {
+	f := newWorkspaceFixture(t)
+	dave := createUser(t, f.db, "dave")
+	if _, err := NewWorkspaceService().AddMember(f.beta, f.bob.ID, &types.AddWorkspaceMemberRequest{UserID: dave.ID}); err != nil {
+		t.Fatalf("add dave to beta: %v", err)
+	}
+
+	links := NewShareLinkService()
+	link, err := links.CreateLink(f.betaThread.ID, f.bob.ID, &types.CreateShareLinkRequest{Role: types.ThreadRoleViewer})
+	if err != nil {
+		t.Fatalf("create share link: %v", err)
+	}
+
+	if _, err := links.JoinByLink(link.Token, f.carol.ID); err == nil {
+		t.Fatal("carol joined a thread of a workspace she is not in")
+	}
+	var collaborators int64
+	f.db.Model(&types.ThreadCollaborator{}).Where("thread_id = ? AND user_id = ?", f.betaThread.ID, f.carol.ID).Count(&collaborators)
+	if collaborators != 0 {
+		t.Fatal("the refused join still added carol to the thread")
+	}
+
+	if _, err := links.JoinByLink(link.Token, dave.ID); err != nil {
+		t.Fatalf("dave cannot join through the link: %v", err)
+	}
+}
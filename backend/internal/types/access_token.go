@@ -0,0 +1,88 @@
+package types
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TokenScope string
+
+const (
+	ScopeThreadsRead  TokenScope = "threads:read"
+	ScopeThreadsWrite TokenScope = "threads:write"
+	ScopeNotesRead    TokenScope = "notes:read"
+	ScopeNotesWrite   TokenScope = "notes:write"
+	ScopeInvitesRead  TokenScope = "invites:read"
+	ScopeInvitesWrite TokenScope = "invites:write"
+	ScopeUsersRead    TokenScope = "users:read"
+	ScopeUsersWrite   TokenScope = "users:write"
+)
+
+// AllTokenScopes lists every scope a personal access token may be granted.
+var AllTokenScopes = []TokenScope{
+	ScopeThreadsRead,
+	ScopeThreadsWrite,
+	ScopeNotesRead,
+	ScopeNotesWrite,
+	ScopeInvitesRead,
+	ScopeInvitesWrite,
+	ScopeUsersRead,
+	ScopeUsersWrite,
+}
+
+func IsValidTokenScope(scope TokenScope) bool {
+	for _, s := range AllTokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type PersonalAccessToken struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	UserID      uint           `json:"user_id" gorm:"not null;index"`
+	User        User           `json:"user" gorm:"foreignKey:UserID"`
+	Name        string         `json:"name" gorm:"not null"`
+	TokenHash   string         `json:"-" gorm:"uniqueIndex;not null"`
+	TokenPrefix string         `json:"token_prefix" gorm:"not null"`
+	Scopes      string         `json:"scopes" gorm:"not null"` // space separated list of TokenScope
+	ExpiresAt   *time.Time     `json:"expires_at"`
+	LastUsedAt  *time.Time     `json:"last_used_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+func (t *PersonalAccessToken) ScopeList() []TokenScope {
+	var scopes []TokenScope
+	for _, s := range strings.Fields(t.Scopes) {
+		scopes = append(scopes, TokenScope(s))
+	}
+	return scopes
+}
+
+type CreateAccessTokenRequest struct {
+	Name          string       `json:"name" binding:"required,max=100"`
+	Scopes        []TokenScope `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int          `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+type AccessTokenResponse struct {
+	ID          uint         `json:"id"`
+	Name        string       `json:"name"`
+	TokenPrefix string       `json:"token_prefix"`
+	Scopes      []TokenScope `json:"scopes"`
+	ExpiresAt   *time.Time   `json:"expires_at"`
+	LastUsedAt  *time.Time   `json:"last_used_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// CreateAccessTokenResponse carries the plain token, which is only ever
+// returned once at creation time.
+type CreateAccessTokenResponse struct {
+	AccessTokenResponse
+	Token string `json:"token"`
+}
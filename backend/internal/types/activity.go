@@ -0,0 +1,47 @@
+package types
+
+import (
+	"time"
+)
+
+type ActivityAction string
+
+const (
+	ActivityCollaboratorAdded       ActivityAction = "collaborator_added"
+	ActivityCollaboratorRoleChanged ActivityAction = "collaborator_role_changed"
+	ActivityCollaboratorRemoved     ActivityAction = "collaborator_removed"
+	ActivityCollaboratorLeft        ActivityAction = "collaborator_left"
+	ActivityCollaboratorJoined      ActivityAction = "collaborator_joined"
+	ActivityOwnershipTransferred    ActivityAction = "ownership_transferred"
+	ActivityGroupGranted            ActivityAction = "group_granted"
+	ActivityGroupRoleChanged        ActivityAction = "group_role_changed"
+	ActivityGroupRevoked            ActivityAction = "group_revoked"
+)
+
+// ThreadActivity is one entry in a thread's membership history.
+type ThreadActivity struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	ThreadID     uint           `json:"thread_id" gorm:"not null;index"`
+	ActorID      uint           `json:"actor_id" gorm:"not null"`
+	Actor        User           `json:"actor" gorm:"foreignKey:ActorID"`
+	Action       ActivityAction `json:"action" gorm:"not null"`
+	TargetUserID *uint          `json:"target_user_id"`
+	TargetUser   *User          `json:"target_user,omitempty" gorm:"foreignKey:TargetUserID"`
+	GroupID      *uint          `json:"group_id"`
+	Group        *UserGroup     `json:"group,omitempty" gorm:"foreignKey:GroupID"`
+	FromRole     ThreadRole     `json:"from_role"`
+	ToRole       ThreadRole     `json:"to_role"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+type ThreadActivityResponse struct {
+	ID         uint           `json:"id"`
+	ThreadID   uint           `json:"thread_id"`
+	Action     ActivityAction `json:"action"`
+	Actor      UserResponse   `json:"actor"`
+	TargetUser *UserResponse  `json:"target_user,omitempty"`
+	Group      *GroupSummary  `json:"group,omitempty"`
+	FromRole   ThreadRole     `json:"from_role,omitempty"`
+	ToRole     ThreadRole     `json:"to_role,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
@@ -0,0 +1,41 @@
+package types
+
+import (
+	"time"
+)
+
+type DataExportStatus string
+
+const (
+	DataExportStatusPending   DataExportStatus = "pending"
+	DataExportStatusRunning   DataExportStatus = "running"
+	DataExportStatusCompleted DataExportStatus = "completed"
+	DataExportStatusFailed    DataExportStatus = "failed"
+	DataExportStatusExpired   DataExportStatus = "expired"
+)
+
+// DataExport is a user's request for an archive of all their personal data.
+type DataExport struct {
+	ID          uint             `json:"id" gorm:"primaryKey"`
+	UserID      uint             `json:"user_id" gorm:"not null;index"`
+	Status      DataExportStatus `json:"status" gorm:"default:'pending'"`
+	BlobKey     string           `json:"-"`
+	Size        int64            `json:"size"`
+	Error       string           `json:"error"`
+	CompletedAt *time.Time       `json:"completed_at"`
+	ExpiresAt   *time.Time       `json:"expires_at" gorm:"index"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+type DataExportResponse struct {
+	ID                uint             `json:"id"`
+	Status            DataExportStatus `json:"status"`
+	Size              int64            `json:"size,omitempty"`
+	Error             string           `json:"error,omitempty"`
+	DownloadURL       string           `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time       `json:"download_expires_at,omitempty"`
+	CompletedAt       *time.Time       `json:"completed_at"`
+	ExpiresAt         *time.Time       `json:"expires_at"`
+	CreatedAt         time.Time        `json:"created_at"`
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"time"
+)
+
+// Folder is a node in a user's own folder tree. Threads are filed into it
+// through the user's ThreadPreference, so collaborators file shared threads
+// independently.
+type Folder struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	ParentID  *uint     `json:"parent_id" gorm:"index"`
+	Name      string    `json:"name" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateFolderRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// UpdateFolderRequest renames a folder or moves it under another one.
+type UpdateFolderRequest struct {
+	Name     string `json:"name" binding:"omitempty,max=100"`
+	ParentID *uint  `json:"parent_id"`
+	// MoveToRoot moves the folder to the top of the tree
+	MoveToRoot bool `json:"move_to_root"`
+}
+
+// MoveThreadRequest files a thread into a folder, or takes it out of its
+// folder when FolderID is left out.
+type MoveThreadRequest struct {
+	FolderID *uint `json:"folder_id"`
+}
+
+type FolderResponse struct {
+	ID          uint             `json:"id"`
+	ParentID    *uint            `json:"parent_id"`
+	Name        string           `json:"name"`
+	Path        string           `json:"path"`
+	ThreadCount int              `json:"thread_count"`
+	Children    []FolderResponse `json:"children"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
@@ -0,0 +1,111 @@
+package types
+
+import (
+	"time"
+)
+
+// GroupRole is a member's standing in a group. Managers change the group and
+// its membership.
+type GroupRole string
+
+const (
+	GroupRoleMember  GroupRole = "member"
+	GroupRoleManager GroupRole = "manager"
+)
+
+// UserGroup is a named set of users, such as a team, that threads are shared
+// with as a unit. Its members reach every thread the group has been granted
+// for as long as they stay in it.
+type UserGroup struct {
+	ID          uint              `json:"id" gorm:"primaryKey"`
+	Name        string            `json:"name" gorm:"not null"`
+	Description string            `json:"description"`
+	WorkspaceID *uint             `json:"workspace_id" gorm:"index"` // nil outside any workspace
+	CreatedByID uint              `json:"created_by_id" gorm:"not null"`
+	Members     []UserGroupMember `json:"members" gorm:"foreignKey:GroupID"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+type UserGroupMember struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	GroupID   uint      `json:"group_id" gorm:"not null;uniqueIndex:idx_group_member_group_user"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_group_member_group_user;index"`
+	User      User      `json:"user" gorm:"foreignKey:UserID"`
+	Role      GroupRole `json:"role" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ThreadGroupGrant gives every member of a group a role on a thread.
+type ThreadGroupGrant struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	ThreadID    uint       `json:"thread_id" gorm:"not null;uniqueIndex:idx_thread_group_thread_group"`
+	GroupID     uint       `json:"group_id" gorm:"not null;uniqueIndex:idx_thread_group_thread_group;index"`
+	Group       UserGroup  `json:"group" gorm:"foreignKey:GroupID"`
+	Role        ThreadRole `json:"role" gorm:"not null"`
+	GrantedByID uint       `json:"granted_by_id" gorm:"not null"`
+	GrantedBy   User       `json:"granted_by" gorm:"foreignKey:GrantedByID"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type CreateGroupRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=500"`
+}
+
+type UpdateGroupRequest struct {
+	Name        string  `json:"name" binding:"omitempty,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+}
+
+type AddGroupMemberRequest struct {
+	UserID uint      `json:"user_id" binding:"required"`
+	Role   GroupRole `json:"role" binding:"omitempty,oneof=member manager"`
+}
+
+type UpdateGroupMemberRequest struct {
+	Role GroupRole `json:"role" binding:"required,oneof=member manager"`
+}
+
+type GrantGroupRequest struct {
+	GroupID uint       `json:"group_id" binding:"required"`
+	Role    ThreadRole `json:"role" binding:"omitempty,oneof=viewer commenter editor admin"`
+}
+
+type UpdateGroupGrantRequest struct {
+	Role ThreadRole `json:"role" binding:"required,oneof=viewer commenter editor admin"`
+}
+
+type GroupResponse struct {
+	ID           uint      `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	WorkspaceID  *uint     `json:"workspace_id"`
+	Role         GroupRole `json:"role"`
+	MembersCount int       `json:"members_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type GroupMemberResponse struct {
+	UserResponse
+	Role     GroupRole `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// ThreadGroupResponse is a group as granted on a thread.
+type ThreadGroupResponse struct {
+	ID           uint         `json:"id"`
+	Name         string       `json:"name"`
+	MembersCount int          `json:"members_count"`
+	Role         ThreadRole   `json:"role"`
+	GrantedBy    UserResponse `json:"granted_by"`
+	GrantedAt    time.Time    `json:"granted_at"`
+}
+
+// GroupSummary names a group in a thread's membership history.
+type GroupSummary struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
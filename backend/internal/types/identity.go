@@ -0,0 +1,52 @@
+package types
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ExternalIdentity links a user to an account at an external identity provider.
+type ExternalIdentity struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	UserID    uint           `json:"user_id" gorm:"not null;index"`
+	User      User           `json:"user" gorm:"foreignKey:UserID"`
+	Provider  string         `json:"provider" gorm:"not null;uniqueIndex:idx_identity_provider_subject"`
+	Subject   string         `json:"subject" gorm:"not null;uniqueIndex:idx_identity_provider_subject"`
+	Email     string         `json:"email"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// OIDCLoginState holds the per-attempt secrets of an authorization code flow
+// between the login redirect and the callback. BindingHash is the hash of the
+// secret kept in the browser that started the flow; UserID is set when a
+// signed in user links an identity rather than logging in.
+type OIDCLoginState struct {
+	State        string    `gorm:"primaryKey"`
+	Provider     string    `gorm:"not null"`
+	CodeVerifier string    `gorm:"not null"`
+	Nonce        string    `gorm:"not null"`
+	BindingHash  string    `gorm:"not null"`
+	UserID       uint      `gorm:"not null;default:0"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time
+}
+
+type OIDCProviderResponse struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+type OIDCCallbackRequest struct {
+	Code  string `form:"code" binding:"required"`
+	State string `form:"state" binding:"required"`
+}
+
+type ExternalIdentityResponse struct {
+	ID        uint      `json:"id"`
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -22,6 +22,7 @@ type Invite struct {
 	FromUser   User           `json:"from_user" gorm:"foreignKey:FromUserID"`
 	ToUserID   uint           `json:"to_user_id" gorm:"not null"`
 	ToUser     User           `json:"to_user" gorm:"foreignKey:ToUserID"`
+	Role       ThreadRole     `json:"role" gorm:"not null;default:'editor'"`
 	Status     InviteStatus   `json:"status" gorm:"default:'pending'"`
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
@@ -29,8 +30,9 @@ type Invite struct {
 }
 
 type CreateInviteRequest struct {
-	ThreadID uint `json:"thread_id" binding:"required"`
-	ToUserID uint `json:"to_user_id" binding:"required"`
+	ThreadID uint       `json:"thread_id" binding:"required"`
+	ToUserID uint       `json:"to_user_id" binding:"required"`
+	Role     ThreadRole `json:"role" binding:"omitempty,oneof=viewer commenter editor admin"`
 }
 
 type InviteResponse struct {
@@ -41,6 +43,7 @@ type InviteResponse struct {
 	FromUser   UserResponse   `json:"from_user"`
 	ToUserID   uint           `json:"to_user_id"`
 	ToUser     UserResponse   `json:"to_user"`
+	Role       ThreadRole     `json:"role"`
 	Status     InviteStatus   `json:"status"`
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
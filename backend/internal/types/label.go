@@ -0,0 +1,54 @@
+package types
+
+import (
+	"time"
+)
+
+// Label is a user's own tag for organizing threads. Once put on a thread it
+// is visible to everyone who can read the thread.
+type Label struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_label_user_name"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_label_user_name"`
+	Color     string    `json:"color" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ThreadLabel puts a label on a thread.
+type ThreadLabel struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ThreadID  uint      `json:"thread_id" gorm:"not null;uniqueIndex:idx_thread_label_thread_label"`
+	LabelID   uint      `json:"label_id" gorm:"not null;uniqueIndex:idx_thread_label_thread_label;index"`
+	Label     Label     `json:"label" gorm:"foreignKey:LabelID"`
+	AddedByID uint      `json:"added_by_id" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateLabelRequest struct {
+	Name  string `json:"name" binding:"required,max=50"`
+	Color string `json:"color" binding:"omitempty,hexcolor"`
+}
+
+type UpdateLabelRequest struct {
+	Name  string `json:"name" binding:"omitempty,max=50"`
+	Color string `json:"color" binding:"omitempty,hexcolor"`
+}
+
+type LabelResponse struct {
+	ID          uint      `json:"id"`
+	UserID      uint      `json:"user_id"`
+	Name        string    `json:"name"`
+	Color       string    `json:"color"`
+	ThreadCount int       `json:"thread_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ThreadLabelResponse is a label as shown on a thread.
+type ThreadLabelResponse struct {
+	ID     uint   `json:"id"`
+	UserID uint   `json:"user_id"`
+	Name   string `json:"name"`
+	Color  string `json:"color"`
+}
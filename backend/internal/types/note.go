@@ -7,32 +7,118 @@ import (
 )
 
 type Note struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Content   string         `json:"content" gorm:"not null"`
-	ThreadID  uint           `json:"thread_id" gorm:"not null"`
-	Thread    Thread         `json:"thread" gorm:"foreignKey:ThreadID"`
-	UserID    uint           `json:"user_id" gorm:"not null"`
-	User      User           `json:"user" gorm:"foreignKey:UserID"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	Content      string         `json:"content" gorm:"not null"`
+	ThreadID     uint           `json:"thread_id" gorm:"not null"`
+	Thread       Thread         `json:"thread" gorm:"foreignKey:ThreadID"`
+	UserID       uint           `json:"user_id" gorm:"not null"`
+	User         User           `json:"user" gorm:"foreignKey:UserID"`
+	ParentNoteID *uint          `json:"parent_note_id" gorm:"index"`       // the note replied to, nil for top-level notes
+	Version      uint           `json:"version" gorm:"not null;default:1"` // bumped by every update
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// NoteRevision is one version of a note's content. Revision 1 is the note as
+// it was posted, and every edit adds the next one.
+type NoteRevision struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	NoteID    uint      `json:"note_id" gorm:"not null;uniqueIndex:idx_note_revision_note_number"`
+	Number    int       `json:"number" gorm:"not null;uniqueIndex:idx_note_revision_note_number"`
+	Content   string    `json:"content" gorm:"not null"`
+	EditorID  uint      `json:"editor_id" gorm:"not null"`
+	Editor    User      `json:"editor" gorm:"foreignKey:EditorID"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NoteReaction is one user's emoji reaction to a note, stored as a unicode
+// emoji or a ":shortcode:".
+type NoteReaction struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	NoteID    uint      `json:"note_id" gorm:"not null;uniqueIndex:idx_note_reaction_note_user_emoji"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_note_reaction_note_user_emoji"`
+	User      User      `json:"user" gorm:"foreignKey:UserID"`
+	Emoji     string    `json:"emoji" gorm:"not null;uniqueIndex:idx_note_reaction_note_user_emoji"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type CreateNoteRequest struct {
-	Content  string `json:"content" binding:"required"`
-	ThreadID uint   `json:"thread_id" binding:"required"`
+	Content      string `json:"content" binding:"required"`
+	ThreadID     uint   `json:"thread_id" binding:"required"`
+	ParentNoteID *uint  `json:"parent_note_id"`
 }
 
 type UpdateNoteRequest struct {
 	Content string `json:"content" binding:"required"`
 }
 
+// NoteListQuery pages through a thread's notes. Thread listings hold only
+// top-level notes unless IncludeReplies is set.
+type NoteListQuery struct {
+	Sort           string    `form:"sort" binding:"omitempty,oneof=oldest newest"`
+	Limit          int       `form:"limit" binding:"omitempty,min=1,max=200"`
+	Cursor         string    `form:"cursor"`
+	UpdatedSince   time.Time `form:"updated_since"`
+	Author         uint      `form:"author"`
+	IncludeReplies bool      `form:"include_replies"`
+}
+
+// ReactionRequest names the emoji to add, in the body, or to remove, in the
+// query string.
+type ReactionRequest struct {
+	Emoji string `json:"emoji" form:"emoji" binding:"required,max=64"`
+}
+
+// NoteDiffQuery picks the revision to compare against, the one before by
+// default, and whether to compare lines or words.
+type NoteDiffQuery struct {
+	Against int    `form:"against" binding:"omitempty,min=1"`
+	Mode    string `form:"mode" binding:"omitempty,oneof=line word"`
+}
+
 type NoteResponse struct {
-	ID        uint         `json:"id"`
+	ID            uint              `json:"id"`
+	Content       string            `json:"content"`
+	ThreadID      uint              `json:"thread_id"`
+	UserID        uint              `json:"user_id"`
+	User          UserResponse      `json:"user"`
+	ParentNoteID  *uint             `json:"parent_note_id"`
+	ReplyCount    int               `json:"reply_count"`
+	Reactions     []ReactionSummary `json:"reactions"`
+	Edited        bool              `json:"edited"`
+	RevisionCount int               `json:"revision_count"`
+	Version       uint              `json:"version"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// ReactionSummary counts the reactions to a note with one emoji.
+type ReactionSummary struct {
+	Emoji       string `json:"emoji"`
+	Count       int    `json:"count"`
+	ReactedByMe bool   `json:"reacted_by_me"`
+}
+
+type NoteRevisionResponse struct {
+	Number    int          `json:"number"`
 	Content   string       `json:"content"`
-	ThreadID  uint         `json:"thread_id"`
-	UserID    uint         `json:"user_id"`
-	User      UserResponse `json:"user"`
+	Editor    UserResponse `json:"editor"`
+	Current   bool         `json:"current"`
 	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// DiffChange is a run of text kept ("equal"), added ("insert") or removed
+// ("delete") between two revisions.
+type DiffChange struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type NoteDiffResponse struct {
+	NoteID  uint         `json:"note_id"`
+	From    int          `json:"from"`
+	To      int          `json:"to"`
+	Mode    string       `json:"mode"`
+	Changes []DiffChange `json:"changes"`
 }
@@ -0,0 +1,60 @@
+package types
+
+import (
+	"time"
+)
+
+// ThreadReadMarker is how far a user has read a thread. Notes after
+// LastReadNoteID written by someone else are unread.
+type ThreadReadMarker struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	UserID         uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_thread_read_marker_user_thread"`
+	ThreadID       uint      `json:"thread_id" gorm:"not null;uniqueIndex:idx_thread_read_marker_user_thread;index"`
+	LastReadNoteID uint      `json:"last_read_note_id" gorm:"not null"`
+	LastReadAt     time.Time `json:"last_read_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// MarkReadRequest moves the read marker up to a note, or up to the latest
+// note when NoteID is left out.
+type MarkReadRequest struct {
+	NoteID uint `json:"note_id"`
+}
+
+type ReadMarkerResponse struct {
+	ThreadID          uint      `json:"thread_id"`
+	LastReadNoteID    uint      `json:"last_read_note_id"`
+	LastReadAt        time.Time `json:"last_read_at"`
+	UnreadCount       int       `json:"unread_count"`
+	FirstUnreadNoteID *uint     `json:"first_unread_note_id"`
+}
+
+type ThreadUnread struct {
+	ThreadID          uint   `json:"thread_id"`
+	Title             string `json:"title"`
+	UnreadCount       int    `json:"unread_count"`
+	FirstUnreadNoteID uint   `json:"first_unread_note_id"`
+}
+
+// UnreadSummary counts unread notes across the threads the user owns or
+// collaborates on. Threads without unread notes are left out.
+type UnreadSummary struct {
+	TotalUnread int            `json:"total_unread"`
+	Threads     []ThreadUnread `json:"threads"`
+}
+
+// NoteReceipt is a member who has read a note. ReadAt is when they last moved
+// their read marker, which is at or after they read the note.
+type NoteReceipt struct {
+	User   UserResponse `json:"user"`
+	ReadAt time.Time    `json:"read_at"`
+}
+
+// NoteReceiptsResponse lists which thread members have read a note. The
+// author is left out of both lists.
+type NoteReceiptsResponse struct {
+	NoteID    uint           `json:"note_id"`
+	ReadBy    []NoteReceipt  `json:"read_by"`
+	NotReadBy []UserResponse `json:"not_read_by"`
+}
@@ -0,0 +1,81 @@
+package types
+
+import (
+	"time"
+)
+
+// ShareLink lets anyone holding its token join a thread with the link's
+// role, and optionally read it without an account.
+type ShareLink struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	ThreadID       uint       `json:"thread_id" gorm:"not null;index"`
+	Thread         Thread     `json:"thread" gorm:"foreignKey:ThreadID"`
+	CreatedByID    uint       `json:"created_by_id" gorm:"not null"`
+	CreatedBy      User       `json:"created_by" gorm:"foreignKey:CreatedByID"`
+	TokenHash      string     `json:"-" gorm:"uniqueIndex;not null"`
+	TokenPrefix    string     `json:"token_prefix" gorm:"not null"`
+	Role           ThreadRole `json:"role" gorm:"not null"`
+	AllowAnonymous bool       `json:"allow_anonymous" gorm:"not null"`
+	MaxUses        int        `json:"max_uses"` // 0 means unlimited
+	UseCount       int        `json:"use_count"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type CreateShareLinkRequest struct {
+	Role           ThreadRole `json:"role" binding:"omitempty,oneof=viewer commenter editor admin"`
+	AllowAnonymous bool       `json:"allow_anonymous"`
+	MaxUses        int        `json:"max_uses" binding:"omitempty,min=1"`
+	ExpiresInHours int        `json:"expires_in_hours" binding:"omitempty,min=1,max=8760"`
+}
+
+type ShareLinkResponse struct {
+	ID             uint         `json:"id"`
+	ThreadID       uint         `json:"thread_id"`
+	TokenPrefix    string       `json:"token_prefix"`
+	Role           ThreadRole   `json:"role"`
+	AllowAnonymous bool         `json:"allow_anonymous"`
+	MaxUses        int          `json:"max_uses"`
+	UseCount       int          `json:"use_count"`
+	ExpiresAt      *time.Time   `json:"expires_at"`
+	RevokedAt      *time.Time   `json:"revoked_at"`
+	CreatedBy      UserResponse `json:"created_by"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// CreateShareLinkResponse carries the plain token and link, which are only
+// ever returned once at creation time.
+type CreateShareLinkResponse struct {
+	ShareLinkResponse
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// SharedAuthor is the part of a profile shown to anonymous readers.
+type SharedAuthor struct {
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type SharedNoteResponse struct {
+	ID        uint          `json:"id"`
+	Content   string        `json:"content"`
+	Author    *SharedAuthor `json:"author"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// SharedThreadResponse is what a share link shows before joining. Notes are
+// only included when the link allows anonymous reading.
+type SharedThreadResponse struct {
+	Title          string               `json:"title"`
+	Description    string               `json:"description"`
+	Owner          SharedAuthor         `json:"owner"`
+	Role           ThreadRole           `json:"role"`
+	AllowAnonymous bool                 `json:"allow_anonymous"`
+	Notes          []SharedNoteResponse `json:"notes,omitempty"`
+}
@@ -6,49 +6,142 @@ import (
 	"gorm.io/gorm"
 )
 
+// ThreadRole is a user's access level on a thread. The owner is implicit and
+// never stored on a collaborator.
+type ThreadRole string
+
+const (
+	ThreadRoleViewer    ThreadRole = "viewer"
+	ThreadRoleCommenter ThreadRole = "commenter"
+	ThreadRoleEditor    ThreadRole = "editor"
+	ThreadRoleAdmin     ThreadRole = "admin"
+	ThreadRoleOwner     ThreadRole = "owner"
+)
+
+var threadRoleRanks = map[ThreadRole]int{
+	ThreadRoleViewer:    1,
+	ThreadRoleCommenter: 2,
+	ThreadRoleEditor:    3,
+	ThreadRoleAdmin:     4,
+	ThreadRoleOwner:     5,
+}
+
+// Rank orders roles by privilege; unknown roles rank 0.
+func (r ThreadRole) Rank() int {
+	return threadRoleRanks[r]
+}
+
+// AtLeast reports whether r grants everything min does.
+func (r ThreadRole) AtLeast(min ThreadRole) bool {
+	return r.Rank() > 0 && r.Rank() >= min.Rank()
+}
+
+// IsAssignable reports whether the role can be given to a collaborator.
+func (r ThreadRole) IsAssignable() bool {
+	return r.Rank() > 0 && r != ThreadRoleOwner
+}
+
 type Thread struct {
-	ID            uint                 `json:"id" gorm:"primaryKey"`
-	Title         string               `json:"title" gorm:"not null"`
-	Description   string               `json:"description"`
-	IsPrivate     bool                 `json:"is_private" gorm:"default:true"`
-	UserID        uint                 `json:"user_id" gorm:"not null"`
-	User          User                 `json:"user" gorm:"foreignKey:UserID"`
-	Collaborators []ThreadCollaborator `json:"collaborators" gorm:"foreignKey:ThreadID"`
-	Notes         []Note               `json:"notes" gorm:"foreignKey:ThreadID"`
-	CreatedAt     time.Time            `json:"created_at"`
-	UpdatedAt     time.Time            `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt       `json:"deleted_at,omitempty" gorm:"index"`
+	ID                  uint                 `json:"id" gorm:"primaryKey"`
+	Title               string               `json:"title" gorm:"not null"`
+	Description         string               `json:"description"`
+	IsPrivate           bool                 `json:"is_private" gorm:"not null"`
+	ReadReceiptsEnabled bool                 `json:"read_receipts_enabled" gorm:"not null"`
+	UserID              uint                 `json:"user_id" gorm:"not null"`
+	User                User                 `json:"user" gorm:"foreignKey:UserID"`
+	WorkspaceID         *uint                `json:"workspace_id" gorm:"index"`
+	Collaborators       []ThreadCollaborator `json:"collaborators" gorm:"foreignKey:ThreadID"`
+	Notes               []Note               `json:"notes" gorm:"foreignKey:ThreadID"`
+	Version             uint                 `json:"version" gorm:"not null;default:1"` // bumped by every update
+	CreatedAt           time.Time            `json:"created_at"`
+	UpdatedAt           time.Time            `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt       `json:"deleted_at,omitempty" gorm:"index"`
 }
 
+// ThreadCollaborator is only soft deleted together with its thread; leaving
+// or being removed deletes the row.
 type ThreadCollaborator struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	ThreadID  uint      `json:"thread_id" gorm:"not null"`
-	UserID    uint      `json:"user_id" gorm:"not null"`
-	User      User      `json:"user" gorm:"foreignKey:UserID"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	ThreadID  uint           `json:"thread_id" gorm:"not null"`
+	UserID    uint           `json:"user_id" gorm:"not null"`
+	User      User           `json:"user" gorm:"foreignKey:UserID"`
+	Role      ThreadRole     `json:"role" gorm:"not null;default:'editor'"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 type CreateThreadRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description"`
-	IsPrivate   bool   `json:"is_private"`
+	Title               string `json:"title" binding:"required"`
+	Description         string `json:"description"`
+	IsPrivate           *bool  `json:"is_private"`
+	ReadReceiptsEnabled *bool  `json:"read_receipts_enabled"`
+}
+
+// ThreadListQuery pages through the threads the user owns or collaborates on.
+type ThreadListQuery struct {
+	Scope        string    `form:"scope" binding:"omitempty,oneof=owned shared all"`
+	Sort         string    `form:"sort" binding:"omitempty,oneof=custom updated created last_note title"`
+	Limit        int       `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor       string    `form:"cursor"`
+	UpdatedSince time.Time `form:"updated_since"`
+	Owner        uint      `form:"owner"`
+	Archived     string    `form:"archived" binding:"omitempty,oneof=true false all"`
+	Pinned       bool      `form:"pinned"`
+	Starred      bool      `form:"starred"`
+	HasUnread    string    `form:"has_unread" binding:"omitempty,oneof=true false"`
+	Labels       string    `form:"labels"` // comma separated label IDs
+	LabelMatch   string    `form:"label_match" binding:"omitempty,oneof=any all"`
+	Folder       string    `form:"folder"` // folder ID, or "none" for unfiled threads
+	Recursive    bool      `form:"recursive"`
+	WorkspaceID  uint      `form:"-"` // set from the request's workspace
+}
+
+// PublicThreadsQuery filters and orders the public thread directory.
+type PublicThreadsQuery struct {
+	Q           string `form:"q"`
+	Sort        string `form:"sort" binding:"omitempty,oneof=recent popular"`
+	Limit       int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor      string `form:"cursor"`
+	WorkspaceID uint   `form:"-"` // set from the request's workspace
+}
+
+type UpdateCollaboratorRequest struct {
+	Role ThreadRole `json:"role" binding:"required,oneof=viewer commenter editor admin"`
 }
 
 type UpdateThreadRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	IsPrivate   *bool  `json:"is_private"`
+	Title               string `json:"title"`
+	Description         string `json:"description"`
+	IsPrivate           *bool  `json:"is_private"`
+	ReadReceiptsEnabled *bool  `json:"read_receipts_enabled"`
+}
+
+// CollaboratorResponse is a collaborator's user profile and role.
+type CollaboratorResponse struct {
+	UserResponse
+	Role     ThreadRole `json:"role"`
+	JoinedAt time.Time  `json:"joined_at"`
 }
 
 type ThreadResponse struct {
-	ID            uint           `json:"id"`
-	Title         string         `json:"title"`
-	Description   string         `json:"description"`
-	IsPrivate     bool           `json:"is_private"`
-	UserID        uint           `json:"user_id"`
-	User          UserResponse   `json:"user"`
-	Collaborators []UserResponse `json:"collaborators"`
-	NotesCount    int            `json:"notes_count"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
+	ID                  uint                   `json:"id"`
+	Title               string                 `json:"title"`
+	Description         string                 `json:"description"`
+	IsPrivate           bool                   `json:"is_private"`
+	ReadReceiptsEnabled bool                   `json:"read_receipts_enabled"`
+	UserID              uint                   `json:"user_id"`
+	User                UserResponse           `json:"user"`
+	WorkspaceID         *uint                  `json:"workspace_id"`
+	Collaborators       []CollaboratorResponse `json:"collaborators"`
+	Role                ThreadRole             `json:"role,omitempty"`
+	Preferences         *ThreadPreferences     `json:"preferences,omitempty"`
+	Labels              []ThreadLabelResponse  `json:"labels"`
+	FolderPath          string                 `json:"folder_path,omitempty"`
+	NotesCount          int                    `json:"notes_count"`
+	LastNoteAt          *time.Time             `json:"last_note_at"`
+	UnreadCount         int                    `json:"unread_count"`
+	FirstUnreadNoteID   *uint                  `json:"first_unread_note_id"`
+	Version             uint                   `json:"version"`
+	CreatedAt           time.Time              `json:"created_at"`
+	UpdatedAt           time.Time              `json:"updated_at"`
 }
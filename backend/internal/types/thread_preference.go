@@ -0,0 +1,43 @@
+package types
+
+import (
+	"time"
+)
+
+// ThreadPreference is how one user has organized a thread for themselves.
+// Collaborators never see each other's preferences.
+type ThreadPreference struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_thread_preference_user_thread"`
+	ThreadID  uint      `json:"thread_id" gorm:"not null;uniqueIndex:idx_thread_preference_user_thread;index"`
+	Archived  bool      `json:"archived" gorm:"not null"`
+	Pinned    bool      `json:"pinned" gorm:"not null"`
+	Starred   bool      `json:"starred" gorm:"not null"`
+	SortOrder *int      `json:"sort_order"`
+	FolderID  *uint     `json:"folder_id" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UpdateThreadPreferencesRequest struct {
+	Archived  *bool `json:"archived"`
+	Pinned    *bool `json:"pinned"`
+	Starred   *bool `json:"starred"`
+	SortOrder *int  `json:"sort_order" binding:"omitempty,min=0"`
+	// ClearSortOrder drops the thread back out of the custom order
+	ClearSortOrder bool `json:"clear_sort_order"`
+}
+
+// ReorderThreadsRequest sets the custom order of the listed threads to
+// their position in the list.
+type ReorderThreadsRequest struct {
+	ThreadIDs []uint `json:"thread_ids" binding:"required,min=1,max=500"`
+}
+
+type ThreadPreferences struct {
+	Archived  bool  `json:"archived"`
+	Pinned    bool  `json:"pinned"`
+	Starred   bool  `json:"starred"`
+	SortOrder *int  `json:"sort_order"`
+	FolderID  *uint `json:"folder_id"`
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"time"
+)
+
+type TransferStatus string
+
+const (
+	TransferStatusPending   TransferStatus = "pending"
+	TransferStatusAccepted  TransferStatus = "accepted"
+	TransferStatusDeclined  TransferStatus = "declined"
+	TransferStatusCancelled TransferStatus = "cancelled"
+)
+
+// ThreadTransfer is an offer to hand a thread's ownership to one of its
+// collaborators. It takes effect once the recipient accepts.
+type ThreadTransfer struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	ThreadID      uint           `json:"thread_id" gorm:"not null;index"`
+	Thread        Thread         `json:"thread" gorm:"foreignKey:ThreadID"`
+	FromUserID    uint           `json:"from_user_id" gorm:"not null"`
+	FromUser      User           `json:"from_user" gorm:"foreignKey:FromUserID"`
+	ToUserID      uint           `json:"to_user_id" gorm:"not null;index"`
+	ToUser        User           `json:"to_user" gorm:"foreignKey:ToUserID"`
+	InitiatedByID uint           `json:"initiated_by_id" gorm:"not null"`
+	InitiatedBy   User           `json:"initiated_by" gorm:"foreignKey:InitiatedByID"`
+	Status        TransferStatus `json:"status" gorm:"default:'pending'"`
+	RespondedAt   *time.Time     `json:"responded_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+type CreateTransferRequest struct {
+	ToUserID uint `json:"to_user_id" binding:"required"`
+}
+
+type ThreadTransferResponse struct {
+	ID          uint           `json:"id"`
+	ThreadID    uint           `json:"thread_id"`
+	Thread      ThreadResponse `json:"thread"`
+	FromUser    UserResponse   `json:"from_user"`
+	ToUser      UserResponse   `json:"to_user"`
+	InitiatedBy UserResponse   `json:"initiated_by"`
+	Status      TransferStatus `json:"status"`
+	RespondedAt *time.Time     `json:"responded_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package types
+
+import (
+	"time"
+)
+
+type TrashedThreadResponse struct {
+	ThreadResponse
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+type TrashedNoteResponse struct {
+	NoteResponse
+	ThreadTitle string    `json:"thread_title"`
+	DeletedAt   time.Time `json:"deleted_at"`
+	PurgeAt     time.Time `json:"purge_at"`
+}
+
+// TrashResponse lists what the user can restore: their deleted threads, and
+// notes deleted on their own from threads that still exist.
+type TrashResponse struct {
+	Threads []TrashedThreadResponse `json:"threads"`
+	Notes   []TrashedNoteResponse   `json:"notes"`
+}
@@ -6,16 +6,36 @@ import (
 	"gorm.io/gorm"
 )
 
+type UserRole string
+
+const (
+	UserRoleUser  UserRole = "user"
+	UserRoleAdmin UserRole = "admin"
+)
+
+type AuthSource string
+
+const (
+	AuthSourceLocal AuthSource = "local"
+	AuthSourceLDAP  AuthSource = "ldap"
+)
+
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	Username  string         `json:"username" gorm:"uniqueIndex;not null"`
-	Password  string         `json:"-" gorm:"not null"`
-	FirstName string         `json:"first_name"`
-	LastName  string         `json:"last_name"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	Email         string         `json:"email" gorm:"uniqueIndex;not null"`
+	Username      string         `json:"username" gorm:"uniqueIndex;not null"`
+	Password      string         `json:"-" gorm:"not null"`
+	FirstName     string         `json:"first_name"`
+	LastName      string         `json:"last_name"`
+	Role          UserRole       `json:"role" gorm:"default:'user'"`
+	AuthSource    AuthSource     `json:"auth_source" gorm:"default:'local'"`
+	ExternalDN    string         `json:"-" gorm:"index"`
+	AvatarURL     string         `json:"avatar_url"`
+	AvatarKey     string         `json:"-"`
+	DeactivatedAt *time.Time     `json:"deactivated_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
 	Threads         []Thread             `json:"threads,omitempty" gorm:"foreignKey:UserID"`
@@ -31,6 +51,7 @@ type UserResponse struct {
 	Username  string    `json:"username"`
 	FirstName string    `json:"first_name"`
 	LastName  string    `json:"last_name"`
+	AvatarURL string    `json:"avatar_url"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -50,3 +71,50 @@ type RegisterRequest struct {
 type SearchUserRequest struct {
 	Query string `json:"query" binding:"required,min=2"`
 }
+
+// EmailChange is a pending change of a user's email address, applied once the
+// new address is verified.
+type EmailChange struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	NewEmail    string     `json:"new_email" gorm:"not null"`
+	TokenHash   string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type UpdateProfileRequest struct {
+	Username  *string `json:"username" binding:"omitempty,min=3,max=30"`
+	FirstName *string `json:"first_name" binding:"omitempty,min=1"`
+	LastName  *string `json:"last_name" binding:"omitempty,min=1"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password"`
+}
+
+type ConfirmEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type OwnedThreadsAction string
+
+const (
+	OwnedThreadsTransfer OwnedThreadsAction = "transfer"
+	OwnedThreadsDelete   OwnedThreadsAction = "delete"
+)
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	// OwnedThreads decides what happens to the user's threads: "transfer"
+	// hands each one to its longest standing active collaborator or a group
+	// member (threads without either are deleted), "delete" deletes them all.
+	OwnedThreads OwnedThreadsAction `json:"owned_threads" binding:"required,oneof=transfer delete"`
+}
@@ -1,5 +1,9 @@
 package types
 
+import (
+	"time"
+)
+
 type WebSocketMessage struct {
 	Type    string      `json:"type"`
 	Payload interface{} `json:"payload"`
@@ -25,6 +29,24 @@ type NoteUpdateMessage struct {
 	Note     NoteResponse `json:"note"`
 }
 
+// NoteReplyMessage tells the author of a note that someone replied to it.
+type NoteReplyMessage struct {
+	ThreadID     uint         `json:"thread_id"`
+	ParentNoteID uint         `json:"parent_note_id"`
+	Note         NoteResponse `json:"note"`
+}
+
+// ReactionMessage tells a thread that a reaction was added to or removed from
+// a note, with how many reactions of that emoji the note has now.
+type ReactionMessage struct {
+	ThreadID uint   `json:"thread_id"`
+	NoteID   uint   `json:"note_id"`
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Emoji    string `json:"emoji"`
+	Count    int    `json:"count"`
+}
+
 type NoteDeleteMessage struct {
 	ThreadID uint `json:"thread_id"`
 	NoteID   uint `json:"note_id"`
@@ -36,6 +58,38 @@ type UserTypingMessage struct {
 	Username string `json:"username"`
 }
 
+// ThreadReadMessage marks a thread read up to a note, or up to the latest
+// note when NoteID is zero.
+type ThreadReadMessage struct {
+	ThreadID uint `json:"thread_id"`
+	UserID   uint `json:"user_id"`
+	NoteID   uint `json:"note_id"`
+}
+
+// NoteReadMessage tells a thread that a member has read it up to a note.
+// Only the server sends it, and only on threads with read receipts on.
+type NoteReadMessage struct {
+	ThreadID uint      `json:"thread_id"`
+	UserID   uint      `json:"user_id"`
+	Username string    `json:"username"`
+	NoteID   uint      `json:"note_id"`
+	ReadAt   time.Time `json:"read_at"`
+}
+
+// ThreadKick removes a user from a live thread session after their
+// membership changed.
+type ThreadKick struct {
+	ThreadID uint   `json:"thread_id"`
+	UserID   uint   `json:"user_id"`
+	Reason   string `json:"reason"`
+}
+
+// UserNotification is a message for one user rather than a thread session.
+type UserNotification struct {
+	UserID  uint
+	Message *WebSocketMessage
+}
+
 type ThreadSession struct {
 	ThreadID uint
 	Users    map[uint]*WebSocketConnection
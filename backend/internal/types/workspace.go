@@ -0,0 +1,87 @@
+package types
+
+import (
+	"time"
+)
+
+// WorkspaceRole is a member's access level on a workspace.
+type WorkspaceRole string
+
+const (
+	WorkspaceRoleMember WorkspaceRole = "member"
+	WorkspaceRoleAdmin  WorkspaceRole = "admin"
+	WorkspaceRoleOwner  WorkspaceRole = "owner"
+)
+
+var workspaceRoleRanks = map[WorkspaceRole]int{
+	WorkspaceRoleMember: 1,
+	WorkspaceRoleAdmin:  2,
+	WorkspaceRoleOwner:  3,
+}
+
+// AtLeast reports whether r grants everything min does.
+func (r WorkspaceRole) AtLeast(min WorkspaceRole) bool {
+	return workspaceRoleRanks[r] > 0 && workspaceRoleRanks[r] >= workspaceRoleRanks[min]
+}
+
+// Workspace is a team's tenant. Its threads are only visible to its members,
+// and can only be shared with them. Threads outside any workspace are the
+// users' personal threads.
+type Workspace struct {
+	ID                 uint              `json:"id" gorm:"primaryKey"`
+	Name               string            `json:"name" gorm:"not null"`
+	OwnerID            uint              `json:"owner_id" gorm:"not null"`
+	DefaultPrivate     bool              `json:"default_private" gorm:"not null"`
+	TrashRetentionDays int               `json:"trash_retention_days"` // 0 means the server default
+	Members            []WorkspaceMember `json:"members" gorm:"foreignKey:WorkspaceID"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+}
+
+type WorkspaceMember struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint          `json:"workspace_id" gorm:"not null;uniqueIndex:idx_workspace_member_workspace_user"`
+	UserID      uint          `json:"user_id" gorm:"not null;uniqueIndex:idx_workspace_member_workspace_user;index"`
+	User        User          `json:"user" gorm:"foreignKey:UserID"`
+	Role        WorkspaceRole `json:"role" gorm:"not null"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type CreateWorkspaceRequest struct {
+	Name               string `json:"name" binding:"required,max=100"`
+	DefaultPrivate     *bool  `json:"default_private"`
+	TrashRetentionDays int    `json:"trash_retention_days" binding:"omitempty,min=1,max=3650"`
+}
+
+type UpdateWorkspaceRequest struct {
+	Name               string `json:"name" binding:"omitempty,max=100"`
+	DefaultPrivate     *bool  `json:"default_private"`
+	TrashRetentionDays *int   `json:"trash_retention_days" binding:"omitempty,min=0,max=3650"`
+}
+
+type AddWorkspaceMemberRequest struct {
+	UserID uint          `json:"user_id" binding:"required"`
+	Role   WorkspaceRole `json:"role" binding:"omitempty,oneof=member admin"`
+}
+
+type UpdateWorkspaceMemberRequest struct {
+	Role WorkspaceRole `json:"role" binding:"required,oneof=member admin"`
+}
+
+type WorkspaceResponse struct {
+	ID                 uint          `json:"id"`
+	Name               string        `json:"name"`
+	OwnerID            uint          `json:"owner_id"`
+	DefaultPrivate     bool          `json:"default_private"`
+	TrashRetentionDays int           `json:"trash_retention_days"`
+	Role               WorkspaceRole `json:"role"`
+	MembersCount       int           `json:"members_count"`
+	CreatedAt          time.Time     `json:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at"`
+}
+
+type WorkspaceMemberResponse struct {
+	UserResponse
+	Role     WorkspaceRole `json:"role"`
+	JoinedAt time.Time     `json:"joined_at"`
+}
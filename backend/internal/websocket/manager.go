@@ -16,6 +16,8 @@ type Manager struct {
 	register       chan *types.WebSocketConnection
 	unregister     chan *types.WebSocketConnection
 	broadcast      chan *types.WebSocketMessage
+	kick           chan *types.ThreadKick
+	notify         chan *types.UserNotification
 	mutex          sync.RWMutex
 }
 
@@ -26,6 +28,8 @@ func NewManager() *Manager {
 		register:       make(chan *types.WebSocketConnection),
 		unregister:     make(chan *types.WebSocketConnection),
 		broadcast:      make(chan *types.WebSocketMessage),
+		kick:           make(chan *types.ThreadKick),
+		notify:         make(chan *types.UserNotification),
 	}
 }
 
@@ -55,6 +59,12 @@ func (m *Manager) Start() {
 
 		case message := <-m.broadcast:
 			m.handleMessage(message)
+
+		case kick := <-m.kick:
+			m.handleKick(kick)
+
+		case notification := <-m.notify:
+			m.handleNotification(notification)
 		}
 	}
 }
@@ -101,6 +111,22 @@ func (m *Manager) handleMessage(message *types.WebSocketMessage) {
 			}
 		}
 
+	case "note_read":
+		var readMsg types.NoteReadMessage
+		if data, err := json.Marshal(message.Payload); err == nil {
+			if err := json.Unmarshal(data, &readMsg); err == nil {
+				m.handleNoteRead(readMsg)
+			}
+		}
+
+	case "reaction_added", "reaction_removed":
+		var reactionMsg types.ReactionMessage
+		if data, err := json.Marshal(message.Payload); err == nil {
+			if err := json.Unmarshal(data, &reactionMsg); err == nil {
+				m.handleReaction(message.Type, reactionMsg)
+			}
+		}
+
 	case "user_typing":
 		var typingMsg types.UserTypingMessage
 		if data, err := json.Marshal(message.Payload); err == nil {
@@ -161,6 +187,71 @@ func (m *Manager) handleThreadLeave(msg types.ThreadLeaveMessage) {
 	}, msg.UserID)
 }
 
+// handleKick drops a user from a thread session, tells them why and lets the
+// remaining users know they left.
+func (m *Manager) handleKick(kick *types.ThreadKick) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	session, exists := m.threadSessions[kick.ThreadID]
+	if !exists {
+		return
+	}
+	client, joined := session.Users[kick.UserID]
+	if !joined {
+		return
+	}
+
+	delete(session.Users, kick.UserID)
+	if len(session.Users) == 0 {
+		delete(m.threadSessions, kick.ThreadID)
+	}
+
+	if conn, ok := client.Conn.(*websocket.Conn); ok {
+		data, err := json.Marshal(&types.WebSocketMessage{
+			Type:    "thread_removed",
+			Payload: kick,
+		})
+		if err == nil {
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				log.Printf("Error sending message to user %d: %v", kick.UserID, err)
+			}
+		}
+	}
+
+	m.broadcastToThread(kick.ThreadID, &types.WebSocketMessage{
+		Type: "user_left",
+		Payload: map[string]interface{}{
+			"thread_id": kick.ThreadID,
+			"user_id":   kick.UserID,
+		},
+	}, kick.UserID)
+}
+
+// handleNotification sends a message to one user, wherever they are in the
+// app.
+func (m *Manager) handleNotification(notification *types.UserNotification) {
+	m.mutex.RLock()
+	client, exists := m.clients[notification.UserID]
+	m.mutex.RUnlock()
+	if !exists {
+		return
+	}
+
+	conn, ok := client.Conn.(*websocket.Conn)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(notification.Message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		log.Printf("Error sending message to user %d: %v", notification.UserID, err)
+	}
+}
+
 func (m *Manager) handleNoteAdd(msg types.NoteAddMessage) {
 	m.broadcastToThread(msg.ThreadID, &types.WebSocketMessage{
 		Type:    "note_added",
@@ -185,7 +276,34 @@ func (m *Manager) handleNoteDelete(msg types.NoteDeleteMessage) {
 	}, 0)
 }
 
+func (m *Manager) handleNoteRead(msg types.NoteReadMessage) {
+	m.broadcastToThread(msg.ThreadID, &types.WebSocketMessage{
+		Type:    "note_read",
+		Payload: msg,
+	}, msg.UserID)
+}
+
+func (m *Manager) handleReaction(messageType string, msg types.ReactionMessage) {
+	m.broadcastToThread(msg.ThreadID, &types.WebSocketMessage{
+		Type:    messageType,
+		Payload: msg,
+	}, 0)
+}
+
+// handleUserTyping passes the typing indicator on to the thread's session,
+// but only from a user who has joined it.
 func (m *Manager) handleUserTyping(msg types.UserTypingMessage) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	session, exists := m.threadSessions[msg.ThreadID]
+	if !exists {
+		return
+	}
+	if _, joined := session.Users[msg.UserID]; !joined {
+		return
+	}
+
 	m.broadcastToThread(msg.ThreadID, &types.WebSocketMessage{
 		Type: "user_typing",
 		Payload: map[string]interface{}{
@@ -231,6 +349,73 @@ func (m *Manager) BroadcastMessage(message *types.WebSocketMessage) {
 	m.broadcast <- message
 }
 
+// BroadcastNoteRead tells the thread's live session that a member has read
+// it up to a note.
+func (m *Manager) BroadcastNoteRead(msg *types.NoteReadMessage) {
+	m.broadcast <- &types.WebSocketMessage{
+		Type:    "note_read",
+		Payload: msg,
+	}
+}
+
+// BroadcastNoteAdded tells the thread's live session about a new note.
+// Replies carry the ID of the note they reply to.
+func (m *Manager) BroadcastNoteAdded(msg *types.NoteAddMessage) {
+	m.broadcast <- &types.WebSocketMessage{
+		Type:    "note_add",
+		Payload: msg,
+	}
+}
+
+// BroadcastNoteUpdated tells the thread's live session that a note was
+// edited, with its new version.
+func (m *Manager) BroadcastNoteUpdated(msg *types.NoteUpdateMessage) {
+	m.broadcast <- &types.WebSocketMessage{
+		Type:    "note_update",
+		Payload: msg,
+	}
+}
+
+// BroadcastReactionAdded tells the thread's live session that someone
+// reacted to a note.
+func (m *Manager) BroadcastReactionAdded(msg *types.ReactionMessage) {
+	m.broadcast <- &types.WebSocketMessage{
+		Type:    "reaction_added",
+		Payload: msg,
+	}
+}
+
+// BroadcastReactionRemoved tells the thread's live session that someone took
+// back a reaction.
+func (m *Manager) BroadcastReactionRemoved(msg *types.ReactionMessage) {
+	m.broadcast <- &types.WebSocketMessage{
+		Type:    "reaction_removed",
+		Payload: msg,
+	}
+}
+
+// NotifyReply tells the author of a note that someone replied to it, if they
+// are connected.
+func (m *Manager) NotifyReply(userID uint, msg *types.NoteReplyMessage) {
+	m.notify <- &types.UserNotification{
+		UserID: userID,
+		Message: &types.WebSocketMessage{
+			Type:    "note_replied",
+			Payload: msg,
+		},
+	}
+}
+
+// RemoveUserFromThread kicks the user out of the thread's live session, e.g.
+// after they were removed as a collaborator. reason is passed to the client.
+func (m *Manager) RemoveUserFromThread(threadID, userID uint, reason string) {
+	m.kick <- &types.ThreadKick{
+		ThreadID: threadID,
+		UserID:   userID,
+		Reason:   reason,
+	}
+}
+
 // Global manager instance
 var globalManager *Manager
 
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// AccessTokenPrefix marks personal access tokens so they can be told apart
+// from JWTs without a database lookup.
+const AccessTokenPrefix = "mmw_pat_"
+
+// GenerateSecret returns a random URL safe secret and the hash that should be
+// persisted in its place.
+func GenerateSecret() (secret, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	secret = base64.RawURLEncoding.EncodeToString(buf)
+	return secret, HashToken(secret), nil
+}
+
+// GenerateAccessToken returns a new random personal access token together with
+// the short display prefix and the hash that should be persisted.
+func GenerateAccessToken() (token, displayPrefix, hash string, err error) {
+	secret, _, err := GenerateSecret()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	token = AccessTokenPrefix + secret
+	displayPrefix = token[:len(AccessTokenPrefix)+6]
+	return token, displayPrefix, HashToken(token), nil
+}
+
+// HashToken hashes a high entropy secret for storage and lookup.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// downloadAudience keeps download tokens and session tokens from being used
+// in place of each other.
+const downloadAudience = "download"
+
+// DownloadClaims authorize fetching a single resource without logging in.
+type DownloadClaims struct {
+	Resource string `json:"resource"`
+	jwt.RegisteredClaims
+}
+
+// GenerateDownloadToken signs a link token for resource that expires after ttl.
+func GenerateDownloadToken(resource string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := DownloadClaims{
+		Resource: resource,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Audience:  jwt.ClaimStrings{downloadAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token, err := DefaultKeyring().Sign(claims)
+	return token, expiresAt, err
+}
+
+// ValidateDownloadToken checks that the token is valid and was issued for resource.
+func ValidateDownloadToken(tokenString, resource string) error {
+	keyring := DefaultKeyring()
+	token, err := jwt.ParseWithClaims(tokenString, &DownloadClaims{}, keyring.Keyfunc,
+		jwt.WithValidMethods(keyring.ValidMethods()),
+		jwt.WithIssuer(Issuer),
+		jwt.WithAudience(downloadAudience),
+	)
+	if err != nil {
+		return err
+	}
+
+	claims, ok := token.Claims.(*DownloadClaims)
+	if !ok || !token.Valid || claims.ExpiresAt == nil || claims.Resource != resource {
+		return errors.New("invalid token")
+	}
+
+	return nil
+}
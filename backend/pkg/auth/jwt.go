@@ -4,11 +4,17 @@ import (
 	"errors"
 	"time"
 
+	"markmywords-backend/pkg/config"
+
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtSecret = []byte("your-secret-key-change-in-production")
+// TokenTTL is how long issued session tokens stay valid.
+const TokenTTL = 24 * time.Hour
+
+// Issuer is set as the iss claim so other services can verify our tokens.
+var Issuer = config.Get("JWT_ISSUER", "markmywords")
 
 type Claims struct {
 	UserID uint   `json:"user_id"`
@@ -16,25 +22,30 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uint, email string) (string, error) {
+// GenerateToken issues a session JWT. tokenID becomes the jti claim and ties
+// the token to its server-side session.
+func GenerateToken(userID uint, email, tokenID string) (string, error) {
 	claims := Claims{
 		UserID: userID,
 		Email:  email,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        tokenID,
+			Issuer:    Issuer,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return DefaultKeyring().Sign(claims)
 }
 
 func ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	keyring := DefaultKeyring()
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyring.Keyfunc,
+		jwt.WithValidMethods(keyring.ValidMethods()),
+		jwt.WithIssuer(Issuer),
+	)
 
 	if err != nil {
 		return nil, err
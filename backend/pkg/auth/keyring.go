@@ -0,0 +1,483 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"markmywords-backend/pkg/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// reloadInterval limits how often an unknown kid reloads keys from the store.
+const reloadInterval = 30 * time.Second
+
+// sealedPrefix marks a private key encrypted with the key encryption secret.
+const sealedPrefix = "sealed:v1:"
+
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	createdAt time.Time
+	retiredAt *time.Time
+}
+
+// Keyring holds the asymmetric keys used to sign and verify tokens. Keys are
+// persisted so every server instance signs and verifies with the same set;
+// the private keys are stored encrypted.
+type Keyring struct {
+	store      KeyStore
+	algorithm  string
+	aead       cipher.AEAD
+	mutex      sync.RWMutex
+	keys       map[string]*signingKey
+	active     *signingKey
+	loadedAt   time.Time
+	reloadLock sync.Mutex
+}
+
+// JWK is the public part of a signing key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var defaultKeyring *Keyring
+
+// InitKeyring loads the process wide keyring from the keys stored in db,
+// with the algorithm and secret from the environment. It must be called
+// before tokens are signed or verified.
+func InitKeyring(db *gorm.DB) (*Keyring, error) {
+	keyring, err := NewKeyring(NewKeyStore(db), config.Get("JWT_SIGNING_ALG", "RS256"), config.Get("KEY_ENCRYPTION_SECRET", ""))
+	if err != nil {
+		return nil, err
+	}
+	defaultKeyring = keyring
+	return keyring, nil
+}
+
+// DefaultKeyring returns the process wide keyring loaded by InitKeyring.
+func DefaultKeyring() *Keyring {
+	return defaultKeyring
+}
+
+// NewKeyring loads the stored keys, creating a first key when none is
+// active. secret encrypts the private keys in the store.
+func NewKeyring(store KeyStore, algorithm, secret string) (*Keyring, error) {
+	if algorithm != "RS256" && algorithm != "EdDSA" {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if secret == "" {
+		return nil, errors.New("KEY_ENCRYPTION_SECRET is required to protect the signing keys")
+	}
+
+	// AES-256-GCM keyed by the secret's SHA-256
+	sum := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &Keyring{store: store, algorithm: algorithm, aead: aead}
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+
+	k.mutex.RLock()
+	needsKey := k.active == nil || k.active.method.Alg() != algorithm
+	k.mutex.RUnlock()
+
+	if needsKey {
+		if err := k.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+// Sign signs the claims with the active key and sets the kid header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	active, err := k.activeKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.kid
+	return token.SignedString(active.private)
+}
+
+// Keyfunc resolves the verification key for a token by its kid header.
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid")
+	}
+
+	key := k.lookup(kid)
+	if key == nil {
+		// Another instance may have rotated since we last loaded
+		k.reloadIfStale()
+		key = k.lookup(kid)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("signing method mismatch")
+	}
+	return key.private.Public(), nil
+}
+
+// activeKey returns the key to sign with. Another instance may have rotated
+// since we loaded, so a key the database has retired is never used; the keys
+// are reloaded instead.
+func (k *Keyring) activeKey() (*signingKey, error) {
+	k.mutex.RLock()
+	active := k.active
+	k.mutex.RUnlock()
+
+	if active != nil {
+		retired, err := k.store.Retired(active.kid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check signing key: %w", err)
+		}
+		if !retired {
+			return active, nil
+		}
+	}
+
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+
+	k.mutex.RLock()
+	active = k.active
+	k.mutex.RUnlock()
+
+	if active == nil {
+		return nil, errors.New("no active signing key")
+	}
+	return active, nil
+}
+
+// ValidMethods lists the algorithms tokens may be signed with.
+func (k *Keyring) ValidMethods() []string {
+	return []string{"RS256", "EdDSA"}
+}
+
+// Rotate creates a new active key and retires the previous one. Retired keys
+// keep verifying until every token they signed has expired.
+func (k *Keyring) Rotate() error {
+	key, pemData, err := generateKey(k.algorithm)
+	if err != nil {
+		return err
+	}
+	sealed, err := k.seal(key.kid, pemData)
+	if err != nil {
+		return err
+	}
+
+	record := KeyRecord{
+		Kid:        key.kid,
+		Algorithm:  k.algorithm,
+		PrivateKey: sealed,
+		CreatedAt:  time.Now(),
+	}
+	if err := k.store.Activate(&record); err != nil {
+		return err
+	}
+
+	log.Printf("Rotated JWT signing key, new kid=%s", key.kid)
+	return k.load()
+}
+
+// PruneExpired deletes retired keys that can no longer have valid tokens.
+func (k *Keyring) PruneExpired() error {
+	if err := k.store.Prune(time.Now().Add(-TokenTTL)); err != nil {
+		return err
+	}
+	return k.load()
+}
+
+// RunRotation rotates the active key once it is older than interval and
+// prunes expired keys. It checks on a fraction of the interval so a restart
+// does not postpone a due rotation.
+func (k *Keyring) RunRotation(interval time.Duration) {
+	check := interval / 24
+	if check < time.Minute {
+		check = time.Minute
+	}
+
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := k.load(); err != nil {
+			log.Printf("Failed to reload signing keys: %v", err)
+			continue
+		}
+
+		k.mutex.RLock()
+		due := k.active == nil || time.Since(k.active.createdAt) >= interval
+		k.mutex.RUnlock()
+
+		if due {
+			if err := k.Rotate(); err != nil {
+				log.Printf("Failed to rotate signing key: %v", err)
+			}
+		}
+		if err := k.PruneExpired(); err != nil {
+			log.Printf("Failed to prune signing keys: %v", err)
+		}
+	}
+}
+
+// PublicJWKS returns the public keys of every key that can still verify tokens.
+func (k *Keyring) PublicJWKS() JWKS {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	keys := make([]*signingKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].createdAt.After(keys[j].createdAt)
+	})
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range keys {
+		jwk := JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+func (k *Keyring) lookup(kid string) *signingKey {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil
+	}
+	if key.retiredAt != nil && time.Since(*key.retiredAt) > TokenTTL {
+		return nil
+	}
+	return key
+}
+
+func (k *Keyring) reloadIfStale() {
+	k.reloadLock.Lock()
+	defer k.reloadLock.Unlock()
+
+	k.mutex.RLock()
+	stale := time.Since(k.loadedAt) > reloadInterval
+	k.mutex.RUnlock()
+
+	if stale {
+		if err := k.load(); err != nil {
+			log.Printf("Failed to reload signing keys: %v", err)
+		}
+	}
+}
+
+func (k *Keyring) load() error {
+	records, err := k.store.Load(time.Now().Add(-TokenTTL))
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey)
+	var active *signingKey
+	for _, record := range records {
+		key, err := k.parseKey(record)
+		if err != nil && record.RetiredAt == nil {
+			// Rotating over it would lock out the instances that can read it
+			return fmt.Errorf("cannot read active signing key %s, check KEY_ENCRYPTION_SECRET: %w", record.Kid, err)
+		}
+		if err != nil {
+			log.Printf("Skipping unreadable signing key %s: %v", record.Kid, err)
+			continue
+		}
+		if !strings.HasPrefix(record.PrivateKey, sealedPrefix) {
+			k.sealStored(record)
+		}
+		keys[key.kid] = key
+		if key.retiredAt == nil {
+			active = key
+		}
+	}
+
+	k.mutex.Lock()
+	k.keys = keys
+	k.active = active
+	k.loadedAt = time.Now()
+	k.mutex.Unlock()
+
+	return nil
+}
+
+func generateKey(algorithm string) (*signingKey, string, error) {
+	var private crypto.Signer
+	var method jwt.SigningMethod
+	var err error
+
+	switch algorithm {
+	case "EdDSA":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+		method = jwt.SigningMethodEdDSA
+	default:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+		method = jwt.SigningMethodRS256
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, "", err
+	}
+
+	kidBytes := make([]byte, 8)
+	if _, err := rand.Read(kidBytes); err != nil {
+		return nil, "", err
+	}
+
+	key := &signingKey{
+		kid:       hex.EncodeToString(kidBytes),
+		method:    method,
+		private:   private,
+		createdAt: time.Now(),
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// seal encrypts a PEM private key for storage, bound to its kid.
+func (k *Keyring) seal(kid, pemData string) (string, error) {
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := k.aead.Seal(nonce, nonce, []byte(pemData), []byte(kid))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// unseal returns the PEM of a stored private key. Keys stored before
+// encryption was introduced are plain PEM.
+func (k *Keyring) unseal(record KeyRecord) (string, error) {
+	encoded, ok := strings.CutPrefix(record.PrivateKey, sealedPrefix)
+	if !ok {
+		return record.PrivateKey, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < k.aead.NonceSize() {
+		return "", errors.New("invalid sealed key")
+	}
+	nonce, ciphertext := sealed[:k.aead.NonceSize()], sealed[k.aead.NonceSize():]
+	pemData, err := k.aead.Open(nil, nonce, ciphertext, []byte(record.Kid))
+	if err != nil {
+		return "", errors.New("cannot decrypt key")
+	}
+	return string(pemData), nil
+}
+
+// sealStored encrypts a key that was stored in plain PEM.
+func (k *Keyring) sealStored(record KeyRecord) {
+	sealed, err := k.seal(record.Kid, record.PrivateKey)
+	if err == nil {
+		err = k.store.Replace(record.Kid, record.PrivateKey, sealed)
+	}
+	if err != nil {
+		log.Printf("Failed to encrypt signing key %s: %v", record.Kid, err)
+		return
+	}
+	log.Printf("Encrypted signing key %s at rest", record.Kid)
+}
+
+func (k *Keyring) parseKey(record KeyRecord) (*signingKey, error) {
+	pemData, err := k.unseal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, errors.New("invalid PEM")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{
+		kid:       record.Kid,
+		createdAt: record.CreatedAt,
+		retiredAt: record.RetiredAt,
+	}
+
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.private = private
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		key.private = private
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testSecret = "correct horse battery staple"
+
+func newKeyDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "keys.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(&KeyRecord{}); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func newTestKeyring(t *testing.T, db *gorm.DB) *Keyring {
+	t.Helper()
+
+	keyring, err := NewKeyring(NewKeyStore(db), "EdDSA", testSecret)
+	if err != nil {
+		t.Fatalf("new keyring: %v", err)
+	}
+	return keyring
+}
+
+// signedKid signs a token and returns the kid it was signed with, after
+// checking it verifies.
+func signedKid(t *testing.T, keyring *Keyring) string {
+	t.Helper()
+
+	signed, err := keyring.Sign(jwt.RegisteredClaims{Subject: "1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))})
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	token, err := jwt.Parse(signed, keyring.Keyfunc, jwt.WithValidMethods(keyring.ValidMethods()))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	return token.Header["kid"].(string)
+}
+
+func activeRecord(t *testing.T, db *gorm.DB) KeyRecord {
+	t.Helper()
+
+	var record KeyRecord
+	if err := db.Where("retired_at IS NULL").First(&record).Error; err != nil {
+		t.Fatalf("load active key: %v", err)
+	}
+	return record
+}
+
+func TestNewKeyringRequiresSecret(t *testing.T) {
+	if _, err := NewKeyring(NewKeyStore(newKeyDB(t)), "EdDSA", ""); err == nil {
+		t.Fatal("a keyring was created without a key encryption secret")
+	}
+}
+
+func TestKeysAreEncryptedAtRest(t *testing.T) {
+	db := newKeyDB(t)
+	keyring := newTestKeyring(t, db)
+
+	record := activeRecord(t, db)
+	if !strings.HasPrefix(record.PrivateKey, sealedPrefix) || strings.Contains(record.PrivateKey, "PRIVATE KEY") {
+		t.Fatalf("stored key is not encrypted: %.40q", record.PrivateKey)
+	}
+	if kid := signedKid(t, keyring); kid != record.Kid {
+		t.Fatalf("signed with %s, want %s", kid, record.Kid)
+	}
+
+	// Another instance with the wrong secret must not rotate over the key
+	if _, err := NewKeyring(NewKeyStore(db), "EdDSA", "wrong secret"); err == nil {
+		t.Fatal("a keyring loaded keys with the wrong secret")
+	}
+	var count int64
+	db.Model(&KeyRecord{}).Count(&count)
+	if count != 1 || activeRecord(t, db).Kid != record.Kid {
+		t.Fatal("the wrong secret replaced the active key")
+	}
+}
+
+func TestPlainKeysAreEncryptedOnLoad(t *testing.T) {
+	db := newKeyDB(t)
+
+	key, pemData, err := generateKey("EdDSA")
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	db.Create(&KeyRecord{Kid: key.kid, Algorithm: "EdDSA", PrivateKey: pemData, CreatedAt: time.Now()})
+
+	keyring := newTestKeyring(t, db)
+	if kid := signedKid(t, keyring); kid != key.kid {
+		t.Fatalf("signed with %s, want the existing key %s", kid, key.kid)
+	}
+	if record := activeRecord(t, db); !strings.HasPrefix(record.PrivateKey, sealedPrefix) {
+		t.Fatal("the plain key was left unencrypted")
+	}
+}
+
+func TestSignFollowsRotationElsewhere(t *testing.T) {
+	db := newKeyDB(t)
+	first := newTestKeyring(t, db)
+	second := newTestKeyring(t, db)
+
+	before := signedKid(t, first)
+	if err := second.Rotate(); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	rotated := activeRecord(t, db).Kid
+	if rotated == before {
+		t.Fatal("rotation kept the key")
+	}
+
+	if kid := signedKid(t, first); kid != rotated {
+		t.Fatalf("signed with %s after the rotation, want %s", kid, rotated)
+	}
+}
+
+func TestSignRefusesRetiredKey(t *testing.T) {
+	db := newKeyDB(t)
+	keyring := newTestKeyring(t, db)
+
+	db.Model(&KeyRecord{}).Where("retired_at IS NULL").Update("retired_at", time.Now())
+	if _, err := keyring.Sign(jwt.RegisteredClaims{Subject: "1"}); err == nil {
+		t.Fatal("signed with a retired key")
+	}
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// KeyRecord is a signing key as stored. Only the newest key signs new tokens;
+// retired keys are kept for verification until tokens signed with them expire.
+type KeyRecord struct {
+	Kid        string     `json:"kid" gorm:"primaryKey"`
+	Algorithm  string     `json:"algorithm" gorm:"not null"`
+	PrivateKey string     `json:"-" gorm:"not null"` // PKCS#8 PEM, encrypted
+	CreatedAt  time.Time  `json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at" gorm:"index"`
+}
+
+func (KeyRecord) TableName() string {
+	return "signing_keys"
+}
+
+// KeyStore persists the keyring's keys, shared by every server instance.
+type KeyStore interface {
+	// Load returns the active key and the keys retired since the cutoff,
+	// oldest first.
+	Load(retiredSince time.Time) ([]KeyRecord, error)
+	// Retired reports whether the key has been retired or deleted.
+	Retired(kid string) (bool, error)
+	// Activate retires the active key and stores the record in its place.
+	Activate(record *KeyRecord) error
+	// Prune deletes the keys retired before the cutoff.
+	Prune(retiredBefore time.Time) error
+	// Replace swaps a key's stored private key, unless it has changed since.
+	Replace(kid, from, to string) error
+}
+
+// NewKeyStore keeps the keys in the signing_keys table of db.
+func NewKeyStore(db *gorm.DB) KeyStore {
+	return &dbKeyStore{db: db}
+}
+
+type dbKeyStore struct {
+	db *gorm.DB
+}
+
+func (s *dbKeyStore) Load(retiredSince time.Time) ([]KeyRecord, error) {
+	var records []KeyRecord
+	err := s.db.Where("retired_at IS NULL OR retired_at >= ?", retiredSince).
+		Order("created_at ASC").
+		Find(&records).Error
+	return records, err
+}
+
+func (s *dbKeyStore) Retired(kid string) (bool, error) {
+	var record KeyRecord
+	err := s.db.Select("kid", "retired_at").Where("kid = ?", kid).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return record.RetiredAt != nil, nil
+}
+
+func (s *dbKeyStore) Activate(record *KeyRecord) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&KeyRecord{}).Where("retired_at IS NULL").Update("retired_at", record.CreatedAt).Error; err != nil {
+			return err
+		}
+		return tx.Create(record).Error
+	})
+}
+
+func (s *dbKeyStore) Prune(retiredBefore time.Time) error {
+	return s.db.Where("retired_at IS NOT NULL AND retired_at < ?", retiredBefore).Delete(&KeyRecord{}).Error
+}
+
+func (s *dbKeyStore) Replace(kid, from, to string) error {
+	return s.db.Model(&KeyRecord{}).Where("kid = ? AND private_key = ?", kid, from).
+		Update("private_key", to).Error
+}
@@ -8,6 +8,7 @@ import (
 	"gorm.io/gorm/logger"
 
 	"markmywords-backend/internal/types"
+	"markmywords-backend/pkg/auth"
 )
 
 var DB *gorm.DB
@@ -26,6 +27,28 @@ func InitDB() {
 
 	// Drop existing tables to handle schema changes
 	log.Println("Dropping existing tables for schema migration...")
+	DB.Migrator().DropTable(&types.NoteReaction{})
+	DB.Migrator().DropTable(&types.NoteRevision{})
+	DB.Migrator().DropTable(&types.ThreadGroupGrant{})
+	DB.Migrator().DropTable(&types.UserGroupMember{})
+	DB.Migrator().DropTable(&types.UserGroup{})
+	DB.Migrator().DropTable(&types.WorkspaceMember{})
+	DB.Migrator().DropTable(&types.Workspace{})
+	DB.Migrator().DropTable(&types.Folder{})
+	DB.Migrator().DropTable(&types.ThreadLabel{})
+	DB.Migrator().DropTable(&types.Label{})
+	DB.Migrator().DropTable(&types.ThreadReadMarker{})
+	DB.Migrator().DropTable(&types.ThreadPreference{})
+	DB.Migrator().DropTable(&types.ShareLink{})
+	DB.Migrator().DropTable(&types.ThreadTransfer{})
+	DB.Migrator().DropTable(&types.ThreadActivity{})
+	DB.Migrator().DropTable(&types.DataExport{})
+	DB.Migrator().DropTable(&types.EmailChange{})
+	DB.Migrator().DropTable(&types.Session{})
+	DB.Migrator().DropTable(&auth.KeyRecord{})
+	DB.Migrator().DropTable(&types.OIDCLoginState{})
+	DB.Migrator().DropTable(&types.ExternalIdentity{})
+	DB.Migrator().DropTable(&types.PersonalAccessToken{})
 	DB.Migrator().DropTable(&types.Invite{})
 	DB.Migrator().DropTable(&types.Note{})
 	DB.Migrator().DropTable(&types.ThreadCollaborator{})
@@ -33,19 +56,44 @@ func InitDB() {
 	DB.Migrator().DropTable(&types.User{})
 
 	// Auto migrate the schema
-	err = DB.AutoMigrate(
+	if err := Migrate(DB); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	log.Println("Database connected and migrated successfully")
+}
+
+// Migrate creates or updates the tables of every model.
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(
 		&types.User{},
 		&types.Thread{},
 		&types.ThreadCollaborator{},
 		&types.Note{},
 		&types.Invite{},
+		&types.PersonalAccessToken{},
+		&types.ExternalIdentity{},
+		&types.OIDCLoginState{},
+		&auth.KeyRecord{},
+		&types.Session{},
+		&types.EmailChange{},
+		&types.DataExport{},
+		&types.ThreadActivity{},
+		&types.ThreadTransfer{},
+		&types.ShareLink{},
+		&types.ThreadPreference{},
+		&types.ThreadReadMarker{},
+		&types.Label{},
+		&types.ThreadLabel{},
+		&types.Folder{},
+		&types.Workspace{},
+		&types.WorkspaceMember{},
+		&types.UserGroup{},
+		&types.UserGroupMember{},
+		&types.ThreadGroupGrant{},
+		&types.NoteRevision{},
+		&types.NoteReaction{},
 	)
-
-	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
-	}
-
-	log.Println("Database connected and migrated successfully")
 }
 
 func GetDB() *gorm.DB {
@@ -0,0 +1,153 @@
+// Package diff compares two texts line by line or word by word.
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Op is what happened to a run of text going from the old text to the new.
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Change is a run of text that was kept, inserted or deleted.
+type Change struct {
+	Op   Op
+	Text string
+}
+
+// maxCells bounds the comparison table. Texts that differ in more than that
+// are reported as deleted and inserted as a whole.
+const maxCells = 4_000_000
+
+// Lines compares the texts line by line.
+func Lines(a, b string) []Change {
+	return compare(splitLines(a), splitLines(b))
+}
+
+// Words compares the texts word by word. Runs of whitespace count as words of
+// their own, so joining the changes gives back the texts.
+func Words(a, b string) []Change {
+	return compare(splitWords(a), splitWords(b))
+}
+
+// compare finds the longest common subsequence of the tokens and reports the
+// rest as deletions and insertions, merging neighbouring tokens with the same
+// outcome.
+func compare(a, b []string) []Change {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var changes []Change
+	add := func(op Op, text string) {
+		if text == "" {
+			return
+		}
+		if n := len(changes); n > 0 && changes[n-1].Op == op {
+			changes[n-1].Text += text
+			return
+		}
+		changes = append(changes, Change{Op: op, Text: text})
+	}
+
+	add(Equal, strings.Join(a[:prefix], ""))
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA)*len(midB) > maxCells {
+		add(Delete, strings.Join(midA, ""))
+		add(Insert, strings.Join(midB, ""))
+	} else {
+		for _, change := range lcs(midA, midB) {
+			add(change.Op, change.Text)
+		}
+	}
+
+	add(Equal, strings.Join(a[len(a)-suffix:], ""))
+	return changes
+}
+
+// lcs walks the longest common subsequence table of a and b, one change per
+// token.
+func lcs(a, b []string) []Change {
+	n, m := len(a), len(b)
+	width := m + 1
+	table := make([]int32, (n+1)*width)
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i*width+j] = table[(i+1)*width+j+1] + 1
+			} else if table[(i+1)*width+j] >= table[i*width+j+1] {
+				table[i*width+j] = table[(i+1)*width+j]
+			} else {
+				table[i*width+j] = table[i*width+j+1]
+			}
+		}
+	}
+
+	changes := make([]Change, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			changes = append(changes, Change{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case table[(i+1)*width+j] >= table[i*width+j+1]:
+			changes = append(changes, Change{Op: Delete, Text: a[i]})
+			i++
+		default:
+			changes = append(changes, Change{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		changes = append(changes, Change{Op: Delete, Text: a[i]})
+	}
+	for ; j < m; j++ {
+		changes = append(changes, Change{Op: Insert, Text: b[j]})
+	}
+	return changes
+}
+
+// splitLines splits text into lines, each keeping its line break.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// splitWords splits text into words and the whitespace between them.
+func splitWords(text string) []string {
+	var tokens []string
+	start := 0
+	for i, r := range text {
+		if i > start && unicode.IsSpace(r) != isSpaceAt(text, start) {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+func isSpaceAt(text string, i int) bool {
+	for _, r := range text[i:] {
+		return unicode.IsSpace(r)
+	}
+	return false
+}
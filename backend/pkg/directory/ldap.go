@@ -0,0 +1,221 @@
+package directory
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"markmywords-backend/pkg/config"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+var ErrEntryNotFound = errors.New("directory entry not found")
+
+// ErrAmbiguousEntry means the lookup matched more than one entry, so the
+// directory cannot say which account the credentials belong to.
+var ErrAmbiguousEntry = errors.New("ambiguous directory entry")
+
+// Config describes how to reach the LDAP / Active Directory server and which
+// attributes hold the user profile.
+type Config struct {
+	URL           string
+	StartTLS      bool
+	BindDN        string
+	BindPassword  string
+	BaseDN        string
+	UserFilter    string // must contain a single %s for the escaped email
+	EmailAttr     string
+	UsernameAttr  string
+	FirstNameAttr string
+	LastNameAttr  string
+	GroupAttr     string
+	GroupRoles    map[string]string // lower-cased group DN -> role
+	Timeout       time.Duration
+	SkipTLSVerify bool
+}
+
+// Entry is a user as seen by the directory.
+type Entry struct {
+	DN        string
+	Email     string
+	Username  string
+	FirstName string
+	LastName  string
+	Groups    []string
+}
+
+// LoadConfig reads the LDAP_* environment variables. It returns nil when no
+// directory is configured.
+func LoadConfig() *Config {
+	url := config.Get("LDAP_URL", "")
+	if url == "" {
+		return nil
+	}
+
+	cfg := &Config{
+		URL:           url,
+		StartTLS:      config.GetBool("LDAP_START_TLS", false),
+		BindDN:        config.Get("LDAP_BIND_DN", ""),
+		BindPassword:  config.Get("LDAP_BIND_PASSWORD", ""),
+		BaseDN:        config.Get("LDAP_BASE_DN", ""),
+		UserFilter:    config.Get("LDAP_USER_FILTER", "(&(objectClass=person)(mail=%s))"),
+		EmailAttr:     config.Get("LDAP_ATTR_EMAIL", "mail"),
+		UsernameAttr:  config.Get("LDAP_ATTR_USERNAME", "uid"),
+		FirstNameAttr: config.Get("LDAP_ATTR_FIRST_NAME", "givenName"),
+		LastNameAttr:  config.Get("LDAP_ATTR_LAST_NAME", "sn"),
+		GroupAttr:     config.Get("LDAP_ATTR_GROUPS", "memberOf"),
+		GroupRoles:    make(map[string]string),
+		Timeout:       config.GetDuration("LDAP_TIMEOUT", 10*time.Second),
+		SkipTLSVerify: config.GetBool("LDAP_SKIP_TLS_VERIFY", false),
+	}
+
+	// LDAP_GROUP_ROLES=cn=admins,ou=groups,dc=example,dc=com:admin;cn=staff,...:user
+	for _, mapping := range strings.Split(config.Get("LDAP_GROUP_ROLES", ""), ";") {
+		idx := strings.LastIndex(mapping, ":")
+		if idx <= 0 {
+			continue
+		}
+		group := strings.ToLower(strings.TrimSpace(mapping[:idx]))
+		cfg.GroupRoles[group] = strings.TrimSpace(mapping[idx+1:])
+	}
+
+	return cfg
+}
+
+type Client struct {
+	config *Config
+}
+
+func NewClient(cfg *Config) *Client {
+	return &Client{config: cfg}
+}
+
+// Authenticate looks the user up with the service account and then binds as
+// that user to check the password.
+func (c *Client) Authenticate(email, password string) (*Entry, error) {
+	if password == "" {
+		// An empty password would be an unauthenticated bind and always succeed
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := c.searchOne(conn, c.config.BaseDN, ldap.ScopeWholeSubtree,
+		fmt.Sprintf(c.config.UserFilter, ldap.EscapeFilter(email)))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// Lookup reads a single entry by DN using the service account.
+func (c *Client) Lookup(dn string) (*Entry, error) {
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return c.searchOne(conn, dn, ldap.ScopeBaseObject, "(objectClass=*)")
+}
+
+// RoleFor maps the entry's group memberships to an application role,
+// preferring admin when several mapped groups match.
+func (c *Client) RoleFor(entry *Entry, fallback string) string {
+	role := fallback
+	for _, group := range entry.Groups {
+		mapped, ok := c.config.GroupRoles[strings.ToLower(group)]
+		if !ok {
+			continue
+		}
+		if mapped == "admin" {
+			return mapped
+		}
+		role = mapped
+	}
+	return role
+}
+
+func (c *Client) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(c.config.URL, ldap.DialWithTLSConfig(&tls.Config{
+		InsecureSkipVerify: c.config.SkipTLSVerify,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("ldap connect failed: %w", err)
+	}
+	conn.SetTimeout(c.config.Timeout)
+
+	if c.config.StartTLS {
+		if err := conn.StartTLS(&tls.Config{InsecureSkipVerify: c.config.SkipTLSVerify}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls failed: %w", err)
+		}
+	}
+
+	if c.config.BindDN != "" {
+		if err := conn.Bind(c.config.BindDN, c.config.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap service bind failed: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *Client) searchOne(conn *ldap.Conn, baseDN string, scope int, filter string) (*Entry, error) {
+	attributes := []string{
+		c.config.EmailAttr,
+		c.config.UsernameAttr,
+		c.config.FirstNameAttr,
+		c.config.LastNameAttr,
+		c.config.GroupAttr,
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		baseDN, scope, ldap.NeverDerefAliases, 2, int(c.config.Timeout.Seconds()), false,
+		filter, attributes, nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrEntryNotFound
+		}
+		// The size limit of 2 is only ever hit by a second match
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, ErrAmbiguousEntry
+		}
+		return nil, err
+	}
+	if len(result.Entries) > 1 {
+		return nil, ErrAmbiguousEntry
+	}
+	if len(result.Entries) == 0 {
+		return nil, ErrEntryNotFound
+	}
+
+	entry := result.Entries[0]
+	return &Entry{
+		DN:        entry.DN,
+		Email:     entry.GetAttributeValue(c.config.EmailAttr),
+		Username:  entry.GetAttributeValue(c.config.UsernameAttr),
+		FirstName: entry.GetAttributeValue(c.config.FirstNameAttr),
+		LastName:  entry.GetAttributeValue(c.config.LastNameAttr),
+		Groups:    entry.GetAttributeValues(c.config.GroupAttr),
+	}, nil
+}
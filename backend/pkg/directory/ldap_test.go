@@ -0,0 +1,145 @@
+package directory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"markmywords-backend/pkg/directory/ldaptest"
+)
+
+const (
+	serviceDN = "cn=service,dc=example,dc=com"
+	adminsDN  = "cn=admins,ou=groups,dc=example,dc=com"
+	staffDN   = "cn=staff,ou=groups,dc=example,dc=com"
+)
+
+func person(uid, email, password string, groups ...string) ldaptest.Entry {
+	return ldaptest.Entry{
+		DN:       "uid=" + uid + ",ou=people,dc=example,dc=com",
+		Password: password,
+		Attributes: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {uid},
+			"mail":        {email},
+			"givenName":   {uid},
+			"sn":          {"Tester"},
+			"memberOf":    groups,
+		},
+	}
+}
+
+func newTestClient(t *testing.T, entries ...ldaptest.Entry) (*ldaptest.Server, *Client) {
+	t.Helper()
+
+	entries = append(entries, ldaptest.Entry{DN: serviceDN, Password: "service-secret"})
+	server, err := ldaptest.NewServer(entries...)
+	if err != nil {
+		t.Fatalf("start directory: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	return server, NewClient(&Config{
+		URL:           server.URL,
+		BindDN:        serviceDN,
+		BindPassword:  "service-secret",
+		BaseDN:        "dc=example,dc=com",
+		UserFilter:    "(&(objectClass=person)(mail=%s))",
+		EmailAttr:     "mail",
+		UsernameAttr:  "uid",
+		FirstNameAttr: "givenName",
+		LastNameAttr:  "sn",
+		GroupAttr:     "memberOf",
+		GroupRoles:    map[string]string{adminsDN: "admin", staffDN: "user"},
+		Timeout:       5 * time.Second,
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	_, client := newTestClient(t, person("ada", "ada@example.com", "ada-secret", staffDN))
+
+	entry, err := client.Authenticate("ada@example.com", "ada-secret")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if entry.Username != "ada" || entry.Email != "ada@example.com" || len(entry.Groups) != 1 {
+		t.Fatalf("entry = %+v", entry)
+	}
+
+	if _, err := client.Authenticate("ada@example.com", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := client.Authenticate("ada@example.com", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("empty password: err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := client.Authenticate("nobody@example.com", "secret"); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("unknown email: err = %v, want ErrEntryNotFound", err)
+	}
+}
+
+func TestAuthenticateRefusesAmbiguousEntries(t *testing.T) {
+	cases := []struct {
+		name    string
+		entries []ldaptest.Entry
+	}{
+		{"two matches", []ldaptest.Entry{
+			person("ada", "ada@example.com", "ada-secret"),
+			person("ada2", "ada@example.com", "other-secret"),
+		}},
+		// The third match makes the directory stop at the size limit
+		{"size limit exceeded", []ldaptest.Entry{
+			person("ada", "ada@example.com", "ada-secret"),
+			person("ada2", "ada@example.com", "other-secret"),
+			person("ada3", "ada@example.com", "third-secret"),
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, client := newTestClient(t, tc.entries...)
+			if _, err := client.Authenticate("ada@example.com", "ada-secret"); !errors.Is(err, ErrAmbiguousEntry) {
+				t.Fatalf("err = %v, want ErrAmbiguousEntry", err)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	ada := person("ada", "ada@example.com", "ada-secret")
+	_, client := newTestClient(t, ada)
+
+	entry, err := client.Lookup(ada.DN)
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if entry.DN != ada.DN || entry.Email != "ada@example.com" {
+		t.Fatalf("entry = %+v", entry)
+	}
+
+	if _, err := client.Lookup("uid=gone,ou=people,dc=example,dc=com"); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("missing entry: err = %v, want ErrEntryNotFound", err)
+	}
+}
+
+func TestRoleFor(t *testing.T) {
+	client := NewClient(&Config{GroupRoles: map[string]string{adminsDN: "admin", staffDN: "user"}})
+
+	cases := []struct {
+		name   string
+		groups []string
+		want   string
+	}{
+		{"no groups", nil, "viewer"},
+		{"unmapped group", []string{"cn=others,ou=groups,dc=example,dc=com"}, "viewer"},
+		{"mapped group", []string{staffDN}, "user"},
+		{"group DNs ignore case", []string{"CN=Staff,OU=Groups,DC=Example,DC=Com"}, "user"},
+		{"admin wins", []string{staffDN, adminsDN}, "admin"},
+		{"admin wins in any order", []string{adminsDN, staffDN}, "admin"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := client.RoleFor(&Entry{Groups: tc.groups}, "viewer"); got != tc.want {
+				t.Fatalf("role = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
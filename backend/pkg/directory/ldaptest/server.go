@@ -0,0 +1,286 @@
+// Package ldaptest provides an in-process LDAP server holding a handful of
+// entries, enough for simple binds and searches in tests.
+package ldaptest
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// LDAP protocol operations and result codes the server speaks.
+const (
+	opBindRequest      = 0
+	opBindResponse     = 1
+	opUnbindRequest    = 2
+	opSearchRequest    = 3
+	opSearchEntry      = 4
+	opSearchDone       = 5
+	resultSuccess      = 0
+	resultProtocol     = 2
+	resultSizeLimit    = 4
+	resultNoSuchObject = 32
+	resultInvalidCreds = 49
+)
+
+const (
+	scopeBaseObject = 0
+	scopeSubtree    = 2
+)
+
+// Entry is a directory entry. Password, when set, lets clients bind as it.
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server answers simple binds and base or subtree searches over its entries.
+// Searches honour the request size limit and report sizeLimitExceeded like a
+// real directory.
+type Server struct {
+	// URL is the ldap:// address to dial.
+	URL string
+
+	listener net.Listener
+	mutex    sync.Mutex
+	entries  []Entry
+	conns    sync.WaitGroup
+}
+
+// NewServer starts a server on a local port. Close it when done.
+func NewServer(entries ...Entry) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		URL:      "ldap://" + listener.Addr().String(),
+		listener: listener,
+		entries:  entries,
+	}
+	go s.serve()
+	return s, nil
+}
+
+// Add stores another entry.
+func (s *Server) Add(entry Entry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries = append(s.entries, entry)
+}
+
+// Remove deletes the entry with the DN.
+func (s *Server) Remove(dn string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := range s.entries {
+		if strings.EqualFold(s.entries[i].DN, dn) {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// Close stops accepting connections and waits for open ones to finish.
+func (s *Server) Close() {
+	s.listener.Close()
+	s.conns.Wait()
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.conns.Add(1)
+		go func() {
+			defer s.conns.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case opBindRequest:
+			conn.Write(result(messageID, opBindResponse, s.bind(op)).Bytes())
+		case opSearchRequest:
+			for _, response := range s.search(messageID, op) {
+				conn.Write(response.Bytes())
+			}
+		case opUnbindRequest:
+			return
+		default:
+			// Extended operations such as StartTLS are not supported
+			conn.Write(result(messageID, 24, resultProtocol).Bytes())
+		}
+	}
+}
+
+func (s *Server) bind(op *ber.Packet) int {
+	if len(op.Children) < 3 {
+		return resultProtocol
+	}
+	dn, _ := op.Children[1].Value.(string)
+	password := op.Children[2].Data.String()
+	if dn == "" && password == "" {
+		return resultSuccess
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, dn) && entry.Password != "" && entry.Password == password {
+			return resultSuccess
+		}
+	}
+	return resultInvalidCreds
+}
+
+func (s *Server) search(messageID int64, op *ber.Packet) []*ber.Packet {
+	if len(op.Children) < 7 {
+		return []*ber.Packet{result(messageID, opSearchDone, resultProtocol)}
+	}
+	baseDN, _ := op.Children[0].Value.(string)
+	scope, _ := op.Children[1].Value.(int64)
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var matches []Entry
+	found := false
+	for _, entry := range s.entries {
+		var inScope bool
+		switch scope {
+		case scopeBaseObject:
+			inScope = strings.EqualFold(entry.DN, baseDN)
+		case scopeSubtree:
+			inScope = strings.HasSuffix(strings.ToLower(entry.DN), strings.ToLower(baseDN))
+		}
+		if !inScope {
+			continue
+		}
+		found = true
+		if matchFilter(entry, filter) {
+			matches = append(matches, entry)
+		}
+	}
+	if scope == scopeBaseObject && !found {
+		return []*ber.Packet{result(messageID, opSearchDone, resultNoSuchObject)}
+	}
+
+	code := resultSuccess
+	if sizeLimit > 0 && int64(len(matches)) > sizeLimit {
+		matches = matches[:sizeLimit]
+		code = resultSizeLimit
+	}
+
+	responses := make([]*ber.Packet, 0, len(matches)+1)
+	for _, entry := range matches {
+		responses = append(responses, searchEntry(messageID, entry))
+	}
+	return append(responses, result(messageID, opSearchDone, code))
+}
+
+// matchFilter evaluates the filter kinds the directory client sends: and, or,
+// not, equality and presence. Values compare case-insensitively.
+func matchFilter(entry Entry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case 0: // and
+		for _, child := range filter.Children {
+			if !matchFilter(entry, child) {
+				return false
+			}
+		}
+		return true
+	case 1: // or
+		for _, child := range filter.Children {
+			if matchFilter(entry, child) {
+				return true
+			}
+		}
+		return false
+	case 2: // not
+		return len(filter.Children) == 1 && !matchFilter(entry, filter.Children[0])
+	case 3: // equalityMatch
+		if len(filter.Children) != 2 {
+			return false
+		}
+		name, _ := filter.Children[0].Value.(string)
+		value, _ := filter.Children[1].Value.(string)
+		for _, candidate := range attribute(entry, name) {
+			if strings.EqualFold(candidate, value) {
+				return true
+			}
+		}
+		return false
+	case 7: // present
+		name := filter.Data.String()
+		return strings.EqualFold(name, "objectClass") || len(attribute(entry, name)) > 0
+	}
+	return false
+}
+
+func attribute(entry Entry, name string) []string {
+	for attr, values := range entry.Attributes {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func envelope(messageID int64) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	return packet
+}
+
+func result(messageID int64, op ber.Tag, code int) *ber.Packet {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, op, nil, "Result")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+
+	packet := envelope(messageID)
+	packet.AppendChild(response)
+	return packet
+}
+
+func searchEntry(messageID int64, entry Entry) *ber.Packet {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opSearchEntry, nil, "Search Result Entry")
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, values := range entry.Attributes {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attr.AppendChild(set)
+		attributes.AppendChild(attr)
+	}
+	response.AppendChild(attributes)
+
+	packet := envelope(messageID)
+	packet.AppendChild(response)
+	return packet
+}
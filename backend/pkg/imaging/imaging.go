@@ -0,0 +1,104 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxPixels bounds decoded images to protect against decompression bombs.
+const MaxPixels = 40_000_000
+
+var ErrUnsupportedFormat = errors.New("unsupported image format, use JPEG, PNG, GIF or WebP")
+
+var supportedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Rendition is one encoded size of a processed image.
+type Rendition struct {
+	Size        int
+	Data        []byte
+	ContentType string
+	Extension   string
+}
+
+// SquareRenditions decodes an uploaded image, applies its EXIF orientation,
+// crops it to a centered square and re-encodes it at each size. Re-encoding
+// drops all metadata, including EXIF location data. Images with transparency
+// are encoded as PNG and everything else as JPEG.
+func SquareRenditions(data []byte, sizes []int) ([]Rendition, error) {
+	// Trust the bytes, not the client supplied content type
+	if !supportedTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedFormat
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, fmt.Errorf("image dimensions %dx%d are too large", cfg.Width, cfg.Height)
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if format == "jpeg" {
+		src = applyOrientation(src, jpegOrientation(data))
+	}
+
+	square := cropSquare(src)
+	transparent := !square.Opaque()
+
+	var renditions []Rendition
+	for _, size := range sizes {
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), square, square.Bounds(), draw.Src, nil)
+
+		var buf bytes.Buffer
+		rendition := Rendition{Size: size}
+		if transparent {
+			err = png.Encode(&buf, dst)
+			rendition.ContentType, rendition.Extension = "image/png", ".png"
+		} else {
+			err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 88})
+			rendition.ContentType, rendition.Extension = "image/jpeg", ".jpg"
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rendition.Data = buf.Bytes()
+		renditions = append(renditions, rendition)
+	}
+
+	return renditions, nil
+}
+
+func cropSquare(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(dst, dst.Bounds(), src, image.Pt(x, y), draw.Src)
+	return dst
+}
@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+
+	"markmywords-backend/pkg/config"
+)
+
+// Mailer delivers transactional email such as verification links.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// New returns an SMTP mailer when SMTP_HOST is configured and a mailer that
+// only logs messages otherwise.
+func New() Mailer {
+	host := config.Get("SMTP_HOST", "")
+	if host == "" {
+		return &LogMailer{}
+	}
+
+	return &SMTPMailer{
+		Addr:     fmt.Sprintf("%s:%d", host, config.GetInt("SMTP_PORT", 587)),
+		Host:     host,
+		Username: config.Get("SMTP_USERNAME", ""),
+		Password: config.Get("SMTP_PASSWORD", ""),
+		From:     config.Get("SMTP_FROM", "no-reply@markmywords.local"),
+	}
+}
+
+// LogMailer writes messages to the server log, for development.
+type LogMailer struct{}
+
+func (m *LogMailer) Send(to, subject, body string) error {
+	log.Printf("Email to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
+
+type SMTPMailer struct {
+	Addr     string
+	Host     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	return smtp.SendMail(m.Addr, auth, m.From, []string{to}, []byte(msg))
+}
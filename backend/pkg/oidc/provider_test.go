@@ -0,0 +1,163 @@
+package oidc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"markmywords-backend/pkg/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestProvider(t *testing.T) (*oidctest.Server, *Provider) {
+	t.Helper()
+
+	server, err := oidctest.NewServer("mmw-client", "mmw-secret")
+	if err != nil {
+		t.Fatalf("start provider: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	provider := NewProvider(ProviderConfig{
+		Name:         "mock",
+		IssuerURL:    server.Issuer(),
+		ClientID:     server.ClientID,
+		ClientSecret: server.ClientSecret,
+		RedirectURL:  "http://localhost/callback",
+	})
+	return server, provider
+}
+
+// login runs the code flow up to the provider's redirect and returns the code.
+func login(t *testing.T, server *oidctest.Server, provider *Provider, verifier, nonce string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	authURL, err := provider.AuthCodeURL("state-1", nonce, verifier)
+	if err != nil {
+		t.Fatalf("auth code url: %v", err)
+	}
+	code, state, err := server.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if state != "state-1" {
+		t.Fatalf("state = %q, want state-1", state)
+	}
+	return code
+}
+
+func TestExchange(t *testing.T) {
+	server, provider := newTestProvider(t)
+
+	code := login(t, server, provider, "verifier-1", "nonce-1", jwt.MapClaims{
+		"sub":            "user-1",
+		"email":          "ada@example.com",
+		"email_verified": "true",
+	})
+	claims, err := provider.Exchange(code, "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "ada@example.com" {
+		t.Fatalf("claims = %+v", claims)
+	}
+	if !claims.EmailVerified {
+		t.Fatal("a string email_verified of \"true\" was not read as verified")
+	}
+
+	if _, err := provider.Exchange(code, "verifier-1", "nonce-1"); err == nil {
+		t.Fatal("a code was redeemed twice")
+	}
+}
+
+func TestExchangeChecksCodeVerifier(t *testing.T) {
+	server, provider := newTestProvider(t)
+
+	code := login(t, server, provider, "verifier-1", "nonce-1", nil)
+	if _, err := provider.Exchange(code, "another-verifier", "nonce-1"); err == nil {
+		t.Fatal("the code was redeemed with the wrong PKCE verifier")
+	}
+}
+
+func TestExchangeChecksNonce(t *testing.T) {
+	server, provider := newTestProvider(t)
+
+	code := login(t, server, provider, "verifier-1", "nonce-1", nil)
+	_, err := provider.Exchange(code, "verifier-1", "nonce-2")
+	if err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("exchange with another nonce: err = %v, want a nonce mismatch", err)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	server, provider := newTestProvider(t)
+	now := time.Now()
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   server.Issuer(),
+			"aud":   server.ClientID,
+			"sub":   "user-1",
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Minute).Unix(),
+			"nonce": "nonce-1",
+		}
+	}
+
+	cases := []struct {
+		name   string
+		change func(jwt.MapClaims)
+		ok     bool
+	}{
+		{"valid", func(jwt.MapClaims) {}, true},
+		{"another audience", func(c jwt.MapClaims) { c["aud"] = "another-client" }, false},
+		{"audience list", func(c jwt.MapClaims) { c["aud"] = []string{"another-client", server.ClientID} }, true},
+		{"another issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, false},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }, false},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }, false},
+		{"no subject", func(c jwt.MapClaims) { delete(c, "sub") }, false},
+		{"no nonce", func(c jwt.MapClaims) { delete(c, "nonce") }, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := valid()
+			tc.change(claims)
+			raw, err := server.SignIDToken(claims)
+			if err != nil {
+				t.Fatalf("sign id_token: %v", err)
+			}
+
+			_, err = provider.VerifyIDToken(raw, "nonce-1")
+			if tc.ok && err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("verify accepted the token")
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsForeignSignature(t *testing.T) {
+	server, provider := newTestProvider(t)
+	other, err := oidctest.NewServer(server.ClientID, server.ClientSecret)
+	if err != nil {
+		t.Fatalf("start provider: %v", err)
+	}
+	defer other.Close()
+
+	raw, err := other.SignIDToken(jwt.MapClaims{
+		"iss":   server.Issuer(),
+		"aud":   server.ClientID,
+		"sub":   "user-1",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": "nonce-1",
+	})
+	if err != nil {
+		t.Fatalf("sign id_token: %v", err)
+	}
+	if _, err := provider.VerifyIDToken(raw, "nonce-1"); err == nil {
+		t.Fatal("verify accepted a token signed with another provider's key")
+	}
+}
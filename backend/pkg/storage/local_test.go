@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateKey(t *testing.T) {
+	cases := []struct {
+		key string
+		ok  bool
+	}{
+		{"avatars/1/photo.png", true},
+		{"notes/2/..hidden", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"../secret", false},
+		{"avatars/../../secret", false},
+		{"avatars/..", false},
+		{"avatars/./photo.png", false},
+		{"avatars//photo.png", false},
+		{"avatars/", false},
+		{`avatars\..\..\secret`, false},
+	}
+	for _, tc := range cases {
+		err := ValidateKey(tc.key)
+		if tc.ok && err != nil {
+			t.Errorf("ValidateKey(%q) = %v, want ok", tc.key, err)
+		}
+		if !tc.ok && !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("ValidateKey(%q) = %v, want ErrInvalidKey", tc.key, err)
+		}
+	}
+}
+
+func TestLocalStoreStaysInRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "uploads")
+	store, err := NewLocalStore(root)
+	if err != nil {
+		t.Fatalf("new local store: %v", err)
+	}
+	ctx := context.Background()
+
+	secret := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secret, []byte("keep out"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+
+	if err := store.Put(ctx, "../escaped", strings.NewReader("x"), 1, "text/plain"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("put outside the root: err = %v, want ErrInvalidKey", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped")); !os.IsNotExist(err) {
+		t.Fatal("put wrote a file outside the root")
+	}
+	if _, err := store.Get(ctx, "../secret"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("get outside the root: err = %v, want ErrInvalidKey", err)
+	}
+	if err := store.Delete(ctx, "../secret"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("delete outside the root: err = %v, want ErrInvalidKey", err)
+	}
+	if _, err := os.Stat(secret); err != nil {
+		t.Fatal("delete removed a file outside the root")
+	}
+
+	if err := store.Put(ctx, "avatars/1/photo.png", strings.NewReader("png"), 3, "image/png"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	object, err := store.Get(ctx, "avatars/1/photo.png")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	data, _ := io.ReadAll(object.Body)
+	object.Body.Close()
+	if string(data) != "png" || object.ContentType != "image/png" {
+		t.Fatalf("get = %q (%s)", data, object.ContentType)
+	}
+}
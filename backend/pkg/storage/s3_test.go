@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKeyID     = "AKIDEXAMPLE"
+	testSecretAccessKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion          = "eu-west-1"
+	testBucket          = "media"
+)
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+// fakeS3 is an S3 stand-in that checks every request's Signature Version 4
+// Authorization header before serving objects from memory.
+type fakeS3 struct {
+	mutex    sync.Mutex
+	objects  map[string]fakeObject
+	requests int
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	t.Helper()
+
+	fake := &fakeS3{objects: make(map[string]fakeObject)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	f.requests++
+	f.mutex.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "unreadable body", http.StatusBadRequest)
+		return
+	}
+	if err := verifySignature(r, body); err != nil {
+		http.Error(w, "SignatureDoesNotMatch: "+err.Error(), http.StatusForbidden)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+testBucket+"/")
+	if !ok || key == "" {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = fakeObject{data: body, contentType: r.Header.Get("Content-Type"), modTime: time.Now()}
+	case http.MethodGet:
+		object, found := f.objects[key]
+		if !found {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Last-Modified", object.modTime.UTC().Format(http.TimeFormat))
+		w.Write(object.data)
+	case http.MethodDelete:
+		if _, found := f.objects[key]; !found {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verifySignature recomputes the signature from the request as received.
+func verifySignature(r *http.Request, body []byte) error {
+	fields, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return errors.New("missing AWS4-HMAC-SHA256 authorization")
+	}
+	auth := make(map[string]string)
+	for _, field := range strings.Split(fields, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		auth[name] = value
+	}
+
+	credential := strings.Split(auth["Credential"], "/")
+	if len(credential) != 5 || credential[0] != testAccessKeyID || credential[2] != testRegion ||
+		credential[3] != "s3" || credential[4] != "aws4_request" {
+		return errors.New("bad credential scope")
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	signedAt, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil || !strings.HasPrefix(amzDate, credential[1]) {
+		return errors.New("bad X-Amz-Date")
+	}
+	if d := time.Since(signedAt); d > 15*time.Minute || d < -15*time.Minute {
+		return errors.New("request time too skewed")
+	}
+
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != "UNSIGNED-PAYLOAD" {
+		sum := sha256.Sum256(body)
+		if payloadHash != hex.EncodeToString(sum[:]) {
+			return errors.New("payload hash mismatch")
+		}
+	}
+
+	signedHeaders := strings.Split(auth["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(signedHeaders) {
+		return errors.New("signed headers are not sorted")
+	}
+	required := map[string]bool{"host": false, "x-amz-date": false, "x-amz-content-sha256": false}
+	var headers strings.Builder
+	for _, name := range signedHeaders {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		if _, ok := required[name]; ok {
+			required[name] = true
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	for name, signed := range required {
+		if !signed {
+			return errors.New(name + " is not signed")
+		}
+	}
+
+	canonical := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.Query().Encode(),
+		headers.String(),
+		auth["SignedHeaders"],
+		payloadHash,
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		strings.Join(credential[1:], "/"),
+		hex.EncodeToString(canonicalHash[:]),
+	}, "\n")
+
+	mac := func(key []byte, data string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+	signingKey := mac(mac(mac(mac([]byte("AWS4"+testSecretAccessKey), credential[1]), testRegion), "s3"), "aws4_request")
+	want := hex.EncodeToString(mac(signingKey, stringToSign))
+	if !hmac.Equal([]byte(want), []byte(auth["Signature"])) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func newTestS3Store(t *testing.T, endpoint, secret string) *S3Store {
+	t.Helper()
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:        endpoint,
+		Region:          testRegion,
+		Bucket:          testBucket,
+		AccessKeyID:     testAccessKeyID,
+		SecretAccessKey: secret,
+	})
+	if err != nil {
+		t.Fatalf("new s3 store: %v", err)
+	}
+	return store
+}
+
+func TestS3PutGetDelete(t *testing.T) {
+	fake, server := newFakeS3(t)
+	store := newTestS3Store(t, server.URL, testSecretAccessKey)
+	ctx := context.Background()
+
+	for _, key := range []string{"avatars/1/photo.png", "exports/2/my notes (1).zip"} {
+		data := []byte("contents of " + key)
+		if err := store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+			t.Fatalf("put %s: %v", key, err)
+		}
+
+		object, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("get %s: %v", key, err)
+		}
+		got, _ := io.ReadAll(object.Body)
+		object.Body.Close()
+		if !bytes.Equal(got, data) || object.ContentType != "image/png" || object.Size != int64(len(data)) {
+			t.Fatalf("get %s = %q (%s, %d bytes)", key, got, object.ContentType, object.Size)
+		}
+		if object.ModTime.IsZero() {
+			t.Fatalf("get %s has no modification time", key)
+		}
+
+		if err := store.Delete(ctx, key); err != nil {
+			t.Fatalf("delete %s: %v", key, err)
+		}
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get after delete: err = %v, want ErrNotFound", err)
+		}
+		// Deleting a missing object is not an error
+		if err := store.Delete(ctx, key); err != nil {
+			t.Fatalf("delete missing %s: %v", key, err)
+		}
+	}
+
+	if len(fake.objects) != 0 {
+		t.Fatalf("%d objects left behind", len(fake.objects))
+	}
+}
+
+func TestS3RejectsWrongSecret(t *testing.T) {
+	fake, server := newFakeS3(t)
+	store := newTestS3Store(t, server.URL, "not-the-secret")
+
+	err := store.Put(context.Background(), "avatars/1/photo.png", strings.NewReader("data"), 4, "image/png")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("put with the wrong secret: err = %v, want 403", err)
+	}
+	if len(fake.objects) != 0 {
+		t.Fatal("the unsigned upload was stored")
+	}
+}
+
+func TestS3RefusesInvalidKeys(t *testing.T) {
+	fake, server := newFakeS3(t)
+	store := newTestS3Store(t, server.URL, testSecretAccessKey)
+
+	for _, key := range []string{"", "../other-bucket/x", "a//b", "/abs"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "text/plain"); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("put %q: err = %v, want ErrInvalidKey", key, err)
+		}
+	}
+	if fake.requests != 0 {
+		t.Fatalf("invalid keys made %d requests", fake.requests)
+	}
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"markmywords-backend/pkg/config"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Object is a stored blob opened for reading. The caller must close Body.
+type Object struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// BlobStore stores uploaded media and generated files under slash separated keys.
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	defaultStore     BlobStore
+	defaultStoreOnce sync.Once
+)
+
+// Default returns the process wide store selected by STORAGE_DRIVER.
+func Default() BlobStore {
+	defaultStoreOnce.Do(func() {
+		store, err := New(config.Get("STORAGE_DRIVER", "local"))
+		if err != nil {
+			log.Fatal("Failed to initialize storage:", err)
+		}
+		defaultStore = store
+	})
+	return defaultStore
+}
+
+// New creates a store for the driver ("local" or "s3") from the environment.
+func New(driver string) (BlobStore, error) {
+	switch driver {
+	case "local":
+		return NewLocalStore(config.Get("STORAGE_LOCAL_DIR", "uploads"))
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:        config.Get("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:          config.Get("S3_REGION", "us-east-1"),
+			Bucket:          config.Get("S3_BUCKET", ""),
+			AccessKeyID:     config.Get("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: config.Get("S3_SECRET_ACCESS_KEY", ""),
+		})
+	default:
+		return nil, fmt.Errorf("unsupported storage driver %q", driver)
+	}
+}
+
+// PublicURL returns the URL the API serves a stored object from.
+func PublicURL(key string) string {
+	base := strings.TrimRight(config.Get("MEDIA_BASE_URL", "http://localhost:8080"), "/")
+	return base + "/media/" + (&url.URL{Path: key}).EscapedPath()
+}
+
+// ValidateKey rejects keys that are empty or could escape the store's namespace.
+func ValidateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -8,8 +8,9 @@ class WebSocketService {
   Function()? _onConnectedCallback;
   Function()? _onDisconnectedCallback;
 
-  void connect({required int userId}) {
-    final wsUrl = '${AppConstants.wsUrl}?user_id=$userId';
+  void connect({required String token}) {
+    final wsUrl =
+        '${AppConstants.wsUrl}?token=${Uri.encodeQueryComponent(token)}';
     _channel = WebSocketChannel.connect(Uri.parse(wsUrl));
     
     _channel!.stream.listen(